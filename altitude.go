@@ -0,0 +1,217 @@
+//--------------------------------------------------------------------------------------------------
+//
+// Copyright (c) 2018 Denis Dyakov
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy of this software and
+// associated documentation files (the "Software"), to deal in the Software without restriction,
+// including without limitation the rights to use, copy, modify, merge, publish, distribute, sublicense,
+// and/or sell copies of the Software, and to permit persons to whom the Software is furnished to do so,
+// subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all copies or substantial
+// portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR IMPLIED, INCLUDING
+// BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY, FITNESS FOR A PARTICULAR PURPOSE AND
+// NONINFRINGEMENT. IN NO EVENT SHALL THE AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM,
+// DAMAGES OR OTHER LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE SOFTWARE.
+//
+//--------------------------------------------------------------------------------------------------
+
+package bsbmp
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+)
+
+// Standard atmosphere pressure at sea level in Pa (pascal),
+// used as reference pressure (QNH) by default.
+const SEA_LEVEL_PRESSURE_PA = 101325.0
+
+// AltitudeModel define formula used to convert pressure to altitude.
+type AltitudeModel int
+
+const (
+	// International barometric formula, which assume standard
+	// atmosphere temperature profile (15 °C at sea level). Default model.
+	ALTITUDE_BAROMETRIC AltitudeModel = iota
+	// Hypsometric formula, which takes into account temperature measured
+	// by sensor. More accurate, when air temperature differs from standard
+	// atmosphere one, and sensor is not heated by surrounding electronics.
+	ALTITUDE_HYPSOMETRIC
+)
+
+// String define stringer interface.
+func (v AltitudeModel) String() string {
+	switch v {
+	case ALTITUDE_BAROMETRIC:
+		return "Barometric formula"
+	case ALTITUDE_HYPSOMETRIC:
+		return "Hypsometric formula"
+	default:
+		return "<unknown>"
+	}
+}
+
+// BarometricAltitude calculates altitude in meters from pressure,
+// with reference pressure qnh at sea level, both in Pa (pascal),
+// according to international barometric formula.
+func BarometricAltitude(pressurePa, qnhPa float64) float64 {
+	return 44330 * (1 - math.Pow(pressurePa/qnhPa, 1/5.255))
+}
+
+// HypsometricAltitude calculates altitude in meters from pressure,
+// with reference pressure qnh at sea level, both in Pa (pascal),
+// and air temperature in C (celsius), according to hypsometric formula.
+func HypsometricAltitude(pressurePa, qnhPa, temperatureC float64) float64 {
+	return (math.Pow(qnhPa/pressurePa, 1/5.257) - 1) * (temperatureC + 273.15) / 0.0065
+}
+
+// QNHFromElevation calculates reference pressure at sea level (QNH) in Pa (pascal)
+// from pressure measured at known elevation in meters, inverting international
+// barometric formula, so BarometricAltitude(pressure, qnh) return elevation back.
+func QNHFromElevation(pressurePa, elevationM float64) float64 {
+	return pressurePa / math.Pow(1-elevationM/44330, 5.255)
+}
+
+// SeaLevelPressure reduce pressure measured at station elevation in meters
+// to mean sea level pressure (QFF) in Pa (pascal), taking into account
+// air temperature in C (celsius) and relative humidity in %RH at station,
+// according to reduction formula used by Deutscher Wetterdienst.
+// Pass zero humidity, if it's not measured.
+func SeaLevelPressure(pressurePa, elevationM, temperatureC, humidityRH float64) float64 {
+	const (
+		// Standard gravity, m/s^2
+		g0 = 9.80665
+		// Gas constant of dry air, J/(kg*K)
+		r = 287.05
+		// Vertical temperature gradient, K/m
+		a = 0.0065
+		// Coefficient taking into account vapor pressure, K/hPa
+		ch = 0.12
+	)
+	// Vapor pressure in hPa by Magnus formula
+	e := humidityRH / 100 * 6.112 * math.Exp(17.62*temperatureC/(243.12+temperatureC))
+	t := temperatureC + 273.15
+	return pressurePa * math.Exp(g0*elevationM/(r*(t+ch*e+a*elevationM/2)))
+}
+
+// altitude calculates altitude in meters from measurement with model specified.
+func (v AltitudeModel) altitude(pressurePa, qnhPa, temperatureC float64) float64 {
+	if v == ALTITUDE_HYPSOMETRIC {
+		return HypsometricAltitude(pressurePa, qnhPa, temperatureC)
+	}
+	return BarometricAltitude(pressurePa, qnhPa)
+}
+
+// qnh calculates sea level pressure in Pa (pascal) from pressure
+// measured at known elevation in meters, inverting model formula.
+func (v AltitudeModel) qnh(pressurePa, elevationM, temperatureC float64) float64 {
+	if v == ALTITUDE_HYPSOMETRIC {
+		return pressurePa * math.Pow(1+0.0065*elevationM/(temperatureC+273.15), 5.257)
+	}
+	return QNHFromElevation(pressurePa, elevationM)
+}
+
+// SetQNH setup reference pressure at sea level in Pa (pascal) used for
+// altitude calculation. Take it from local weather report (METAR QNH in hPa
+// should be multiplied by 100), or use CalibrateQNH at known elevation.
+func (v *BMP) SetQNH(qnhPa float64) error {
+	if qnhPa <= 0 {
+		return errors.New(fmt.Sprintf("reference pressure %v Pa should be positive", qnhPa))
+	}
+	v.qnh = qnhPa
+	return nil
+}
+
+// QNH returns reference pressure at sea level in Pa (pascal).
+func (v *BMP) QNH() float64 {
+	return v.qnh
+}
+
+// SetAltitudeModel select formula used for altitude calculation.
+func (v *BMP) SetAltitudeModel(model AltitudeModel) error {
+	switch model {
+	case ALTITUDE_BAROMETRIC, ALTITUDE_HYPSOMETRIC:
+		v.altitudeModel = model
+		return nil
+	default:
+		return errors.New(fmt.Sprintf("unknown altitude model %d", model))
+	}
+}
+
+// AltitudeModel returns formula used for altitude calculation.
+func (v *BMP) AltitudeModel() AltitudeModel {
+	return v.altitudeModel
+}
+
+// measureAltitude measures pressure and temperature for altitude calculation.
+func (v *BMP) measureAltitude(ctx context.Context, accuracy AccuracyMode) (pressurePa, temperatureC float64, err error) {
+	m, err := v.MeasureContext(ctx, MeasureSettings{Temperature: accuracy, Pressure: accuracy})
+	if err != nil {
+		return 0, 0, err
+	}
+	return float64(m.PressureMult10Pa) / 10, float64(m.TemperatureMult100C) / 100, nil
+}
+
+// CalibrateQNH measures pressure at known elevation in meters above sea
+// level, and setup reference pressure (QNH) matching it, so further
+// ReadAltitude calls return elevation from here. Returns QNH in Pa.
+func (v *BMP) CalibrateQNH(elevationM float64, accuracy AccuracyMode) (float64, error) {
+	return v.CalibrateQNHContext(context.Background(), elevationM, accuracy)
+}
+
+// CalibrateQNHContext is CalibrateQNH with context.
+func (v *BMP) CalibrateQNHContext(ctx context.Context, elevationM float64,
+	accuracy AccuracyMode) (float64, error) {
+	p, t, err := v.measureAltitude(ctx, accuracy)
+	if err != nil {
+		return 0, err
+	}
+	v.qnh = v.altitudeModel.qnh(p, elevationM, t)
+	lg.Debugf("QNH=%v Pa at %v m", v.qnh, elevationM)
+	return v.qnh, nil
+}
+
+// ReadAltitudeM reads pressure and calculates altitude in meters
+// above sea level, using reference pressure (QNH) and altitude model set up.
+func (v *BMP) ReadAltitudeM(accuracy AccuracyMode) (float64, error) {
+	return v.ReadAltitudeMContext(context.Background(), accuracy)
+}
+
+// ReadAltitudeMContext is ReadAltitudeM with context.
+func (v *BMP) ReadAltitudeMContext(ctx context.Context, accuracy AccuracyMode) (float64, error) {
+	p, t, err := v.measureAltitude(ctx, accuracy)
+	if err != nil {
+		return 0, err
+	}
+	return v.altitudeModel.altitude(p, v.qnh, t), nil
+}
+
+// ReadSeaLevelPressurePa measures pressure at station elevation in meters
+// and reduce it to mean sea level pressure (QFF) in Pa (pascal), taking
+// into account temperature and humidity (if supported) measured as well.
+// Unlike QNH, this is the value reported by weather stations.
+func (v *BMP) ReadSeaLevelPressurePa(elevationM float64, accuracy AccuracyMode) (float64, error) {
+	return v.ReadSeaLevelPressurePaContext(context.Background(), elevationM, accuracy)
+}
+
+// ReadSeaLevelPressurePaContext is ReadSeaLevelPressurePa with context.
+func (v *BMP) ReadSeaLevelPressurePaContext(ctx context.Context, elevationM float64,
+	accuracy AccuracyMode) (float64, error) {
+	m, err := v.MeasureContext(ctx, MeasureSettings{Temperature: accuracy,
+		Pressure: accuracy, Humidity: accuracy})
+	if err != nil {
+		return 0, err
+	}
+	var h float64
+	if m.HumiditySupported {
+		h = float64(m.HumidityMultQ2210) / 1024
+	}
+	return SeaLevelPressure(float64(m.PressureMult10Pa)/10, elevationM,
+		float64(m.TemperatureMult100C)/100, h), nil
+}
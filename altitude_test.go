@@ -0,0 +1,171 @@
+//--------------------------------------------------------------------------------------------------
+//
+// Copyright (c) 2018 Denis Dyakov
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy of this software and
+// associated documentation files (the "Software"), to deal in the Software without restriction,
+// including without limitation the rights to use, copy, modify, merge, publish, distribute, sublicense,
+// and/or sell copies of the Software, and to permit persons to whom the Software is furnished to do so,
+// subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all copies or substantial
+// portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR IMPLIED, INCLUDING
+// BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY, FITNESS FOR A PARTICULAR PURPOSE AND
+// NONINFRINGEMENT. IN NO EVENT SHALL THE AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM,
+// DAMAGES OR OTHER LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE SOFTWARE.
+//
+//--------------------------------------------------------------------------------------------------
+
+package bsbmp_test
+
+import (
+	"fmt"
+	"math"
+	"testing"
+
+	"github.com/d2r2/go-bsbmp"
+	"github.com/d2r2/go-bsbmp/sim"
+)
+
+func TestBarometricAltitude(t *testing.T) {
+	// standard atmosphere: 898.746 hPa at 1000 m
+	checkValue(t, "BarometricAltitude", bsbmp.BarometricAltitude(89874.6, bsbmp.SEA_LEVEL_PRESSURE_PA),
+		1000, 0.5)
+	checkValue(t, "BarometricAltitude at QNH", bsbmp.BarometricAltitude(100500, 100500), 0, 1e-9)
+	for _, elevation := range []float64{-400, 0, 250, 1500, 4000} {
+		for _, pressure := range []float64{60000, 85000, 101325} {
+			qnh := bsbmp.QNHFromElevation(pressure, elevation)
+			checkValue(t, fmt.Sprintf("BarometricAltitude(%v, %v)", pressure, qnh),
+				bsbmp.BarometricAltitude(pressure, qnh), elevation, 1e-6)
+		}
+	}
+}
+
+func TestHypsometricAltitude(t *testing.T) {
+	checkValue(t, "HypsometricAltitude at QNH",
+		bsbmp.HypsometricAltitude(100500, 100500, 20), 0, 1e-9)
+	// the same as barometric formula, when temperature
+	// follows standard atmosphere profile
+	checkValue(t, "HypsometricAltitude", bsbmp.HypsometricAltitude(89874.6,
+		bsbmp.SEA_LEVEL_PRESSURE_PA, 15-0.0065*1000), 1000, 1)
+	// warm air is less dense, so the same pressure drop mean higher altitude
+	if bsbmp.HypsometricAltitude(89874.6, bsbmp.SEA_LEVEL_PRESSURE_PA, 30) <=
+		bsbmp.HypsometricAltitude(89874.6, bsbmp.SEA_LEVEL_PRESSURE_PA, 0) {
+		t.Error("altitude doesn't grow with temperature")
+	}
+}
+
+func TestSetQNH(t *testing.T) {
+	sensor, err := bsbmp.NewBMP(bsbmp.BMP280, sim.NewBMP280())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if sensor.QNH() != bsbmp.SEA_LEVEL_PRESSURE_PA {
+		t.Errorf("QNH() = %v, want %v by default", sensor.QNH(), bsbmp.SEA_LEVEL_PRESSURE_PA)
+	}
+	for _, qnh := range []float64{0, -101325, math.Inf(-1)} {
+		err = sensor.SetQNH(qnh)
+		if err == nil {
+			t.Errorf("SetQNH(%v) accepted", qnh)
+		}
+	}
+	err = sensor.SetQNH(99800)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if sensor.QNH() != 99800 {
+		t.Errorf("QNH() = %v, want 99800", sensor.QNH())
+	}
+	err = sensor.SetAltitudeModel(bsbmp.AltitudeModel(10))
+	if err == nil {
+		t.Error("unknown altitude model accepted")
+	}
+}
+
+func TestCalibrateQNH(t *testing.T) {
+	for _, c := range []struct {
+		model bsbmp.AltitudeModel
+		qnh   float64
+	}{
+		{bsbmp.ALTITUDE_BAROMETRIC, bsbmp.QNHFromElevation(94500, 620)},
+		// hypsometric formula inverted
+		{bsbmp.ALTITUDE_HYPSOMETRIC, 94500 * math.Pow(1+0.0065*620/(28+273.15), 5.257)},
+	} {
+		for _, ts := range testSensors {
+			dev := ts.newDevice()
+			dev.SetEnvironment(sim.Environment{Temperature: 28, Pressure: 94500, Humidity: 40})
+			sensor, err := bsbmp.NewBMP(ts.sensorType, dev)
+			if err != nil {
+				t.Fatal(err)
+			}
+			err = sensor.SetAltitudeModel(c.model)
+			if err != nil {
+				t.Fatal(err)
+			}
+			name := fmt.Sprintf("%v/%v", ts.sensorType, c.model)
+			qnh, err := sensor.CalibrateQNH(620, bsbmp.ACCURACY_HIGH)
+			if err != nil {
+				t.Fatalf("%s: %v", name, err)
+			}
+			if qnh != sensor.QNH() {
+				t.Errorf("%s: CalibrateQNH() = %v, QNH() = %v", name, qnh, sensor.QNH())
+			}
+			checkValue(t, name+" QNH", qnh, c.qnh, 2*pressureTolerance)
+			altitude, err := sensor.ReadAltitudeM(bsbmp.ACCURACY_HIGH)
+			if err != nil {
+				t.Fatalf("%s: %v", name, err)
+			}
+			checkValue(t, name+" ReadAltitudeM", altitude, 620, 0.5)
+			// 12 Pa pressure drop is about 1 m of altitude
+			dev.SetEnvironment(sim.Environment{Temperature: 28, Pressure: 94380, Humidity: 40})
+			altitude, err = sensor.ReadAltitudeM(bsbmp.ACCURACY_HIGH)
+			if err != nil {
+				t.Fatalf("%s: %v", name, err)
+			}
+			checkValue(t, name+" ReadAltitudeM after climb", altitude, 631, 1.5)
+		}
+	}
+}
+
+func TestSeaLevelPressure(t *testing.T) {
+	for _, c := range []struct {
+		pressure, elevation, temperature, humidity float64
+		want                                       float64
+	}{
+		{101325, 0, 15, 50, 101325},
+		// worked out with DWD reduction formula constants
+		{95000, 500, 15, 0, 100768.45},
+		{95000, 500, 15, 60, 100743.44},
+		{90000, 1000, 5, 80, 101580.88},
+	} {
+		checkValue(t, fmt.Sprintf("SeaLevelPressure(%v, %v, %v, %v)",
+			c.pressure, c.elevation, c.temperature, c.humidity),
+			bsbmp.SeaLevelPressure(c.pressure, c.elevation, c.temperature, c.humidity),
+			c.want, 0.01)
+	}
+}
+
+func TestReadSeaLevelPressure(t *testing.T) {
+	env := sim.Environment{Temperature: 15, Pressure: 95000, Humidity: 60}
+	for _, ts := range testSensors {
+		dev := ts.newDevice()
+		dev.SetEnvironment(env)
+		sensor, err := bsbmp.NewBMP(ts.sensorType, dev)
+		if err != nil {
+			t.Fatal(err)
+		}
+		p, err := sensor.ReadSeaLevelPressurePa(500, bsbmp.ACCURACY_HIGH)
+		if err != nil {
+			t.Fatalf("%v: %v", ts.sensorType, err)
+		}
+		// humidity is taken into account, if supported
+		want := 100768.45
+		if ts.humidity {
+			want = 100743.44
+		}
+		checkValue(t, ts.sensorType.String()+" ReadSeaLevelPressurePa", p, want, 2*pressureTolerance)
+	}
+}
@@ -26,8 +26,7 @@ import (
 	"encoding/binary"
 	"errors"
 	"fmt"
-
-	i2c "github.com/d2r2/go-i2c"
+	"time"
 )
 
 // BME280 sensors memory map
@@ -37,7 +36,7 @@ const (
 	BME280_CTRL_HUM  = 0xF2
 	BME280_STATUS    = 0xF3
 	BME280_CTRL_MEAS = 0xF4
-	BME280_CONFIG    = 0xF5 // TODO: support IIR filter settings
+	BME280_CONFIG    = 0xF5
 	BME280_RESET     = 0xE0
 	// BME280 specific compensation register's blocks
 	BME280_COEF_PART1_START = 0x88
@@ -167,16 +166,20 @@ func (v *CoeffBME280) dig_H6() int8 {
 // SensorBME280 specific type
 type SensorBME280 struct {
 	Coeff *CoeffBME280
+	// Operating mode, forced by default
+	mode PowerMode
 }
 
 // Static cast to verify at compile time
 // that type implement interface.
 var _ SensorInterface = &SensorBME280{}
+var _ PowerModeInterface = &SensorBME280{}
+var _ StandbyInterface = &SensorBME280{}
 
 // ReadSensorID reads sensor signature. It may be used for validation,
 // that proper code settings used for sensor data decoding.
-func (v *SensorBME280) ReadSensorID(i2c *i2c.I2C) (uint8, error) {
-	id, err := i2c.ReadRegU8(BME280_ID_REG)
+func (v *SensorBME280) ReadSensorID(bus Bus) (uint8, error) {
+	id, err := bus.ReadRegU8(BME280_ID_REG)
 	if err != nil {
 		return 0, err
 	}
@@ -184,38 +187,26 @@ func (v *SensorBME280) ReadSensorID(i2c *i2c.I2C) (uint8, error) {
 }
 
 // ReadCoefficients reads compensation coefficients, unique for each sensor.
-func (v *SensorBME280) ReadCoefficients(i2c *i2c.I2C) error {
+func (v *SensorBME280) ReadCoefficients(bus Bus) error {
 	// read coefficients #1
-	_, err := i2c.WriteBytes([]byte{BME280_COEF_PART1_START})
-	if err != nil {
-		return err
-	}
 	var coef1 [BME280_COEF_PART1_BYTES]byte
-	err = readDataToStruct(i2c, BME280_COEF_PART1_BYTES,
+	err := readDataToStruct(bus, BME280_COEF_PART1_START, BME280_COEF_PART1_BYTES,
 		binary.LittleEndian, &coef1)
 	if err != nil {
 		return err
 	}
 
 	// read coefficients #2
-	_, err = i2c.WriteBytes([]byte{BME280_COEF_PART2_START})
-	if err != nil {
-		return err
-	}
 	var coef2 [BME280_COEF_PART2_BYTES]byte
-	err = readDataToStruct(i2c, BME280_COEF_PART2_BYTES,
+	err = readDataToStruct(bus, BME280_COEF_PART2_START, BME280_COEF_PART2_BYTES,
 		binary.LittleEndian, &coef2)
 	if err != nil {
 		return err
 	}
 
 	// read coefficients #3
-	_, err = i2c.WriteBytes([]byte{BME280_COEF_PART3_START})
-	if err != nil {
-		return err
-	}
 	var coef3 [BME280_COEF_PART3_BYTES]byte
-	err = readDataToStruct(i2c, BME280_COEF_PART3_BYTES,
+	err = readDataToStruct(bus, BME280_COEF_PART3_START, BME280_COEF_PART3_BYTES,
 		binary.LittleEndian, &coef3)
 	if err != nil {
 		return err
@@ -235,6 +226,15 @@ func (v *SensorBME280) ReadCoefficients(i2c *i2c.I2C) error {
 	return nil
 }
 
+// loadCoefficients reads compensation coefficients only once,
+// since they never change. Use ReadCoefficients to force reload.
+func (v *SensorBME280) loadCoefficients(bus Bus) error {
+	if v.Coeff == nil {
+		return v.ReadCoefficients(bus)
+	}
+	return nil
+}
+
 // IsValidCoefficients verify that compensate registers
 // are not empty, and thus are valid.
 func (v *SensorBME280) IsValidCoefficients() error {
@@ -301,16 +301,16 @@ func (v *SensorBME280) RecognizeSignature(signature uint8) (string, error) {
 	case 0x60:
 		return "BME280", nil
 	default:
-		return "", errors.New(fmt.Sprintf("signature 0x%x doesn't belong to BME280 series", signature))
+		return "", &SignatureError{Sensor: BME280, Signature: signature}
 	}
 }
 
 // IsBusy reads register 0xF3 for "busy" flag,
 // according to sensor specification.
-func (v *SensorBME280) IsBusy(i2c *i2c.I2C) (busy bool, err error) {
+func (v *SensorBME280) IsBusy(bus Bus) (busy bool, err error) {
 	// Check flag to know status of calculation, according
 	// to specification about SCO (Start of conversion) flag
-	b, err := i2c.ReadRegU8(BME280_STATUS)
+	b, err := bus.ReadRegU8(BME280_STATUS)
 	if err != nil {
 		return false, err
 	}
@@ -319,6 +319,15 @@ func (v *SensorBME280) IsBusy(i2c *i2c.I2C) (busy bool, err error) {
 	return b != 0, nil
 }
 
+// SetIIRFilter setup IIR filter coefficient in config register,
+// keeping standby time and SPI 3-wire settings unchanged.
+func (v *SensorBME280) SetIIRFilter(bus Bus, filter IIRFilter) error {
+	if filter < IIR_FILTER_OFF || filter > IIR_FILTER_16 {
+		return &NotSupportedError{Sensor: BME280, Feature: fmt.Sprintf("IIR filter %v", filter)}
+	}
+	return updateRegU8(bus, BME280_CONFIG, 0x7<<2, byte(filter)<<2)
+}
+
 func (v *SensorBME280) getOversamplingRation(accuracy AccuracyMode) byte {
 	var b byte
 	switch accuracy {
@@ -339,19 +348,83 @@ func (v *SensorBME280) getOversamplingRation(accuracy AccuracyMode) byte {
 	return b
 }
 
-// readUncompTemprature reads uncompensated temprature from sensor.
-func (v *SensorBME280) readUncompTemprature(i2c *i2c.I2C, accuracy AccuracyMode) (int32, error) {
+// triggerMeasurement starts forced conversion with oversampling specified
+// and waits for completion. Does nothing in normal mode, since sensor converts
+// data autonomously and data registers keep latest sample.
+func (v *SensorBME280) triggerMeasurement(bus Bus, osrt, osrp, osrh byte) error {
+	if v.mode == POWER_MODE_NORMAL {
+		return nil
+	}
+	// Changes to ctrl_hum register become effective
+	// only after a write operation to ctrl_meas register
+	err := bus.WriteRegU8(BME280_CTRL_HUM, osrh)
+	if err != nil {
+		return err
+	}
 	var power byte = 1 // Forced mode
-	osrt := v.getOversamplingRation(accuracy)
-	err := i2c.WriteRegU8(BME280_CTRL_MEAS, power|(osrt<<5))
+	err = bus.WriteRegU8(BME280_CTRL_MEAS, power|(osrt<<5)|(osrp<<2))
 	if err != nil {
-		return 0, err
+		return err
+	}
+	err = waitForCompletion(v, bus)
+	return err
+}
+
+// SetPowerMode switch sensor to sleep, forced or normal mode.
+// Oversampling from settings is used for normal mode.
+func (v *SensorBME280) SetPowerMode(bus Bus, mode PowerMode, settings MeasureSettings) error {
+	var power byte
+	switch mode {
+	case POWER_MODE_SLEEP, POWER_MODE_FORCED:
+		// forced conversion is triggered on each read,
+		// sensor stays in sleep mode meanwhile
+		power = 0
+	case POWER_MODE_NORMAL:
+		power = 3
+	default:
+		return &NotSupportedError{Sensor: BME280, Feature: fmt.Sprintf("power mode %v", mode)}
+	}
+	osrt := v.getOversamplingRation(settings.Temperature)
+	osrp := v.getOversamplingRation(settings.Pressure)
+	osrh := v.getOversamplingRation(settings.Humidity)
+	err := bus.WriteRegU8(BME280_CTRL_HUM, osrh)
+	if err != nil {
+		return err
+	}
+	err = bus.WriteRegU8(BME280_CTRL_MEAS, power|(osrt<<5)|(osrp<<2))
+	if err != nil {
+		return err
+	}
+	v.mode = mode
+	return nil
+}
+
+// SetStandbyTime setup standby time between measurements in normal mode,
+// keeping IIR filter and SPI 3-wire settings unchanged.
+func (v *SensorBME280) SetStandbyTime(bus Bus, standby StandbyTime) error {
+	var b byte
+	switch standby {
+	case STANDBY_0_5_MS, STANDBY_62_5_MS, STANDBY_125_MS,
+		STANDBY_250_MS, STANDBY_500_MS, STANDBY_1000_MS:
+		b = byte(standby)
+	case STANDBY_10_MS:
+		b = 6
+	case STANDBY_20_MS:
+		b = 7
+	default:
+		return &NotSupportedError{Sensor: BME280, Feature: fmt.Sprintf("standby time %v", standby.Duration())}
 	}
-	_, err = waitForCompletion(v, i2c)
+	return updateRegU8(bus, BME280_CONFIG, 0x7<<5, b<<5)
+}
+
+// readUncompTemprature reads uncompensated temprature from sensor.
+func (v *SensorBME280) readUncompTemprature(bus Bus, accuracy AccuracyMode) (int32, error) {
+	osrt := v.getOversamplingRation(accuracy)
+	err := v.triggerMeasurement(bus, osrt, 0, 0)
 	if err != nil {
 		return 0, err
 	}
-	buf, _, err := i2c.ReadRegBytes(BME280_TEMP_OUT_MSB_LSB_XLSB, 3)
+	buf, _, err := bus.ReadRegBytes(BME280_TEMP_OUT_MSB_LSB_XLSB, 3)
 	if err != nil {
 		return 0, err
 	}
@@ -360,18 +433,13 @@ func (v *SensorBME280) readUncompTemprature(i2c *i2c.I2C, accuracy AccuracyMode)
 }
 
 // readUncompPressure reads uncompensated atmospheric pressure from sensor.
-func (v *SensorBME280) readUncompPressure(i2c *i2c.I2C, accuracy AccuracyMode) (int32, error) {
-	var power byte = 1 // Forced mode
+func (v *SensorBME280) readUncompPressure(bus Bus, accuracy AccuracyMode) (int32, error) {
 	osrp := v.getOversamplingRation(accuracy)
-	err := i2c.WriteRegU8(BME280_CTRL_MEAS, power|(osrp<<2))
+	err := v.triggerMeasurement(bus, 0, osrp, 0)
 	if err != nil {
 		return 0, err
 	}
-	_, err = waitForCompletion(v, i2c)
-	if err != nil {
-		return 0, err
-	}
-	buf, _, err := i2c.ReadRegBytes(BME280_PRESS_OUT_MSB_LSB_XLSB, 3)
+	buf, _, err := bus.ReadRegBytes(BME280_PRESS_OUT_MSB_LSB_XLSB, 3)
 	if err != nil {
 		return 0, err
 	}
@@ -380,27 +448,14 @@ func (v *SensorBME280) readUncompPressure(i2c *i2c.I2C, accuracy AccuracyMode) (
 }
 
 // readUncompHumidity reads uncompensated humidity from sensor.
-func (v *SensorBME280) readUncompHumidity(i2c *i2c.I2C, accuracy AccuracyMode) (int32, error) {
-	var power byte = 1 // Forced mode
-	osrt := v.getOversamplingRation(accuracy)
-	err := i2c.WriteRegU8(BME280_CTRL_MEAS, power|(osrt<<5))
-	if err != nil {
-		return 0, err
-	}
-	_, err = waitForCompletion(v, i2c)
-	if err != nil {
-		return 0, err
-	}
+func (v *SensorBME280) readUncompHumidity(bus Bus, accuracy AccuracyMode) (int32, error) {
 	osrh := v.getOversamplingRation(ACCURACY_ULTRA_LOW)
-	err = i2c.WriteRegU8(BME280_CTRL_HUM, osrh)
-	if err != nil {
-		return 0, err
-	}
-	_, err = waitForCompletion(v, i2c)
+	osrt := v.getOversamplingRation(accuracy)
+	err := v.triggerMeasurement(bus, osrt, 0, osrh)
 	if err != nil {
 		return 0, err
 	}
-	buf, _, err := i2c.ReadRegBytes(BME280_HUM_OUT_MSB_LSB, 2)
+	buf, _, err := bus.ReadRegBytes(BME280_HUM_OUT_MSB_LSB, 2)
 	if err != nil {
 		return 0, err
 	}
@@ -412,25 +467,20 @@ func (v *SensorBME280) readUncompHumidity(i2c *i2c.I2C, accuracy AccuracyMode) (
 // atmospheric uncompensated pressure from sensor.
 // BME280 allows to read temprature and pressure in one cycle,
 // BMP180 - doesn't.
-func (v *SensorBME280) readUncompTempratureAndPressure(i2c *i2c.I2C,
+func (v *SensorBME280) readUncompTempratureAndPressure(bus Bus,
 	accuracy AccuracyMode) (temprature int32, pressure int32, err error) {
-	var power byte = 1 // Forced mode
 	osrt := v.getOversamplingRation(ACCURACY_STANDARD)
 	osrp := v.getOversamplingRation(accuracy)
-	err = i2c.WriteRegU8(BME280_CTRL_MEAS, power|(osrt<<5)|(osrp<<2))
+	err = v.triggerMeasurement(bus, osrt, osrp, 0)
 	if err != nil {
 		return 0, 0, err
 	}
-	_, err = waitForCompletion(v, i2c)
-	if err != nil {
-		return 0, 0, err
-	}
-	buf, _, err := i2c.ReadRegBytes(BME280_TEMP_OUT_MSB_LSB_XLSB, 3)
+	buf, _, err := bus.ReadRegBytes(BME280_TEMP_OUT_MSB_LSB_XLSB, 3)
 	if err != nil {
 		return 0, 0, err
 	}
 	ut := int32(buf[0])<<12 + int32(buf[1])<<4 + int32(buf[2]&0xF0)>>4
-	buf, _, err = i2c.ReadRegBytes(BME280_PRESS_OUT_MSB_LSB_XLSB, 3)
+	buf, _, err = bus.ReadRegBytes(BME280_PRESS_OUT_MSB_LSB_XLSB, 3)
 	if err != nil {
 		return 0, 0, err
 	}
@@ -438,50 +488,24 @@ func (v *SensorBME280) readUncompTempratureAndPressure(i2c *i2c.I2C,
 	return ut, up, nil
 }
 
-// ReadTemperatureMult100C reads and calculates temrature in C (celsius) multiplied by 100.
-// Multiplication approach allow to keep result as integer number.
-func (v *SensorBME280) ReadTemperatureMult100C(i2c *i2c.I2C, accuracy AccuracyMode) (int32, error) {
-	ut, err := v.readUncompTemprature(i2c, accuracy)
-	if err != nil {
-		return 0, err
-	}
-	err = v.ReadCoefficients(i2c)
-	if err != nil {
-		return 0, err
-	}
-
+// compensateTemperature calculates temrature in C (celsius) multiplied by 100
+// from uncompensated value ut. Returns t_fine as well, required to compensate
+// pressure and humidity.
+func (v *SensorBME280) compensateTemperature(ut int32) (t int32, tFine int32) {
 	var1 := ((ut>>3 - int32(v.Coeff.dig_T1())<<1) * int32(v.Coeff.dig_T2())) >> 11
 	lg.Debugf("var1=%v", var1)
 	var2 := (((ut>>4 - int32(v.Coeff.dig_T1())) * (ut>>4 - int32(v.Coeff.dig_T1()))) >> 12 *
 		int32(v.Coeff.dig_T3())) >> 14
-	lg.Debugf("var1=%v", var2)
-	tFine := var1 + var2
+	lg.Debugf("var2=%v", var2)
+	tFine = var1 + var2
 	lg.Debugf("t_fine=%v", tFine)
-	t := (tFine*5 + 128) >> 8
-	return t, nil
+	t = (tFine*5 + 128) >> 8
+	return t, tFine
 }
 
-// ReadPressureMult10Pa reads and calculates atmospheric pressure in Pa (Pascal) multiplied by 10.
-// Multiplication approach allow to keep result as integer number.
-func (v *SensorBME280) ReadPressureMult10Pa(i2c *i2c.I2C, accuracy AccuracyMode) (uint32, error) {
-	ut, up, err := v.readUncompTempratureAndPressure(i2c, accuracy)
-	if err != nil {
-		return 0, err
-	}
-	lg.Debugf("ut=%v, up=%v", ut, up)
-
-	err = v.ReadCoefficients(i2c)
-	if err != nil {
-		return 0, err
-	}
-
-	var01 := ((ut>>3 - int32(v.Coeff.dig_T1())<<1) * int32(v.Coeff.dig_T2())) >> 11
-	lg.Debugf("var01=%v", var01)
-	var02 := (((ut>>4 - int32(v.Coeff.dig_T1())) * (ut>>4 - int32(v.Coeff.dig_T1()))) >> 12 *
-		int32(v.Coeff.dig_T3())) >> 14
-	lg.Debugf("var01=%v", var02)
-	tFine := var01 + var02
-
+// compensatePressure calculates atmospheric pressure in Pa (Pascal) multiplied by 10
+// from uncompensated value up and t_fine obtained from temperature compensation.
+func (v *SensorBME280) compensatePressure(up int32, tFine int32) uint32 {
 	var1 := int64(tFine) - 128000
 	lg.Debugf("var1=%v", var1)
 	var2 := var1 * var1 * int64(v.Coeff.dig_P6())
@@ -493,7 +517,7 @@ func (v *SensorBME280) ReadPressureMult10Pa(i2c *i2c.I2C, accuracy AccuracyMode)
 	var1 = ((int64(1)<<47 + var1) * int64(v.Coeff.dig_P1())) >> 33
 	lg.Debugf("var1=%v", var1)
 	if var1 == 0 {
-		return 0, nil
+		return 0
 	}
 	p1 := int64(1048576) - int64(up)
 	p1 = ((p1<<31 - var2) * 3125) / var1
@@ -501,39 +525,12 @@ func (v *SensorBME280) ReadPressureMult10Pa(i2c *i2c.I2C, accuracy AccuracyMode)
 	var2 = (int64(v.Coeff.dig_P8()) * p1) >> 19
 	p1 = (p1+var1+var2)>>8 + int64(v.Coeff.dig_P7())<<4
 	p2 := p1 * 10 / 256
-	p := uint32(p2)
-
-	return p, nil
+	return uint32(p2)
 }
 
-// ReadHumidityMultQ2210 reads and calculate humidity in %RH.
-// Multiplication approach allow to keep result as integer number.
-// To get real value it's necessary to divide result by 1024.
-func (v *SensorBME280) ReadHumidityMultQ2210(i2c *i2c.I2C,
-	accuracy AccuracyMode) (supported bool, humidity uint32, erro error) {
-
-	ut, err := v.readUncompTemprature(i2c, accuracy)
-	if err != nil {
-		return true, 0, err
-	}
-	uh, err := v.readUncompHumidity(i2c, accuracy)
-	if err != nil {
-		return true, 0, err
-	}
-	lg.Debugf("ut=%v, uh=%v", ut, uh)
-	err = v.ReadCoefficients(i2c)
-	if err != nil {
-		return true, 0, err
-	}
-
-	var01 := ((ut>>3 - int32(v.Coeff.dig_T1())<<1) * int32(v.Coeff.dig_T2())) >> 11
-	lg.Debugf("var01=%v", var01)
-	var02 := (((ut>>4 - int32(v.Coeff.dig_T1())) * (ut>>4 - int32(v.Coeff.dig_T1()))) >> 12 *
-		int32(v.Coeff.dig_T3())) >> 14
-	lg.Debugf("var01=%v", var02)
-	tFine := var01 + var02
-	lg.Debugf("t_fine=%v", tFine)
-
+// compensateHumidity calculates humidity in %RH multiplied by 1024 from
+// uncompensated value uh and t_fine obtained from temperature compensation.
+func (v *SensorBME280) compensateHumidity(uh int32, tFine int32) uint32 {
 	// Alternative version of humidity calculation from raw value
 	// based on float ariphmetics.
 	//
@@ -549,7 +546,7 @@ func (v *SensorBME280) ReadHumidityMultQ2210(i2c *i2c.I2C,
 	// if var_H < 0.0 {
 	// 	var_H = 0.0
 	// }
-	// return true, uint32(var_H * 1024), nil
+	// return uint32(var_H * 1024)
 
 	var v_x1 int32
 	v_x1 = tFine - 76800
@@ -573,5 +570,93 @@ func (v *SensorBME280) ReadHumidityMultQ2210(i2c *i2c.I2C,
 	lg.Debugf("v_x1=%v", v_x1)
 	v_x1 = v_x1 >> 12
 	lg.Debugf("v_x1=%v", v_x1)
-	return true, uint32(v_x1), nil
+	return uint32(v_x1)
+}
+
+// ReadTemperatureMult100C reads and calculates temrature in C (celsius) multiplied by 100.
+// Multiplication approach allow to keep result as integer number.
+func (v *SensorBME280) ReadTemperatureMult100C(bus Bus, accuracy AccuracyMode) (int32, error) {
+	ut, err := v.readUncompTemprature(bus, accuracy)
+	if err != nil {
+		return 0, err
+	}
+	err = v.loadCoefficients(bus)
+	if err != nil {
+		return 0, err
+	}
+	t, _ := v.compensateTemperature(ut)
+	return t, nil
+}
+
+// ReadPressureMult10Pa reads and calculates atmospheric pressure in Pa (Pascal) multiplied by 10.
+// Multiplication approach allow to keep result as integer number.
+func (v *SensorBME280) ReadPressureMult10Pa(bus Bus, accuracy AccuracyMode) (uint32, error) {
+	ut, up, err := v.readUncompTempratureAndPressure(bus, accuracy)
+	if err != nil {
+		return 0, err
+	}
+	lg.Debugf("ut=%v, up=%v", ut, up)
+
+	err = v.loadCoefficients(bus)
+	if err != nil {
+		return 0, err
+	}
+	_, tFine := v.compensateTemperature(ut)
+	p := v.compensatePressure(up, tFine)
+	return p, nil
+}
+
+// ReadHumidityMultQ2210 reads and calculate humidity in %RH.
+// Multiplication approach allow to keep result as integer number.
+// To get real value it's necessary to divide result by 1024.
+func (v *SensorBME280) ReadHumidityMultQ2210(bus Bus,
+	accuracy AccuracyMode) (supported bool, humidity uint32, erro error) {
+
+	ut, err := v.readUncompTemprature(bus, accuracy)
+	if err != nil {
+		return true, 0, err
+	}
+	uh, err := v.readUncompHumidity(bus, accuracy)
+	if err != nil {
+		return true, 0, err
+	}
+	lg.Debugf("ut=%v, uh=%v", ut, uh)
+	err = v.loadCoefficients(bus)
+	if err != nil {
+		return true, 0, err
+	}
+	_, tFine := v.compensateTemperature(ut)
+	h := v.compensateHumidity(uh, tFine)
+	return true, h, nil
+}
+
+// Measure runs single forced conversion of temperature, pressure and humidity,
+// and reads out all values in one burst read. In normal mode latest sample is read.
+func (v *SensorBME280) Measure(bus Bus, settings MeasureSettings) (*Measurement, error) {
+	err := v.loadCoefficients(bus)
+	if err != nil {
+		return nil, err
+	}
+	osrt := v.getOversamplingRation(settings.Temperature)
+	osrp := v.getOversamplingRation(settings.Pressure)
+	osrh := v.getOversamplingRation(settings.Humidity)
+	err = v.triggerMeasurement(bus, osrt, osrp, osrh)
+	if err != nil {
+		return nil, err
+	}
+	// pressure, temperature and humidity registers go one by one
+	buf, _, err := bus.ReadRegBytes(BME280_PRESS_OUT_MSB_LSB_XLSB, 8)
+	if err != nil {
+		return nil, err
+	}
+	m := &Measurement{Time: time.Now(), HumiditySupported: true}
+	m.Raw.Pressure = getU20BE(buf[0:3])
+	m.Raw.Temperature = getU20BE(buf[3:6])
+	m.Raw.Humidity = int32(getU16BE(buf[6:8]))
+	lg.Debugf("ut=%v, up=%v, uh=%v", m.Raw.Temperature, m.Raw.Pressure, m.Raw.Humidity)
+	t, tFine := v.compensateTemperature(m.Raw.Temperature)
+	m.TemperatureMult100C = t
+	m.PressureMult10Pa = v.compensatePressure(m.Raw.Pressure, tFine)
+	m.HumidityMultQ2210 = v.compensateHumidity(m.Raw.Humidity, tFine)
+	return m, nil
 }
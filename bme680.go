@@ -1,7 +1,6 @@
 //--------------------------------------------------------------------------------------------------
 //
 // Copyright (c) 2018 Denis Dyakov
-//   Portions Copyright (c) 2019 Iron Heart Consulting, LLC
 //
 // Permission is hereby granted, free of charge, to any person obtaining a copy of this software and
 // associated documentation files (the "Software"), to deal in the Software without restriction,
@@ -27,142 +26,287 @@ import (
 	"encoding/binary"
 	"errors"
 	"fmt"
-
-	i2c "github.com/d2r2/go-i2c"
+	"time"
 )
 
 // BME680 sensors memory map
 const (
 	// BME680 general registers
-	BME680_ID_REG     = 0x00
-	BME680_STATUS_REG = 0x03
-	BME680_ERR_REG    = 0x02
-	//	BME680_CNTR_MEAS_REG = 0xF4  // No such reg in BME680
-	BME680_ODR_REG      = 0x1D // Data Rate control
-	BME680_OSR_REG      = 0x74 // Over sample rate control
-	BME680_PWR_CTRL_REG = 0x1B // enable/disable press or temp, set operating mode
+	BME680_ID_REG      = 0xD0
+	BME680_RESET       = 0xE0
+	BME680_VARIANT_REG = 0xF0
+	// Field 0 status: new_data, gas_measuring, measuring flags and gas_meas_index
+	BME680_MEAS_STATUS_REG = 0x1D
+	// Heater set-points 0..9: current, target resistance and wait time
+	BME680_IDAC_HEAT_0 = 0x50
+	BME680_RES_HEAT_0  = 0x5A
+	BME680_GAS_WAIT_0  = 0x64
+	// Heating duration shared by heater profile steps in parallel mode (BME688)
+	BME680_GAS_WAIT_SHARED = 0x6E
+	BME680_CTRL_GAS_0      = 0x70 // heat_off
+	BME680_CTRL_GAS_1      = 0x71 // run_gas, nb_conv
+	BME680_CTRL_HUM        = 0x72 // osrs_h
+	BME680_CTRL_MEAS       = 0x74 // osrs_t, osrs_p, mode
 	// CONFIG Register is used to set IIR Filter coefficent
-	BME680_CONFIG = 0x75 // TODO: support IIR filter settings
-	//	BME680_RESET         = 0xE0 // TODO: '388 doesn't have a reset register
-	BME680_CMD_REG = 0x7E
-	//  cmds - nop, extmode, clear FIFO, softreset
-	// BME680 specific compensation register's block
-	BME680_COEF_START = 0x31
-	BME680_COEF_BYTES = 21
-	// BME680 specific 3-byte reading out temprature and preassure
-	BME680_PRES_OUT_MSB_LSB_XLSB = 0x04
-	BME680_TEMP_OUT_MSB_LSB_XLSB = 0x22
-
-	BME680_PWR_MODE_SLEEP  = 0
-	BME680_PWR_MODE_FORCED = 1
-	BME680_PWR_MODE_NORMAL = 3
+	BME680_CONFIG = 0x75
+	// BME680 specific compensation register's blocks
+	BME680_COEF_PART1_START = 0x89
+	BME680_COEF_PART1_BYTES = 25
+	BME680_COEF_PART2_START = 0xE1
+	BME680_COEF_PART2_BYTES = 16
+	// res_heat_val, res_heat_range, range_switching_error
+	BME680_COEF_PART3_START = 0x00
+	BME680_COEF_PART3_BYTES = 5
+	// BME680 specific reading out of field 0 data block:
+	// status, 3-byte pressure, temprature, 2-byte humidity and gas resistance
+	BME680_FIELD0_START = BME680_MEAS_STATUS_REG
+	BME680_FIELD0_BYTES = 15
+	// BME688 keep gas resistance of high gas variant in 2 more bytes
+	// of each data field, and use 3 fields in parallel mode
+	BME688_FIELD_BYTES  = 17
+	BME688_FIELD_COUNT  = 3
+	BME688_HEATER_STEPS = 10
+
+	// Value of variant_id register
+	BME680_VARIANT_GAS_LOW  = 0x00
+	BME680_VARIANT_GAS_HIGH = 0x01
+
+	BME680_PWR_MODE_SLEEP    = 0
+	BME680_PWR_MODE_FORCED   = 1
+	BME680_PWR_MODE_PARALLEL = 2 // BME688 only
 
 	// IIR Filter coefficent
 	BME680_coef_0   = 0 // bypass-mode
-	BME680_coef_1   = 0
-	BME680_coef_3   = 0
-	BME680_coef_7   = 0
-	BME680_coef_15  = 0
-	BME680_coef_31  = 0
-	BME680_coef_63  = 0
-	BME680_coef_127 = 0
+	BME680_coef_1   = 1
+	BME680_coef_3   = 2
+	BME680_coef_7   = 3
+	BME680_coef_15  = 4
+	BME680_coef_31  = 5
+	BME680_coef_63  = 6
+	BME680_coef_127 = 7
 )
 
 // Unique BME680 calibration coefficients
 type CoeffBME680 struct {
 	// Registers storing unique calibration coefficients
-	COEF_31 uint8
-	COEF_32 uint8
-	COEF_33 uint8
-	COEF_34 uint8
-	COEF_35 uint8
-	COEF_36 uint8
-	COEF_37 uint8
-	COEF_38 uint8
-	COEF_39 uint8
-	COEF_3A uint8
-	COEF_3B uint8
-	COEF_3C uint8
-	COEF_3D uint8
-	COEF_3E uint8
-	COEF_3F uint8
-	COEF_40 uint8
-	COEF_41 uint8
-	COEF_42 uint8
-	COEF_43 uint8
-	COEF_44 uint8
-	COEF_45 uint8
+	COEF_89 uint8
+	COEF_8A uint8
+	COEF_8B uint8
+	COEF_8C uint8
+	COEF_8D uint8
+	COEF_8E uint8
+	COEF_8F uint8
+	COEF_90 uint8
+	COEF_91 uint8
+	COEF_92 uint8
+	COEF_93 uint8
+	COEF_94 uint8
+	COEF_95 uint8
+	COEF_96 uint8
+	COEF_97 uint8
+	COEF_98 uint8
+	COEF_99 uint8
+	COEF_9A uint8
+	COEF_9B uint8
+	COEF_9C uint8
+	COEF_9D uint8
+	COEF_9E uint8
+	COEF_9F uint8
+	COEF_A0 uint8
+	COEF_A1 uint8
+
+	COEF_E1 uint8
+	COEF_E2 uint8
+	COEF_E3 uint8
+	COEF_E4 uint8
+	COEF_E5 uint8
+	COEF_E6 uint8
+	COEF_E7 uint8
+	COEF_E8 uint8
+	COEF_E9 uint8
+	COEF_EA uint8
+	COEF_EB uint8
+	COEF_EC uint8
+	COEF_ED uint8
+	COEF_EE uint8
+	COEF_EF uint8
+	COEF_F0 uint8
+
+	COEF_00 uint8
+	COEF_01 uint8
+	COEF_02 uint8
+	COEF_03 uint8
+	COEF_04 uint8
 }
 
 func (v *CoeffBME680) PAR_T1() uint16 {
-	return uint16(v.COEF_32)<<8 | uint16(v.COEF_31)
+	return uint16(v.COEF_EA)<<8 | uint16(v.COEF_E9)
 }
 
-func (v *CoeffBME680) PAR_T2() uint16 {
-	return uint16(uint16(v.COEF_34)<<8 | uint16(v.COEF_33))
+func (v *CoeffBME680) PAR_T2() int16 {
+	return int16(uint16(v.COEF_8B)<<8 | uint16(v.COEF_8A))
 }
 
 func (v *CoeffBME680) PAR_T3() int8 {
-	return int8(v.COEF_35)
+	return int8(v.COEF_8C)
 }
 
-func (v *CoeffBME680) PAR_P1() int16 {
-	return int16(uint16(v.COEF_37)<<8 | uint16(v.COEF_36))
+func (v *CoeffBME680) PAR_P1() uint16 {
+	return uint16(v.COEF_8F)<<8 | uint16(v.COEF_8E)
 }
 
 func (v *CoeffBME680) PAR_P2() int16 {
-	return int16(uint16(v.COEF_39)<<8 | uint16(v.COEF_38))
+	return int16(uint16(v.COEF_91)<<8 | uint16(v.COEF_90))
 }
 
 func (v *CoeffBME680) PAR_P3() int8 {
-	return int8(uint16(v.COEF_3A))
+	return int8(v.COEF_92)
 }
 
-func (v *CoeffBME680) PAR_P4() int8 {
-	return int8(uint16(v.COEF_3B))
+func (v *CoeffBME680) PAR_P4() int16 {
+	return int16(uint16(v.COEF_95)<<8 | uint16(v.COEF_94))
 }
 
-func (v *CoeffBME680) PAR_P5() uint16 {
-	return uint16(uint16(v.COEF_3D)<<8 | uint16(v.COEF_3C))
+func (v *CoeffBME680) PAR_P5() int16 {
+	return int16(uint16(v.COEF_97)<<8 | uint16(v.COEF_96))
 }
 
-func (v *CoeffBME680) PAR_P6() uint16 {
-	return uint16(uint16(v.COEF_3F)<<8 | uint16(v.COEF_3E))
+func (v *CoeffBME680) PAR_P6() int8 {
+	return int8(v.COEF_99)
 }
 
 func (v *CoeffBME680) PAR_P7() int8 {
-	return int8(uint16(v.COEF_40))
+	return int8(v.COEF_98)
 }
 
-func (v *CoeffBME680) PAR_P8() int8 {
-	return int8(uint16(v.COEF_41))
+func (v *CoeffBME680) PAR_P8() int16 {
+	return int16(uint16(v.COEF_9D)<<8 | uint16(v.COEF_9C))
 }
 
 func (v *CoeffBME680) PAR_P9() int16 {
-	return int16(uint16(v.COEF_43)<<8 | uint16(v.COEF_42))
+	return int16(uint16(v.COEF_9F)<<8 | uint16(v.COEF_9E))
+}
+
+func (v *CoeffBME680) PAR_P10() uint8 {
+	return v.COEF_A0
+}
+
+// PAR_H1 is 12-bit value sharing register 0xE2 with PAR_H2.
+func (v *CoeffBME680) PAR_H1() uint16 {
+	return uint16(v.COEF_E3)<<4 | uint16(v.COEF_E2&0x0F)
+}
+
+// PAR_H2 is 12-bit value sharing register 0xE2 with PAR_H1.
+func (v *CoeffBME680) PAR_H2() uint16 {
+	return uint16(v.COEF_E1)<<4 | uint16(v.COEF_E2>>4)
+}
+
+func (v *CoeffBME680) PAR_H3() int8 {
+	return int8(v.COEF_E4)
+}
+
+func (v *CoeffBME680) PAR_H4() int8 {
+	return int8(v.COEF_E5)
+}
+
+func (v *CoeffBME680) PAR_H5() int8 {
+	return int8(v.COEF_E6)
+}
+
+func (v *CoeffBME680) PAR_H6() uint8 {
+	return v.COEF_E7
+}
+
+func (v *CoeffBME680) PAR_H7() int8 {
+	return int8(v.COEF_E8)
+}
+
+func (v *CoeffBME680) PAR_GH1() int8 {
+	return int8(v.COEF_ED)
+}
+
+func (v *CoeffBME680) PAR_GH2() int16 {
+	return int16(uint16(v.COEF_EC)<<8 | uint16(v.COEF_EB))
+}
+
+func (v *CoeffBME680) PAR_GH3() int8 {
+	return int8(v.COEF_EE)
 }
 
-func (v *CoeffBME680) PAR_P10() int8 {
-	return int8(uint16(v.COEF_44))
+// RES_HEAT_VAL is heater resistance correction factor.
+func (v *CoeffBME680) RES_HEAT_VAL() int8 {
+	return int8(v.COEF_00)
 }
 
-func (v *CoeffBME680) PAR_P11() int8 {
-	return int8(uint16(v.COEF_45))
+// RES_HEAT_RANGE is heater resistance range, bits 5:4 of register 0x02.
+func (v *CoeffBME680) RES_HEAT_RANGE() uint8 {
+	return (v.COEF_02 >> 4) & 0x03
 }
 
-// SensorBME680 specific type
+// RANGE_SW_ERR is signed gas resistance range switching error,
+// bits 7:4 of register 0x04.
+func (v *CoeffBME680) RANGE_SW_ERR() int8 {
+	return int8(v.COEF_04) >> 4
+}
+
+// Gas resistance calculation lookup tables
+// from Bosch Sensortec BME680 API (integer version).
+var bme680GasLookupTable1 = [16]int64{2147483647, 2147483647, 2147483647, 2147483647,
+	2147483647, 2126008810, 2147483647, 2130303777, 2147483647, 2147483647,
+	2143188679, 2136746228, 2147483647, 2126008810, 2147483647, 2147483647}
+var bme680GasLookupTable2 = [16]int64{4096000000, 2048000000, 1024000000, 512000000,
+	255744255, 127110228, 64000000, 32258064, 16016016, 8000000,
+	4000000, 2000000, 1000000, 500000, 250000, 125000}
+
+// SensorBME680 specific type. It drives BME688 as well, which
+// has the same register map, extended with parallel mode.
 type SensorBME680 struct {
 	Coeff *CoeffBME680
+	// BME680 or BME688
+	variant SensorType
+	// Operating mode, forced by default
+	mode PowerMode
+	// Heater profile used in parallel mode
+	profile *HeaterProfile
+	// Sequence number of the last field returned in parallel mode
+	lastMeasIndex uint8
+	lastMeasValid bool
+	// Gas sensor hot plate target temperature in C (celsius),
+	// gas measurement is disabled if zero.
+	heaterTemp int
+	// Hot plate heating duration.
+	heaterDuration time.Duration
+	// Latest measured temperature in C (celsius), used
+	// to calculate heater resistance.
+	ambientTemp  int32
+	ambientValid bool
 }
 
 // Static cast to verify at compile time
 // that type implement interface.
 var _ SensorInterface = &SensorBME680{}
+var _ GasHeaterInterface = &SensorBME680{}
+var _ PowerModeInterface = &SensorBME680{}
+var _ ParallelModeInterface = &SensorBME680{}
+
+// Variant return sensor model driven: BME680 or BME688.
+func (v *SensorBME680) Variant() SensorType {
+	if v.variant == BME688 {
+		return BME688
+	}
+	return BME680
+}
+
+// isHighGasVariant return true for sensors reporting high gas variant
+// (BME688), which use different gas measurement registers and formula.
+func (v *SensorBME680) isHighGasVariant() bool {
+	return v.Coeff != nil && v.Coeff.COEF_F0 == BME680_VARIANT_GAS_HIGH
+}
 
 // ReadSensorID reads sensor signature. It may be used for validation,
 // that proper code settings used for sensor data decoding.
-func (v *SensorBME680) ReadSensorID(i2c *i2c.I2C) (uint8, error) {
-	id, err := i2c.ReadRegU8(BME680_ID_REG)
+func (v *SensorBME680) ReadSensorID(bus Bus) (uint8, error) {
+	id, err := bus.ReadRegU8(BME680_ID_REG)
 	if err != nil {
 		return 0, err
 	}
@@ -170,33 +314,63 @@ func (v *SensorBME680) ReadSensorID(i2c *i2c.I2C) (uint8, error) {
 }
 
 // ReadCoefficients reads compensation coefficients, unique for each sensor.
-func (v *SensorBME680) ReadCoefficients(i2c *i2c.I2C) error {
-	_, err := i2c.WriteBytes([]byte{BME680_COEF_START})
+func (v *SensorBME680) ReadCoefficients(bus Bus) error {
+	// read coefficients #1
+	var coef1 [BME680_COEF_PART1_BYTES]byte
+	err := readDataToStruct(bus, BME680_COEF_PART1_START, BME680_COEF_PART1_BYTES,
+		binary.LittleEndian, &coef1)
 	if err != nil {
 		return err
 	}
-	var coef1 [BME680_COEF_BYTES]byte
-	err = readDataToStruct(i2c, BME680_COEF_BYTES,
-		binary.LittleEndian, &coef1)
+
+	// read coefficients #2
+	var coef2 [BME680_COEF_PART2_BYTES]byte
+	err = readDataToStruct(bus, BME680_COEF_PART2_START, BME680_COEF_PART2_BYTES,
+		binary.LittleEndian, &coef2)
+	if err != nil {
+		return err
+	}
+
+	// read heater and gas range parameters
+	var coef3 [BME680_COEF_PART3_BYTES]byte
+	err = readDataToStruct(bus, BME680_COEF_PART3_START, BME680_COEF_PART3_BYTES,
+		binary.LittleEndian, &coef3)
 	if err != nil {
 		return err
 	}
-	buf := bytes.NewBuffer(coef1[:])
+
+	// combine coefficients altogether in single structure
+	arr := coef1[:]
+	arr = append(arr, coef2[:]...)
+	arr = append(arr, coef3[:]...)
+	buf := bytes.NewBuffer(arr)
 	coeff := &CoeffBME680{}
 	err = binary.Read(buf, binary.LittleEndian, coeff)
 	if err != nil {
 		return err
 	}
+	// variant_id register 0xF0 is read along with coefficients
+	if v.Variant() == BME688 && coeff.COEF_F0 != BME680_VARIANT_GAS_HIGH {
+		return &SignatureError{Sensor: BME688, Signature: coeff.COEF_F0}
+	}
 	v.Coeff = coeff
 	return nil
 }
 
+// loadCoefficients reads compensation coefficients only once,
+// since they never change. Use ReadCoefficients to force reload.
+func (v *SensorBME680) loadCoefficients(bus Bus) error {
+	if v.Coeff == nil {
+		return v.ReadCoefficients(bus)
+	}
+	return nil
+}
+
 // IsValidCoefficients verify that compensate registers
 // are not empty, and thus are valid.
 func (v *SensorBME680) IsValidCoefficients() error {
-	// TODO:  research a better test for valid Coef.  Refeence code doesn't check
 	if v.Coeff != nil {
-		err := checkCoefficient(uint16(v.Coeff.PAR_T1()), "PAR_T1")
+		err := checkCoefficient(v.Coeff.PAR_T1(), "PAR_T1")
 		if err != nil {
 			return err
 		}
@@ -204,11 +378,7 @@ func (v *SensorBME680) IsValidCoefficients() error {
 		if err != nil {
 			return err
 		}
-		err = checkCoefficient(uint16(v.Coeff.PAR_T3()), "PAR_T3")
-		if err != nil {
-			return err
-		}
-		err = checkCoefficient(uint16(v.Coeff.PAR_P1()), "PAR_P1")
+		err = checkCoefficient(v.Coeff.PAR_P1(), "PAR_P1")
 		if err != nil {
 			return err
 		}
@@ -216,39 +386,15 @@ func (v *SensorBME680) IsValidCoefficients() error {
 		if err != nil {
 			return err
 		}
-		err = checkCoefficient(uint16(v.Coeff.PAR_P3()), "PAR_P3")
+		err = checkCoefficient(v.Coeff.PAR_H1(), "PAR_H1")
 		if err != nil {
 			return err
 		}
-		//		err = checkCoefficient(uint16(v.Coeff.PAR_P4()), "PAR_P4")
-		//		if err != nil {
-		//			return err
-		//		}
-		err = checkCoefficient(uint16(v.Coeff.PAR_P5()), "PAR_P5")
+		err = checkCoefficient(v.Coeff.PAR_H2(), "PAR_H2")
 		if err != nil {
 			return err
 		}
-		err = checkCoefficient(uint16(v.Coeff.PAR_P6()), "PAR_P6")
-		if err != nil {
-			return err
-		}
-		err = checkCoefficient(uint16(v.Coeff.PAR_P7()), "PAR_P7")
-		if err != nil {
-			return err
-		}
-		err = checkCoefficient(uint16(v.Coeff.PAR_P8()), "PAR_P8")
-		if err != nil {
-			return err
-		}
-		err = checkCoefficient(uint16(v.Coeff.PAR_P9()), "PAR_P9")
-		if err != nil {
-			return err
-		}
-		err = checkCoefficient(uint16(v.Coeff.PAR_P10()), "PAR_P10")
-		if err != nil {
-			return err
-		}
-		err = checkCoefficient(uint16(v.Coeff.PAR_P11()), "PAR_P11")
+		err = checkCoefficient(uint16(v.Coeff.PAR_GH2()), "PAR_GH2")
 		if err != nil {
 			return err
 		}
@@ -256,20 +402,6 @@ func (v *SensorBME680) IsValidCoefficients() error {
 		err := errors.New("CoeffBME680 struct does not build")
 		return err
 	}
-	lg.Debugf("PAR_T1:%v", v.Coeff.PAR_T1())
-	lg.Debugf("PAR_T2:%v", v.Coeff.PAR_T2())
-	lg.Debugf("PAR_T3:%v", v.Coeff.PAR_T3())
-	lg.Debugf("PAR_P1:%v", v.Coeff.PAR_P1())
-	lg.Debugf("PAR_P2:%v", v.Coeff.PAR_P2())
-	lg.Debugf("PAR_P3:%v", v.Coeff.PAR_P3())
-	lg.Debugf("PAR_P4:%v", v.Coeff.PAR_P4())
-	lg.Debugf("PAR_P5:%v", v.Coeff.PAR_P5())
-	lg.Debugf("PAR_P6:%v", v.Coeff.PAR_P6())
-	lg.Debugf("PAR_P7:%v", v.Coeff.PAR_P7())
-	lg.Debugf("PAR_P8:%v", v.Coeff.PAR_P8())
-	lg.Debugf("PAR_P9:%v", v.Coeff.PAR_P9())
-	lg.Debugf("PAR_P10:%v", v.Coeff.PAR_P10())
-	lg.Debugf("PAR_P11:%v", v.Coeff.PAR_P11())
 	return nil
 }
 
@@ -277,272 +409,428 @@ func (v *SensorBME680) IsValidCoefficients() error {
 // otherwise - error.
 func (v *SensorBME680) RecognizeSignature(signature uint8) (string, error) {
 	switch signature {
-	case 0x25:
-		return "BME680", nil
+	case 0x61:
+		// BME688 has the same chip identifier as BME680,
+		// they differ by variant identifier
+		return v.Variant().String(), nil
 	default:
-		return "", errors.New(fmt.Sprintf("signature 0x%x doesn't belong to BME680 series", signature))
+		return "", &SignatureError{Sensor: v.Variant(), Signature: signature}
 	}
 }
 
-// IsBusy reads register 0xF3 for "busy" flag,
-// according to sensor specification.
-//  BME680 has three separate busy/done flags - pres, temp, and cmd
-//  this routine is called by a 'waitFor Completion' shared by the other BMP parts, which all have a combined
-//    busy/done bit.
-//    for now - we return TRUE when any of the done bits go true
-//   TODO: break out the busy polling
-func (v *SensorBME680) IsBusy(i2c *i2c.I2C) (busy bool, err error) {
-	// Check flag to know status of calculation, according
-	// to specification about SCO (Start of conversion) flag
-	b, err := i2c.ReadRegU8(BME680_STATUS_REG)
+// IsBusy reads register 0x1D for "measuring" and "gas_measuring"
+// flags, according to sensor specification.
+func (v *SensorBME680) IsBusy(bus Bus) (busy bool, err error) {
+	b, err := bus.ReadRegU8(BME680_MEAS_STATUS_REG)
 	if err != nil {
 		return false, err
 	}
+	b = b & 0x60
 	lg.Debugf("Busy flag=0x%0X", b)
-	b = b & 0x60 // ignore cmd done
-	return b == 0, nil
+	return b != 0, nil
+}
+
+// SetIIRFilter setup IIR filter coefficient in config register,
+// keeping SPI 3-wire setting unchanged.
+func (v *SensorBME680) SetIIRFilter(bus Bus, filter IIRFilter) error {
+	if filter < IIR_FILTER_OFF || filter > IIR_FILTER_128 {
+		return &NotSupportedError{Sensor: v.Variant(), Feature: fmt.Sprintf("IIR filter %v", filter)}
+	}
+	return updateRegU8(bus, BME680_CONFIG, 0x7<<2, byte(filter)<<2)
+}
+
+// SetGasHeater setup gas sensor hot plate target temperature in C (celsius)
+// in range [200..400] and heating duration up to 4032 ms, using heater
+// set-point 0. Gas resistance is measured afterwards by Measure call.
+// Zero temperature or duration turn heater off and disable gas measurement.
+func (v *SensorBME680) SetGasHeater(bus Bus, temperature int, duration time.Duration) error {
+	if temperature == 0 || duration == 0 {
+		v.heaterTemp = 0
+		v.heaterDuration = 0
+		// heat_off
+		return bus.WriteRegU8(BME680_CTRL_GAS_0, 0x08)
+	}
+	if temperature < 200 || temperature > 400 {
+		return errors.New(fmt.Sprintf("heater temperature %d*C is out of range [200..400]", temperature))
+	}
+	if duration < time.Millisecond || duration > 4032*time.Millisecond {
+		return errors.New(fmt.Sprintf("heater duration %v is out of range [1ms..4032ms]", duration))
+	}
+	err := bus.WriteRegU8(BME680_GAS_WAIT_0, bme680HeaterDuration(duration))
+	if err != nil {
+		return err
+	}
+	err = bus.WriteRegU8(BME680_CTRL_GAS_0, 0x00)
+	if err != nil {
+		return err
+	}
+	v.heaterTemp = temperature
+	v.heaterDuration = duration
+	return nil
+}
+
+// SetPowerMode switch sensor to power mode specified. BME680 doesn't have
+// normal mode, so only sleep and forced modes are available, while BME688
+// supports parallel mode as well, which requires heater profile set up
+// by SetHeaterProfile. Accuracy from settings is used for all samples
+// in parallel mode.
+func (v *SensorBME680) SetPowerMode(bus Bus, mode PowerMode, settings MeasureSettings) error {
+	err := v.loadCoefficients(bus)
+	if err != nil {
+		return err
+	}
+	switch mode {
+	case POWER_MODE_SLEEP, POWER_MODE_FORCED:
+		// forced conversion is triggered on each read,
+		// sensor stays in sleep mode meanwhile
+	case POWER_MODE_PARALLEL:
+		if !v.isHighGasVariant() {
+			return &NotSupportedError{Sensor: v.Variant(), Feature: fmt.Sprintf("power mode %v", mode)}
+		}
+		if v.profile == nil {
+			return errors.New("heater profile should be set up before entering parallel mode")
+		}
+	default:
+		return &NotSupportedError{Sensor: v.Variant(), Feature: fmt.Sprintf("power mode %v", mode)}
+	}
+	osrt := v.getOversamplingRation(settings.Temperature)
+	osrp := v.getOversamplingRation(settings.Pressure)
+	osrh := v.getOversamplingRation(settings.Humidity)
+	// go through sleep mode, to change configuration
+	err = bus.WriteRegU8(BME680_CTRL_MEAS, BME680_PWR_MODE_SLEEP|(osrt<<5)|(osrp<<2))
+	if err != nil {
+		return err
+	}
+	if mode == POWER_MODE_PARALLEL {
+		// run_gas with all heater profile steps
+		err = bus.WriteRegU8(BME680_CTRL_GAS_1, v.runGas()|byte(len(v.profile.Steps)))
+		if err != nil {
+			return err
+		}
+		err = bus.WriteRegU8(BME680_CTRL_HUM, osrh)
+		if err != nil {
+			return err
+		}
+		err = bus.WriteRegU8(BME680_CTRL_MEAS, BME680_PWR_MODE_PARALLEL|(osrt<<5)|(osrp<<2))
+		if err != nil {
+			return err
+		}
+		v.lastMeasValid = false
+	}
+	v.mode = mode
+	return nil
+}
+
+// bme680HeaterDuration encode heating duration to gas_wait_x register value:
+// 6-bit timer value with multiplication factor 1, 4, 16 or 64 in bits 7:6.
+func bme680HeaterDuration(duration time.Duration) byte {
+	ms := uint32(duration / time.Millisecond)
+	if ms >= 0xFC0 {
+		// max duration
+		return 0xFF
+	}
+	var factor byte
+	for ms > 0x3F {
+		ms /= 4
+		factor++
+	}
+	return byte(ms) + factor*64
+}
+
+// heaterResistance calculates res_heat_x register value for
+// hot plate target temperature in C (celsius), taking into account
+// ambient temperature, according to Bosch Sensortec BME680 API.
+func (v *SensorBME680) heaterResistance(temperature int) byte {
+	if temperature > 400 {
+		temperature = 400
+	}
+	var1 := ((v.ambientTemp * int32(v.Coeff.PAR_GH3())) / 1000) * 256
+	var2 := (int32(v.Coeff.PAR_GH1()) + 784) * (((((int32(v.Coeff.PAR_GH2()) + 154009) *
+		int32(temperature) * 5) / 100) + 3276800) / 10)
+	var3 := var1 + (var2 / 2)
+	var4 := var3 / (int32(v.Coeff.RES_HEAT_RANGE()) + 4)
+	var5 := (131 * int32(v.Coeff.RES_HEAT_VAL())) + 65536
+	heatrResX100 := ((var4 / var5) - 250) * 34
+	heatrRes := byte((heatrResX100 + 50) / 100)
+	lg.Debugf("res_heat=%v", heatrRes)
+	return heatrRes
 }
 
 func (v *SensorBME680) getOversamplingRation(accuracy AccuracyMode) byte {
 	var b byte
 	switch accuracy {
 	case ACCURACY_ULTRA_LOW:
-		b = 0
-	case ACCURACY_LOW:
 		b = 1
-	case ACCURACY_STANDARD:
+	case ACCURACY_LOW:
 		b = 2
-	case ACCURACY_HIGH:
+	case ACCURACY_STANDARD:
 		b = 3
-	case ACCURACY_ULTRA_HIGH:
+	case ACCURACY_HIGH:
 		b = 4
-	case ACCURACY_HIGHEST:
+	case ACCURACY_ULTRA_HIGH:
 		b = 5
 	default:
 		// assign accuracy to lowest resolution by default
-		b = 0
+		b = 1
 	}
 	return b
 }
 
-// readUncompTemprature reads uncompensated temprature from sensor.
-func (v *SensorBME680) readUncompTemprature(i2c *i2c.I2C, accuracy AccuracyMode) (int32, error) {
-	//  set IIR filter to bypass
-	err := i2c.WriteRegU8(BME680_CONFIG, BME680_coef_0<<1)
-	if err != nil {
-		return 0, err
+// measurementTime estimates duration of measurement cycle
+// for oversampling specified, according to Bosch Sensortec BME680 API.
+func (v *SensorBME680) measurementTime(osrt, osrp, osrh byte, gas bool) time.Duration {
+	cycles := [...]int{0, 1, 2, 4, 8, 16}
+	n := cycles[osrt] + cycles[osrp] + cycles[osrh]
+	// conversion, TPH switching and gas measurement
+	us := n*1963 + 477*4 + 477*5
+	// wake up
+	d := time.Duration(us)*time.Microsecond + time.Millisecond
+	if gas {
+		d += v.heaterDuration
 	}
-	//   set over sample rate to 1x
-	osrt := v.getOversamplingRation(accuracy)
-	err = i2c.WriteRegU8(BME680_OSR_REG, (osrt<<5) | 0x01)
+	return d
+}
+
+// measure runs forced measurement cycle with oversampling specified, optionally
+// including gas resistance measurement, and reads out field 0 data block.
+// In parallel mode the latest data field is read out instead.
+func (v *SensorBME680) measure(bus Bus, osrt, osrp, osrh byte, gas bool) (*Measurement, error) {
+	err := v.loadCoefficients(bus)
 	if err != nil {
-		return 0, err
+		return nil, err
 	}
-	/*
-	// enable pres and temp measurement, start a measurment
-	var power byte = (BME680_PWR_MODE_FORCED << 4) | 3 // enable pres, temp, FORCED operating mode
-	lg.Debugf("power=0x%0X", power)
-	err = i2c.WriteRegU8(BME680_PWR_CTRL_REG, power)
-	if err != nil {
-		return 0, err
+	if v.mode == POWER_MODE_PARALLEL {
+		return v.readLatestField(bus)
 	}
-
-
-	_, err = waitForCompletion(v, i2c)
-	if err != nil {
-		return 0, err
+	gas = gas && v.heaterTemp != 0
+	var ctrlGas byte
+	if gas {
+		if !v.ambientValid {
+			// assume room temperature until first measurement
+			v.ambientTemp = 25
+		}
+		err = bus.WriteRegU8(BME680_RES_HEAT_0, v.heaterResistance(v.heaterTemp))
+		if err != nil {
+			return nil, err
+		}
+		// run_gas with heater set-point 0
+		ctrlGas = v.runGas()
 	}
-
-	 */
-
-
-	buf, _, err := i2c.ReadRegBytes(BME680_TEMP_OUT_MSB_LSB_XLSB, 3)
+	err = bus.WriteRegU8(BME680_CTRL_GAS_1, ctrlGas)
 	if err != nil {
-		return 0, err
+		return nil, err
 	}
-	ut := int32(uint32(buf[0]) + uint32(buf[1])<<8 + uint32(buf[2])<<16)
-	return ut, nil
-}
-
-// readUncompPressure reads atmospheric uncompensated pressure from sensor.
-func (v *SensorBME680) readUncompPressure(i2c *i2c.I2C, accuracy AccuracyMode) (int32, error) {
-	var power byte = (BME680_PWR_MODE_FORCED << 4) | 3 // enable pres, temp, FORCED operating mode
-	err := i2c.WriteRegU8(BME680_PWR_CTRL_REG, power)
+	// Changes to ctrl_hum register become effective
+	// only after a write operation to ctrl_meas register
+	err = bus.WriteRegU8(BME680_CTRL_HUM, osrh)
 	if err != nil {
-		return 0, err
+		return nil, err
 	}
-	_, err = waitForCompletion(v, i2c)
+	var power byte = BME680_PWR_MODE_FORCED
+	err = bus.WriteRegU8(BME680_CTRL_MEAS, power|(osrt<<5)|(osrp<<2))
 	if err != nil {
-		return 0, err
+		return nil, err
 	}
-	osrp := v.getOversamplingRation(accuracy)
-	err = i2c.WriteRegU8(BME680_OSR_REG, osrp)
+	// heating might take up to several seconds,
+	// so sleep estimated time before polling
+	err = sleep(bus, v.measurementTime(osrt, osrp, osrh, gas))
 	if err != nil {
-		return 0, err
+		return nil, err
 	}
-	_, err = waitForCompletion(v, i2c)
+	err = waitForCompletion(v, bus)
 	if err != nil {
-		return 0, err
+		return nil, err
+	}
+	n := BME680_FIELD0_BYTES
+	if v.isHighGasVariant() {
+		n = BME688_FIELD_BYTES
 	}
-	buf, _, err := i2c.ReadRegBytes(BME680_PRES_OUT_MSB_LSB_XLSB, 3)
+	buf, _, err := bus.ReadRegBytes(BME680_FIELD0_START, n)
 	if err != nil {
-		return 0, err
+		return nil, err
 	}
-	up := int32(buf[0]) + int32(buf[1])<<8 + int32(buf[2])<<16
-	return up, nil
+	return v.decodeField(buf, gas), nil
 }
 
-// readUncompTempratureAndPressure reads temprature and
-// atmospheric uncompensated pressure from sensor.
-// BME680 allows to read temprature and pressure in one cycle,
-// BMP180 - doesn't.
-func (v *SensorBME680) readUncompTempratureAndPressure(i2c *i2c.I2C,
-	accuracy AccuracyMode) (temprature int32, pressure int32, err error) {
-	var power byte = (BME680_PWR_MODE_FORCED << 4) | 3 // enable pres, temp, FORCED operating mode
-	err = i2c.WriteRegU8(BME680_PWR_CTRL_REG, power)
-	if err != nil {
-		return 0, 0, err
-	}
-	_, err = waitForCompletion(v, i2c)
-	if err != nil {
-		return 0, 0, err
+// runGas return ctrl_gas_1 register run_gas bits value enabling
+// gas measurement, which differ for high gas variant (BME688).
+func (v *SensorBME680) runGas() byte {
+	if v.isHighGasVariant() {
+		return 0x20
 	}
-	osrt := v.getOversamplingRation(ACCURACY_STANDARD)
-	osrp := v.getOversamplingRation(accuracy)
-	err = i2c.WriteRegU8(BME680_OSR_REG, (osrt<<3)|osrp)
-	if err != nil {
-		return 0, 0, err
-	}
-	_, err = waitForCompletion(v, i2c)
-	if err != nil {
-		return 0, 0, err
+	return 0x10
+}
+
+// decodeField compensates values read out from data field, and
+// updates ambient temperature used for heater resistance calculation.
+func (v *SensorBME680) decodeField(buf []byte, gas bool) *Measurement {
+	lg.Debugf("meas_status=0x%0X", buf[0])
+	m := &Measurement{Time: time.Now(), HumiditySupported: true, GasSupported: gas}
+	m.GasIndex = buf[0] & 0x0F
+	m.MeasIndex = buf[1]
+	m.Raw.Pressure = getU20BE(buf[2:5])
+	m.Raw.Temperature = getU20BE(buf[5:8])
+	m.Raw.Humidity = int32(getU16BE(buf[8:10]))
+	lg.Debugf("ut=%v, up=%v, uh=%v", m.Raw.Temperature, m.Raw.Pressure, m.Raw.Humidity)
+	t, tFine := v.compensateTemperature(m.Raw.Temperature)
+	m.TemperatureMult100C = t
+	m.PressureMult10Pa = v.compensatePressure(m.Raw.Pressure, tFine)
+	m.HumidityMultQ2210 = v.compensateHumidity(m.Raw.Humidity, tFine)
+	v.ambientTemp = t / 100
+	v.ambientValid = true
+	if gas {
+		gasBuf := buf[13:15]
+		if v.isHighGasVariant() {
+			gasBuf = buf[15:17]
+		}
+		m.Raw.Gas = int32(gasBuf[0])<<2 | int32(gasBuf[1]>>6)
+		m.Raw.GasRange = gasBuf[1] & 0x0F
+		m.GasValid = gasBuf[1]&0x20 != 0
+		m.GasHeaterStable = gasBuf[1]&0x10 != 0
+		lg.Debugf("gas_adc=%v, gas_range=%v, gas_valid=%v, heat_stab=%v",
+			m.Raw.Gas, m.Raw.GasRange, m.GasValid, m.GasHeaterStable)
+		if m.GasValid {
+			if v.isHighGasVariant() {
+				m.GasResistance = v.compensateGasResistanceHigh(m.Raw.Gas, m.Raw.GasRange)
+			} else {
+				m.GasResistance = v.compensateGasResistance(m.Raw.Gas, m.Raw.GasRange)
+			}
+		}
 	}
-	buf, _, err := i2c.ReadRegBytes(BME680_TEMP_OUT_MSB_LSB_XLSB, 3)
-	if err != nil {
-		return 0, 0, err
+	return m
+}
+
+// compensateTemperature calculates temrature in C (celsius) multiplied by 100
+// from uncompensated value ut. Returns t_fine as well, required to compensate
+// pressure and humidity.
+func (v *SensorBME680) compensateTemperature(ut int32) (t int32, tFine int32) {
+	var1 := (ut >> 3) - (int32(v.Coeff.PAR_T1()) << 1)
+	var2 := (var1 * int32(v.Coeff.PAR_T2())) >> 11
+	var3 := ((var1 >> 1) * (var1 >> 1)) >> 12
+	var3 = (var3 * (int32(v.Coeff.PAR_T3()) << 4)) >> 14
+	tFine = var2 + var3
+	lg.Debugf("t_fine=%v", tFine)
+	t = (tFine*5 + 128) >> 8
+	return t, tFine
+}
+
+// compensatePressure calculates atmospheric pressure in Pa (Pascal) multiplied by 10
+// from uncompensated value up and t_fine obtained from temperature compensation.
+func (v *SensorBME680) compensatePressure(up int32, tFine int32) uint32 {
+	var1 := (tFine >> 1) - 64000
+	var2 := ((((var1 >> 2) * (var1 >> 2)) >> 11) * int32(v.Coeff.PAR_P6())) >> 2
+	var2 = var2 + ((var1 * int32(v.Coeff.PAR_P5())) << 1)
+	var2 = (var2 >> 2) + (int32(v.Coeff.PAR_P4()) << 16)
+	var1 = (((((var1 >> 2) * (var1 >> 2)) >> 13) * (int32(v.Coeff.PAR_P3()) << 5)) >> 3) +
+		((int32(v.Coeff.PAR_P2()) * var1) >> 1)
+	var1 = var1 >> 18
+	var1 = ((32768 + var1) * int32(v.Coeff.PAR_P1())) >> 15
+	lg.Debugf("var1=%v, var2=%v", var1, var2)
+	if var1 == 0 {
+		return 0
 	}
-	ut := int32(buf[0]) + int32(buf[1])<<8 + int32(buf[2])<<16
-	buf, _, err = i2c.ReadRegBytes(BME680_PRES_OUT_MSB_LSB_XLSB, 3)
-	if err != nil {
-		return 0, 0, err
+	// calculated in 64 bit, since reference code 32 bit version
+	// overflow at low temperature and high pressure
+	p64 := (int64(1048576-up) - int64(var2>>12)) * 3125
+	p := int32((p64 << 1) / int64(var1))
+	var1 = (int32(v.Coeff.PAR_P9()) * (((p >> 3) * (p >> 3)) >> 13)) >> 12
+	var2 = ((p >> 2) * int32(v.Coeff.PAR_P8())) >> 13
+	// cube of p>>8 multiplied by PAR_P10 exceed int32 above ~106 kPa
+	p8 := int64(p >> 8)
+	var3 := int32((p8 * p8 * p8 * int64(v.Coeff.PAR_P10())) >> 17)
+	p = p + ((var1 + var2 + var3 + (int32(v.Coeff.PAR_P7()) << 7)) >> 4)
+	return uint32(p) * 10
+}
+
+// compensateHumidity calculates humidity in %RH multiplied by 1024 from
+// uncompensated value uh and t_fine obtained from temperature compensation.
+func (v *SensorBME680) compensateHumidity(uh int32, tFine int32) uint32 {
+	tempScaled := (tFine*5 + 128) >> 8
+	var1 := (uh - int32(v.Coeff.PAR_H1())*16) -
+		(((tempScaled * int32(v.Coeff.PAR_H3())) / 100) >> 1)
+	var2 := (int32(v.Coeff.PAR_H2()) * (((tempScaled * int32(v.Coeff.PAR_H4())) / 100) +
+		(((tempScaled * ((tempScaled * int32(v.Coeff.PAR_H5())) / 100)) >> 6) / 100) +
+		(1 << 14))) >> 10
+	var3 := var1 * var2
+	var4 := int32(v.Coeff.PAR_H6()) << 7
+	var4 = (var4 + ((tempScaled * int32(v.Coeff.PAR_H7())) / 100)) >> 4
+	var5 := ((var3 >> 14) * (var3 >> 14)) >> 10
+	var6 := (var4 * var5) >> 1
+	// humidity in %RH multiplied by 1000
+	h := (((var3 + var6) >> 10) * 1000) >> 12
+	lg.Debugf("h=%v", h)
+	if h > 100000 {
+		h = 100000
+	} else if h < 0 {
+		h = 0
 	}
-	up := int32(buf[0]) + int32(buf[1])<<8 + int32(buf[2])<<16
-	return ut, up, nil
+	return uint32(h) * 1024 / 1000
+}
+
+// compensateGasResistance calculates gas resistance in Ohm
+// from uncompensated ADC value and gas range.
+func (v *SensorBME680) compensateGasResistance(adc int32, gasRange uint8) uint32 {
+	var1 := ((1340 + 5*int64(v.Coeff.RANGE_SW_ERR())) * bme680GasLookupTable1[gasRange]) >> 16
+	var2 := (int64(adc) << 15) - 16777216 + var1
+	var3 := (bme680GasLookupTable2[gasRange] * var1) >> 9
+	r := uint32((var3 + (var2 >> 1)) / var2)
+	lg.Debugf("gas_res=%v", r)
+	return r
+}
+
+// compensateGasResistanceHigh calculates gas resistance in Ohm from
+// uncompensated ADC value and gas range of high gas variant (BME688).
+func (v *SensorBME680) compensateGasResistanceHigh(adc int32, gasRange uint8) uint32 {
+	var1 := uint32(262144) >> gasRange
+	var2 := 4096 + (adc-512)*3
+	// multiply by 10000, then by 100 instead of 1000000 to prevent overflow
+	r := 10000 * var1 / uint32(var2) * 100
+	lg.Debugf("gas_res=%v", r)
+	return r
 }
 
 // ReadTemperatureMult100C reads and calculates temperature in C (celsius) multiplied by 100.
 // Multiplication approach allow to keep result as integer number.
-func (v *SensorBME680) ReadTemperatureMult100C(i2c *i2c.I2C, accuracy AccuracyMode) (int32, error) {
-
-	ut, err := v.readUncompTemprature(i2c, accuracy)
-	if err != nil {
-		return 0, err
-	}
-	err = v.ReadCoefficients(i2c)
+func (v *SensorBME680) ReadTemperatureMult100C(bus Bus, accuracy AccuracyMode) (int32, error) {
+	osrt := v.getOversamplingRation(accuracy)
+	m, err := v.measure(bus, osrt, 0, 0, false)
 	if err != nil {
 		return 0, err
 	}
-
-	//  comp formula - taken from BMP3 API on github
-	partial_data1 := uint64(ut - int32(256*int32(v.Coeff.PAR_T1())))
-	partial_data2 := uint64(v.Coeff.PAR_T2()) * partial_data1
-	partial_data3 := partial_data1 * partial_data1
-	partial_data4 := int64(partial_data3) * int64(v.Coeff.PAR_T3())
-	partial_data5 := (int64(partial_data2*262144) + partial_data4)
-	partial_data6 := partial_data5 / 4294967269
-	t := int32(partial_data6 * 25 / 16384)
-	lg.Debugf("ut=%v", ut)
-	lg.Debugf("d1=%v ", partial_data1)
-	lg.Debugf("p_d2=%v ", partial_data2)
-	lg.Debugf("p_d3=%v ", partial_data3)
-	lg.Debugf("p_d4=%v ", partial_data4)
-	lg.Debugf("p_d5=%v ", partial_data5)
-	lg.Debugf("p_d6=%v ", partial_data6)
-	return t, nil
-
+	return m.TemperatureMult100C, nil
 }
 
 // ReadPressureMult10Pa reads and calculates atmospheric pressure in Pa (Pascal) multiplied by 10.
 // Multiplication approach allow to keep result as integer number.
-func (v *SensorBME680) ReadPressureMult10Pa(i2c *i2c.I2C, accuracy AccuracyMode) (uint32, error) {
-	ut, up, err := v.readUncompTempratureAndPressure(i2c, accuracy)
+func (v *SensorBME680) ReadPressureMult10Pa(bus Bus, accuracy AccuracyMode) (uint32, error) {
+	osrt := v.getOversamplingRation(ACCURACY_STANDARD)
+	osrp := v.getOversamplingRation(accuracy)
+	m, err := v.measure(bus, osrt, osrp, 0, false)
 	if err != nil {
 		return 0, err
 	}
-	lg.Debugf("ut=%v, up=%v", ut, up)
+	return m.PressureMult10Pa, nil
+}
 
-	err = v.ReadCoefficients(i2c)
+// ReadHumidityMultQ2210 reads and calculate humidity in %RH.
+// Multiplication approach allow to keep result as integer number.
+// To get real value it's necessary to divide result by 1024.
+func (v *SensorBME680) ReadHumidityMultQ2210(bus Bus, accuracy AccuracyMode) (bool, uint32, error) {
+	osrt := v.getOversamplingRation(ACCURACY_STANDARD)
+	osrh := v.getOversamplingRation(accuracy)
+	m, err := v.measure(bus, osrt, 0, osrh, false)
 	if err != nil {
-		return 0, err
+		return true, 0, err
 	}
+	return true, m.HumidityMultQ2210, nil
+}
 
-	//  Comp temp for use in pressure comp
-	//  comp formula - taken from BMP3 API on github
-	partial_data1_t := uint64(ut - int32(256*int32(v.Coeff.PAR_T1())))
-	partial_data2_t := uint64(v.Coeff.PAR_T2()) * partial_data1_t
-	partial_data3_t := partial_data1_t * partial_data1_t
-	partial_data4_t := int64(partial_data3_t) * int64(v.Coeff.PAR_T3())
-	partial_data5_t := (int64(partial_data2_t*262144) + partial_data4_t)
-	partial_data6_t := partial_data5_t / 4294967269
-	t_lin := partial_data6_t
-	lg.Debugf("t_lin=%v", t_lin)
-	lg.Debugf("----------")
-
-	//  Compensate pressure - fixed point/integer arthmetic
-	//  taken form formulas written in github
-	partial_data1 := t_lin * t_lin
-	partial_data2 := partial_data1 / 64
-	partial_data3 := (partial_data2 * t_lin) / 256
-	partial_data4 := (int64(v.Coeff.PAR_P8()) * partial_data3) / 32
-	partial_data5 := (int64(v.Coeff.PAR_P7()) * partial_data1) * 16
-	partial_data6 := (int64(v.Coeff.PAR_P6()) * t_lin) * 4194304
-	offset := (int64(v.Coeff.PAR_P5()) * 140737488355328) + partial_data4 + partial_data5 + partial_data6
-	lg.Debugf("partial_data1=%v", partial_data1)
-	lg.Debugf("partial_data2=%v", partial_data2)
-	lg.Debugf("partial_data3=%v", partial_data3)
-	lg.Debugf("partial_data4=%v", partial_data4)
-	lg.Debugf("partial_data5=%v", partial_data5)
-	lg.Debugf("partial_data6=%v", partial_data6)
-	lg.Debugf("offset=%v", offset)
-	lg.Debugf("----------")
-
-	partial_data2 = (int64(v.Coeff.PAR_P4()) * partial_data3) / 32
-	partial_data4 = (int64(v.Coeff.PAR_P3()) * partial_data1) * 4
-	partial_data5 = (int64(v.Coeff.PAR_P2()) - 16384) * t_lin * 2097152
-	sensitivity := ((int64(v.Coeff.PAR_P1()) - 16384) * 70368744177664) + partial_data2 + partial_data4 + partial_data5
-	lg.Debugf("partial_data2=%v", partial_data2)
-	lg.Debugf("partial_data4=%v", partial_data4)
-	lg.Debugf("partial_data5=%v", partial_data5)
-	lg.Debugf("sensitivity=%v", sensitivity)
-	lg.Debugf("----------")
-
-	partial_data1 = (sensitivity / 16777216) * int64(up)
-	partial_data2 = int64(v.Coeff.PAR_P10()) * t_lin
-	partial_data3 = partial_data2 + (65536 * int64(v.Coeff.PAR_P9()))
-	partial_data4 = (partial_data3 * int64(up)) / 8192
-	partial_data5 = (partial_data4 * int64(up)) / 512
-	partial_data6 = int64(uint64(up) * uint64(up))
-	lg.Debugf("----------")
-	lg.Debugf("partial_data1=%v", partial_data1)
-	lg.Debugf("partial_data2=%v", partial_data2)
-	lg.Debugf("partial_data3=%v", partial_data3)
-	lg.Debugf("partial_data4=%v", partial_data4)
-	lg.Debugf("partial_data5=%v", partial_data5)
-	lg.Debugf("partial_data6=%v", partial_data6)
-	lg.Debugf("----------")
-	partial_data2 = (int64(v.Coeff.PAR_P11()) * partial_data6) / 65536
-	partial_data3 = (partial_data2 * int64(up)) / 128
-	partial_data4 = (offset / 4) + partial_data1 + partial_data5 + partial_data3
-	lg.Debugf("partial_data2=%v", partial_data2)
-	lg.Debugf("partial_data3=%v", partial_data3)
-	lg.Debugf("partial_data4=%v", partial_data4)
-	comp_press := uint32((uint64(partial_data4) * 25) / 1099511627776)
-
-	return comp_press, nil
-}
-
-// ReadHumidityMultQ2210 does nothing. Humidity function is not applicable for BME680.
-func (v *SensorBME680) ReadHumidityMultQ2210(i2c *i2c.I2C, accuracy AccuracyMode) (bool, uint32, error) {
-	// Not supported
-	return false, 0, nil
+// Measure runs single forced conversion of temperature, pressure, humidity
+// and gas resistance (if heater is set up by SetGasHeater), and reads out
+// all values in one burst read.
+func (v *SensorBME680) Measure(bus Bus, settings MeasureSettings) (*Measurement, error) {
+	osrt := v.getOversamplingRation(settings.Temperature)
+	osrp := v.getOversamplingRation(settings.Pressure)
+	osrh := v.getOversamplingRation(settings.Humidity)
+	return v.measure(bus, osrt, osrp, osrh, true)
 }
@@ -0,0 +1,190 @@
+//--------------------------------------------------------------------------------------------------
+//
+// Copyright (c) 2018 Denis Dyakov
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy of this software and
+// associated documentation files (the "Software"), to deal in the Software without restriction,
+// including without limitation the rights to use, copy, modify, merge, publish, distribute, sublicense,
+// and/or sell copies of the Software, and to permit persons to whom the Software is furnished to do so,
+// subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all copies or substantial
+// portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR IMPLIED, INCLUDING
+// BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY, FITNESS FOR A PARTICULAR PURPOSE AND
+// NONINFRINGEMENT. IN NO EVENT SHALL THE AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM,
+// DAMAGES OR OTHER LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE SOFTWARE.
+//
+//--------------------------------------------------------------------------------------------------
+
+package bsbmp
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"time"
+)
+
+// HeaterStep define single step of gas sensor heater profile.
+type HeaterStep struct {
+	// Hot plate target temperature in C (celsius), in range [200..400].
+	Temperature int
+	// Step duration as amount of measurement cycles, in range [1..255].
+	Multiplier int
+}
+
+// HeaterProfile define sequence of up to 10 heater steps,
+// which BME688 goes through in parallel mode.
+type HeaterProfile struct {
+	Steps []HeaterStep
+	// Heating duration within each measurement cycle, shared by all
+	// steps, up to 1923 ms. Measurement cycle lasts this time plus
+	// temperature, pressure and humidity conversion time.
+	SharedDuration time.Duration
+}
+
+// bme688SharedHeaterDuration encode shared heating duration to gas_wait_shared
+// register value: 6-bit timer value in 0.477 ms steps with multiplication
+// factor 1, 4, 16 or 64 in bits 7:6.
+func bme688SharedHeaterDuration(duration time.Duration) byte {
+	if duration >= 1923*time.Millisecond {
+		// max duration
+		return 0xFF
+	}
+	steps := uint32(duration / (477 * time.Microsecond))
+	var factor byte
+	for steps > 0x3F {
+		steps /= 4
+		factor++
+	}
+	return byte(steps) + factor*64
+}
+
+// SetHeaterProfile write heater profile used in parallel mode (BME688 only).
+// Heater resistance is calculated from the latest temperature measured,
+// so profile should be set up once sensor has done at least one measurement.
+func (v *SensorBME680) SetHeaterProfile(bus Bus, profile HeaterProfile) error {
+	err := v.loadCoefficients(bus)
+	if err != nil {
+		return err
+	}
+	if !v.isHighGasVariant() {
+		return &NotSupportedError{Sensor: v.Variant(), Feature: "heater profile"}
+	}
+	if len(profile.Steps) < 1 || len(profile.Steps) > BME688_HEATER_STEPS {
+		return errors.New(fmt.Sprintf("heater profile should contain from 1 to %d steps, but %d found",
+			BME688_HEATER_STEPS, len(profile.Steps)))
+	}
+	if profile.SharedDuration < 0 || profile.SharedDuration > 1923*time.Millisecond {
+		return errors.New(fmt.Sprintf("shared heater duration %v is out of range [0ms..1923ms]",
+			profile.SharedDuration))
+	}
+	if !v.ambientValid {
+		// assume room temperature until first measurement
+		v.ambientTemp = 25
+	}
+	for i, step := range profile.Steps {
+		if step.Temperature < 200 || step.Temperature > 400 {
+			return errors.New(fmt.Sprintf("heater step %d temperature %d*C is out of range [200..400]",
+				i, step.Temperature))
+		}
+		if step.Multiplier < 1 || step.Multiplier > 255 {
+			return errors.New(fmt.Sprintf("heater step %d multiplier %d is out of range [1..255]",
+				i, step.Multiplier))
+		}
+	}
+	for i, step := range profile.Steps {
+		err = bus.WriteRegU8(BME680_RES_HEAT_0+byte(i), v.heaterResistance(step.Temperature))
+		if err != nil {
+			return err
+		}
+		err = bus.WriteRegU8(BME680_GAS_WAIT_0+byte(i), byte(step.Multiplier))
+		if err != nil {
+			return err
+		}
+	}
+	err = bus.WriteRegU8(BME680_GAS_WAIT_SHARED, bme688SharedHeaterDuration(profile.SharedDuration))
+	if err != nil {
+		return err
+	}
+	// heater on
+	err = bus.WriteRegU8(BME680_CTRL_GAS_0, 0x00)
+	if err != nil {
+		return err
+	}
+	steps := make([]HeaterStep, len(profile.Steps))
+	copy(steps, profile.Steps)
+	v.profile = &HeaterProfile{Steps: steps, SharedDuration: profile.SharedDuration}
+	return nil
+}
+
+// readFields read out and decode data fields, used in parallel mode.
+// Only fields containing new data are returned.
+func (v *SensorBME680) readFields(bus Bus) ([]*Measurement, error) {
+	buf, _, err := bus.ReadRegBytes(BME680_FIELD0_START, BME688_FIELD_BYTES*BME688_FIELD_COUNT)
+	if err != nil {
+		return nil, err
+	}
+	var fields []*Measurement
+	for i := 0; i < BME688_FIELD_COUNT; i++ {
+		field := buf[i*BME688_FIELD_BYTES : (i+1)*BME688_FIELD_BYTES]
+		// new_data flag
+		if field[0]&0x80 != 0 {
+			fields = append(fields, v.decodeField(field, true))
+		}
+	}
+	return fields, nil
+}
+
+// readLatestField return the most recent data field measured in parallel mode.
+func (v *SensorBME680) readLatestField(bus Bus) (*Measurement, error) {
+	fields, err := v.readFields(bus)
+	if err != nil {
+		return nil, err
+	}
+	var latest *Measurement
+	for _, m := range fields {
+		if latest == nil || int8(m.MeasIndex-latest.MeasIndex) > 0 {
+			latest = m
+		}
+	}
+	if latest == nil {
+		return nil, errors.New("no data measured in parallel mode yet")
+	}
+	// update ambient temperature by the latest field
+	v.ambientTemp = latest.TemperatureMult100C / 100
+	return latest, nil
+}
+
+// ReadParallelData read out data fields measured in parallel mode since previous
+// call (BME688 only). Sensor keeps 3 latest fields, so it should be read at least
+// every 3 measurement cycles to not lose data. Fields are ordered by MeasIndex,
+// each one contain gas resistance measured at heater profile step GasIndex.
+func (v *SensorBME680) ReadParallelData(bus Bus) ([]*Measurement, error) {
+	if v.mode != POWER_MODE_PARALLEL {
+		return nil, errors.New(fmt.Sprintf("sensor %v is not in parallel mode", v.Variant()))
+	}
+	fields, err := v.readFields(bus)
+	if err != nil {
+		return nil, err
+	}
+	var result []*Measurement
+	for _, m := range fields {
+		if !v.lastMeasValid || int8(m.MeasIndex-v.lastMeasIndex) > 0 {
+			result = append(result, m)
+		}
+	}
+	sort.Slice(result, func(i, j int) bool {
+		return int8(result[i].MeasIndex-result[j].MeasIndex) < 0
+	})
+	if len(result) > 0 {
+		latest := result[len(result)-1]
+		v.lastMeasIndex = latest.MeasIndex
+		v.lastMeasValid = true
+		v.ambientTemp = latest.TemperatureMult100C / 100
+	}
+	return result, nil
+}
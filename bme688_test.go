@@ -0,0 +1,180 @@
+//--------------------------------------------------------------------------------------------------
+//
+// Copyright (c) 2018 Denis Dyakov
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy of this software and
+// associated documentation files (the "Software"), to deal in the Software without restriction,
+// including without limitation the rights to use, copy, modify, merge, publish, distribute, sublicense,
+// and/or sell copies of the Software, and to permit persons to whom the Software is furnished to do so,
+// subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all copies or substantial
+// portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR IMPLIED, INCLUDING
+// BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY, FITNESS FOR A PARTICULAR PURPOSE AND
+// NONINFRINGEMENT. IN NO EVENT SHALL THE AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM,
+// DAMAGES OR OTHER LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE SOFTWARE.
+//
+//--------------------------------------------------------------------------------------------------
+
+package bsbmp_test
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/d2r2/go-bsbmp"
+	"github.com/d2r2/go-bsbmp/sim"
+)
+
+func TestBME688HeaterProfile(t *testing.T) {
+	dev := sim.NewBME688()
+	bus := &writeLogBus{Device: dev}
+	sensor, err := bsbmp.NewBMP(bsbmp.BME688, bus)
+	if err != nil {
+		t.Fatal(err)
+	}
+	valid := bsbmp.HeaterStep{Temperature: 300, Multiplier: 5}
+	for _, profile := range []bsbmp.HeaterProfile{
+		{},
+		{Steps: make([]bsbmp.HeaterStep, 11)},
+		{Steps: []bsbmp.HeaterStep{valid, {Temperature: 199, Multiplier: 1}}},
+		{Steps: []bsbmp.HeaterStep{valid, {Temperature: 401, Multiplier: 1}}},
+		{Steps: []bsbmp.HeaterStep{valid, {Temperature: 300, Multiplier: 0}}},
+		{Steps: []bsbmp.HeaterStep{valid, {Temperature: 300, Multiplier: 256}}},
+		{Steps: []bsbmp.HeaterStep{valid}, SharedDuration: -time.Millisecond},
+		{Steps: []bsbmp.HeaterStep{valid}, SharedDuration: 2 * time.Second},
+	} {
+		err = sensor.SetHeaterProfile(profile)
+		if err == nil {
+			t.Errorf("%+v accepted", profile)
+		}
+	}
+	if len(bus.writes) != 0 {
+		t.Errorf("invalid profiles written to registers %v", bus.writes)
+	}
+	err = sensor.SetHeaterProfile(bsbmp.HeaterProfile{
+		Steps:          []bsbmp.HeaterStep{{Temperature: 200, Multiplier: 1}, {Temperature: 400, Multiplier: 255}},
+		SharedDuration: 100 * time.Millisecond,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	res0, res1 := dev.Register(bsbmp.BME680_RES_HEAT_0), dev.Register(bsbmp.BME680_RES_HEAT_0+1)
+	if res0 == 0 || res1 <= res0 {
+		t.Errorf("heater resistance registers = 0x%X, 0x%X, want growing with temperature", res0, res1)
+	}
+	for reg, want := range map[byte]byte{
+		bsbmp.BME680_GAS_WAIT_0:      1,
+		bsbmp.BME680_GAS_WAIT_0 + 1:  255,
+		bsbmp.BME680_GAS_WAIT_SHARED: 0x74, // 52 * 4 * 0.477 ms
+	} {
+		if b := dev.Register(reg); b != want {
+			t.Errorf("register 0x%X = 0x%X, want 0x%X", reg, b, want)
+		}
+	}
+	// heater profile is BME688 only
+	sensor, err = bsbmp.NewBMP(bsbmp.BME680, sim.NewBME680())
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = sensor.SetHeaterProfile(bsbmp.HeaterProfile{Steps: []bsbmp.HeaterStep{valid}})
+	if !errors.Is(err, bsbmp.ErrNotSupported) {
+		t.Errorf("err = %v, want %v", err, bsbmp.ErrNotSupported)
+	}
+}
+
+func TestBME688Parallel(t *testing.T) {
+	dev := sim.NewBME688()
+	env := sim.Environment{Temperature: 23, Pressure: 99500, Humidity: 55, GasResistance: 50000}
+	dev.SetEnvironment(env)
+	sensor, err := bsbmp.NewBMP(bsbmp.BME688, dev)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = sensor.SetHeaterProfile(bsbmp.HeaterProfile{
+		Steps: []bsbmp.HeaterStep{{Temperature: 320, Multiplier: 1}, {Temperature: 250, Multiplier: 2}},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	// heater doesn't reach target at step #1
+	dev.SetRegister(bsbmp.BME680_RES_HEAT_0+1, 0)
+	err = sensor.SetPowerMode(bsbmp.POWER_MODE_PARALLEL, bsbmp.MeasureSettings{
+		Temperature: bsbmp.ACCURACY_STANDARD,
+		Pressure:    bsbmp.ACCURACY_STANDARD,
+		Humidity:    bsbmp.ACCURACY_STANDARD,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	// heater steps: 0, 1, 1, 0, 1
+	for _, want := range [][]uint8{{0, 1, 1}, {0, 1}} {
+		dev.Sample(len(want))
+		data, err := sensor.ReadParallelData()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(data) != len(want) {
+			t.Fatalf("%d fields read, want %d", len(data), len(want))
+		}
+		for i, m := range data {
+			if m.GasIndex != want[i] {
+				t.Errorf("GasIndex = %d, want %d", m.GasIndex, want[i])
+			}
+			if i > 0 && m.MeasIndex != data[i-1].MeasIndex+1 {
+				t.Errorf("MeasIndex = %d follows %d", m.MeasIndex, data[i-1].MeasIndex)
+			}
+			if !m.GasSupported || !m.GasValid {
+				t.Errorf("gas supported = %v, valid = %v", m.GasSupported, m.GasValid)
+			}
+			if m.GasHeaterStable != (m.GasIndex == 0) {
+				t.Errorf("step %d heater stable = %v", m.GasIndex, m.GasHeaterStable)
+			}
+			checkValue(t, "temperature", float64(m.TemperatureC()), env.Temperature, temperatureTolerance)
+			checkValue(t, "pressure", float64(m.PressurePa()), env.Pressure, pressureTolerance)
+			checkValue(t, "humidity", float64(m.HumidityRH()), env.Humidity, humidityTolerance)
+			checkValue(t, "gas resistance", float64(m.GasResistance), env.GasResistance,
+				env.GasResistance*0.01)
+		}
+	}
+	// nothing new measured since previous read
+	data, err := sensor.ReadParallelData()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(data) != 0 {
+		t.Errorf("%d fields read, want 0", len(data))
+	}
+}
+
+// Parallel mode is rejected prior to any register change.
+func TestBME688ParallelNotSupported(t *testing.T) {
+	for _, c := range []struct {
+		sensorType bsbmp.SensorType
+		dev        *sim.Device
+	}{
+		{bsbmp.BME680, sim.NewBME680()},
+		// no heater profile set up
+		{bsbmp.BME688, sim.NewBME688()},
+	} {
+		bus := &writeLogBus{Device: c.dev}
+		sensor, err := bsbmp.NewBMP(c.sensorType, bus)
+		if err != nil {
+			t.Fatal(err)
+		}
+		err = sensor.SetPowerMode(bsbmp.POWER_MODE_PARALLEL, bsbmp.MeasureSettings{})
+		if err == nil {
+			t.Errorf("%v: parallel mode accepted", c.sensorType)
+		}
+		if c.sensorType == bsbmp.BME680 && !errors.Is(err, bsbmp.ErrNotSupported) {
+			t.Errorf("%v: err = %v, want %v", c.sensorType, err, bsbmp.ErrNotSupported)
+		}
+		if len(bus.writes) != 0 {
+			t.Errorf("%v: registers written %v", c.sensorType, bus.writes)
+		}
+	}
+}
@@ -22,22 +22,28 @@
 
 //  go-bsbmp package implements reading sensors values and providing compensating the readings, based on a table of coefficents stored in the device.
 //   Sensors supported:
+//     BMP085 - Abs Press, Temp. (BMP180 predecessor)
 //     BMP180 - Abs Press, Temp. (Not recommeneded for new designs)
 //     BMP280 - Abs Press, Tewp.
 //     BME280 - ABs Press, Temp, Relative Humidity
 //     BMP388 - Abs Press, Temp.
+//     BMP390 - Abs Press, Temp. (BMP388 successor, lower noise)
+//     BMP384 - Abs Press, Temp. (BMP388 with gel-filled robust package)
+//     BMP581 - Abs Press, Temp. (on-chip compensation)
+//     BMP585 - Abs Press, Temp. (BMP581 with gel-filled robust package)
+//     BME680 - Abs Press, Temp, Relative Humidity, Gas
+//     BME688 - Abs Press, Temp, Relative Humidity, Gas (BME680 with heater profiles)
 //   Note: the BMP300 device was never produced
 package bsbmp
 
 import (
-	"math"
-
-	"github.com/d2r2/go-i2c"
+	"context"
+	"fmt"
+	"time"
 )
 
 // SensorType identify which Bosch Sensortec
 // temperature and pressure sensor is used.
-// BMP180 and BMP280 are supported.
 type SensorType int
 
 // Implement Stringer interface.
@@ -50,6 +56,20 @@ func (v SensorType) String() string {
 		return "BME280"
 	} else if v == BMP388 {
 		return "BMP388"
+	} else if v == BME680 {
+		return "BME680"
+	} else if v == BMP390 {
+		return "BMP390"
+	} else if v == BMP384 {
+		return "BMP384"
+	} else if v == BMP581 {
+		return "BMP581"
+	} else if v == BMP585 {
+		return "BMP585"
+	} else if v == BME688 {
+		return "BME688"
+	} else if v == BMP085 {
+		return "BMP085"
 	} else {
 		return "!!! unknown !!!"
 	}
@@ -64,10 +84,26 @@ const (
 	BME280
 	// Bosch Sensortec pressure and temperature sensor model BMP388.
 	BMP388
-
+	// Bosch Sensortec pressure, temperature, relative humidity and gas sensor model BME680.
 	BME680
+	// Bosch Sensortec pressure and temperature sensor model BMP390.
+	BMP390
+	// Bosch Sensortec pressure and temperature sensor model BMP384.
+	BMP384
+	// Bosch Sensortec pressure and temperature sensor model BMP581.
+	BMP581
+	// Bosch Sensortec pressure and temperature sensor model BMP585.
+	BMP585
+	// Bosch Sensortec pressure, temperature, relative humidity and gas sensor model BME688.
+	BME688
+	// Bosch Sensortec pressure and temperature sensor model BMP085.
+	BMP085
 )
 
+// UNKNOWN_SENSOR is reported by SignatureError, when signature
+// doesn't belong to any sensor supported.
+const UNKNOWN_SENSOR SensorType = -1
+
 // Accuracy mode for calculation of atmospheric pressure and temprature.
 // Impact to value accuracy, calculation time frame and power consumption.
 type AccuracyMode int
@@ -81,51 +117,314 @@ const (
 	ACCURACY_HIGHEST                        // x32 samples - added in BMP388
 )
 
+// IIRFilter define coefficient of sensor built-in IIR filter,
+// which suppress short-term disturbances in temperature and pressure
+// output (door slamming, wind blowing and so on).
+type IIRFilter int
+
+const (
+	IIR_FILTER_OFF IIRFilter = iota // bypass mode
+	IIR_FILTER_2                    // filter coefficient 2 (BMP388 "coef_1")
+	IIR_FILTER_4                    // filter coefficient 4 (BMP388 "coef_3")
+	IIR_FILTER_8                    // filter coefficient 8 (BMP388 "coef_7")
+	IIR_FILTER_16                   // filter coefficient 16 (BMP388 "coef_15")
+	IIR_FILTER_32                   // filter coefficient 32 - BMP388, BME680 only
+	IIR_FILTER_64                   // filter coefficient 64 - BMP388, BME680 only
+	IIR_FILTER_128                  // filter coefficient 128 - BMP388, BME680 only
+)
+
+// Implement Stringer interface.
+func (v IIRFilter) String() string {
+	if v == IIR_FILTER_OFF {
+		return "off"
+	} else if v > IIR_FILTER_OFF && v <= IIR_FILTER_128 {
+		return fmt.Sprintf("x%d", 1<<uint(v))
+	} else {
+		return "!!! unknown !!!"
+	}
+}
+
+// PowerMode define sensor operating mode.
+type PowerMode int
+
+const (
+	// Sensor is idle, no measurements are performed.
+	POWER_MODE_SLEEP PowerMode = iota
+	// Single measurement is triggered on each read request,
+	// then sensor returns to sleep mode. Default mode.
+	POWER_MODE_FORCED
+	// Sensor runs measurements continuously, with standby time
+	// (BMP280, BME280) or output data rate (BMP388) specified,
+	// so read request simply fetch latest sample.
+	POWER_MODE_NORMAL
+	// Sensor runs measurements continuously, going through heater
+	// profile steps in gas measurement (BME688 only).
+	POWER_MODE_PARALLEL
+)
+
+// Implement Stringer interface.
+func (v PowerMode) String() string {
+	if v == POWER_MODE_SLEEP {
+		return "sleep"
+	} else if v == POWER_MODE_FORCED {
+		return "forced"
+	} else if v == POWER_MODE_NORMAL {
+		return "normal"
+	} else if v == POWER_MODE_PARALLEL {
+		return "parallel"
+	} else {
+		return "!!! unknown !!!"
+	}
+}
+
+// StandbyTime define inactive period between measurements
+// in normal mode of BMP280 and BME280 sensors.
+type StandbyTime int
+
+const (
+	STANDBY_0_5_MS  StandbyTime = iota // 0.5 ms
+	STANDBY_62_5_MS                    // 62.5 ms
+	STANDBY_125_MS                     // 125 ms
+	STANDBY_250_MS                     // 250 ms
+	STANDBY_500_MS                     // 500 ms
+	STANDBY_1000_MS                    // 1000 ms
+	STANDBY_2000_MS                    // 2000 ms - BMP280 only
+	STANDBY_4000_MS                    // 4000 ms - BMP280 only
+	STANDBY_10_MS                      // 10 ms - BME280 only
+	STANDBY_20_MS                      // 20 ms - BME280 only
+)
+
+// Duration returns standby time as time.Duration.
+func (v StandbyTime) Duration() time.Duration {
+	switch v {
+	case STANDBY_0_5_MS:
+		return 500 * time.Microsecond
+	case STANDBY_62_5_MS:
+		return 62500 * time.Microsecond
+	case STANDBY_10_MS:
+		return 10 * time.Millisecond
+	case STANDBY_20_MS:
+		return 20 * time.Millisecond
+	default:
+		return time.Duration(125<<uint(v-STANDBY_125_MS)) * time.Millisecond
+	}
+}
+
+// OutputDataRate define sampling frequency of BMP388 in normal mode,
+// which is equal to 200 Hz divided by 2^n, where n is the value of constant.
+// BMP581 has own set of rates, so the closest one is used there.
+type OutputDataRate int
+
+const (
+	ODR_200_HZ     OutputDataRate = iota // 200 Hz (5 ms period)
+	ODR_100_HZ                           // 100 Hz
+	ODR_50_HZ                            // 50 Hz
+	ODR_25_HZ                            // 25 Hz
+	ODR_12_5_HZ                          // 25/2 Hz
+	ODR_6_25_HZ                          // 25/4 Hz
+	ODR_3_1_HZ                           // 25/8 Hz
+	ODR_1_5_HZ                           // 25/16 Hz
+	ODR_0_78_HZ                          // 25/32 Hz
+	ODR_0_39_HZ                          // 25/64 Hz
+	ODR_0_2_HZ                           // 25/128 Hz
+	ODR_0_1_HZ                           // 25/256 Hz
+	ODR_0_05_HZ                          // 25/512 Hz
+	ODR_0_02_HZ                          // 25/1024 Hz
+	ODR_0_01_HZ                          // 25/2048 Hz
+	ODR_0_006_HZ                         // 25/4096 Hz
+	ODR_0_003_HZ                         // 25/8192 Hz
+	ODR_0_0015_HZ                        // 25/16384 Hz (655.36 s period)
+)
+
+// Period returns sampling period corresponding to output data rate.
+func (v OutputDataRate) Period() time.Duration {
+	return (5 * time.Millisecond) << uint(v)
+}
+
 // Abstract BMPx sensor interface
 // to control and gather data.
 type SensorInterface interface {
 	// ReadSensorID read sensor identifier unuque for each sensor type.
-	ReadSensorID(i2c *i2c.I2C) (uint8, error)
+	ReadSensorID(bus Bus) (uint8, error)
 	// ReadCoefficients read coefficient's block unique for each sensor.
-	ReadCoefficients(i2c *i2c.I2C) error
+	ReadCoefficients(bus Bus) error
 	// IsValidCoefficients verify that coefficient values are not empty.
 	IsValidCoefficients() error
 	// Verify, that specific sensor can own signature identifier and
 	// return text description of this specific id.
 	RecognizeSignature(signature uint8) (string, error)
 	// IsBusy check via status register that sensor ready for data exchange.
-	IsBusy(i2c *i2c.I2C) (bool, error)
+	IsBusy(bus Bus) (bool, error)
 	// Divide by 10 to get float temperature value in celsius.
-	ReadTemperatureMult100C(i2c *i2c.I2C, mode AccuracyMode) (temperature int32, erro error)
+	ReadTemperatureMult100C(bus Bus, mode AccuracyMode) (temperature int32, erro error)
 	// Divide by 10 to get float preasure value in pascal.
-	ReadPressureMult10Pa(i2c *i2c.I2C, mode AccuracyMode) (pressure uint32, erro error)
+	ReadPressureMult10Pa(bus Bus, mode AccuracyMode) (pressure uint32, erro error)
 	// Divide by 1024 to get float humidity value in range [0..100]%.
-	ReadHumidityMultQ2210(i2c *i2c.I2C, mode AccuracyMode) (supported bool, humidity uint32, erro error)
+	ReadHumidityMultQ2210(bus Bus, mode AccuracyMode) (supported bool, humidity uint32, erro error)
+	// Measure runs single measurement cycle with all channels
+	// supported by sensor and returns compensated values altogether.
+	Measure(bus Bus, settings MeasureSettings) (*Measurement, error)
+}
+
+// IIRFilterInterface is implemented by sensors
+// having configurable built-in IIR filter.
+type IIRFilterInterface interface {
+	// SetIIRFilter write IIR filter coefficient to sensor configuration.
+	// Setting persist until changed, or sensor reset.
+	SetIIRFilter(bus Bus, filter IIRFilter) error
+}
+
+// PowerModeInterface is implemented by sensors
+// supporting normal (continuous) mode.
+type PowerModeInterface interface {
+	// SetPowerMode switch sensor to power mode specified. Entering normal mode
+	// accuracy from settings is written to sensor and used for all samples.
+	SetPowerMode(bus Bus, mode PowerMode, settings MeasureSettings) error
+}
+
+// StandbyInterface is implemented by sensors having
+// configurable standby time in normal mode.
+type StandbyInterface interface {
+	// SetStandbyTime write standby time to sensor configuration.
+	SetStandbyTime(bus Bus, standby StandbyTime) error
+}
+
+// ODRInterface is implemented by sensors having
+// configurable output data rate in normal mode.
+type ODRInterface interface {
+	// SetOutputDataRate write output data rate to sensor configuration.
+	SetOutputDataRate(bus Bus, odr OutputDataRate) error
+}
+
+// GasHeaterInterface is implemented by sensors
+// measuring gas resistance with heated hot plate.
+type GasHeaterInterface interface {
+	// SetGasHeater setup hot plate target temperature in C (celsius)
+	// and heating duration used in gas resistance measurement.
+	SetGasHeater(bus Bus, temperature int, duration time.Duration) error
+}
+
+// FIFOInterface is implemented by sensors having
+// FIFO buffer to collect samples in normal mode.
+type FIFOInterface interface {
+	// SetFIFO write FIFO configuration.
+	SetFIFO(bus Bus, settings FIFOSettings) error
+	// ReadFIFOLength return amount of bytes stored in FIFO.
+	ReadFIFOLength(bus Bus) (int, error)
+	// FlushFIFO remove all data from FIFO.
+	FlushFIFO(bus Bus) error
+	// ReadFIFO drain FIFO and decode stored frames.
+	ReadFIFO(bus Bus) (*FIFOData, error)
+}
+
+// InterruptInterface is implemented by sensors
+// having interrupt output pin.
+type InterruptInterface interface {
+	// SetInterrupt write INT pin configuration and interrupt sources.
+	SetInterrupt(bus Bus, settings InterruptSettings) error
+	// ReadInterruptStatus read and clear interrupt status.
+	ReadInterruptStatus(bus Bus) (*InterruptStatus, error)
+}
+
+// Specification keep sensor characteristics declared in datasheet.
+type Specification struct {
+	// Absolute pressure accuracy in Pa (pascal), ±.
+	AbsoluteAccuracyPa float32
+	// Relative pressure accuracy in Pa (pascal), ±.
+	RelativeAccuracyPa float32
+	// Temperature coefficient offset in Pa/K, ±.
+	TempCoeffOffsetPa float32
+	// Typical pressure RMS noise in Pa (pascal) in highest
+	// resolution mode with IIR filter applied.
+	NoisePa float32
+}
+
+// SpecificationInterface is implemented by sensors
+// whose family members differ in characteristics.
+type SpecificationInterface interface {
+	// Specification return datasheet characteristics of sensor.
+	Specification() Specification
+}
+
+// PressureOutOfRange define pressure window, outside of which
+// sensor signals out-of-range interrupt.
+type PressureOutOfRange struct {
+	// Window center in Pa (pascal), up to 131071 Pa.
+	ThresholdPa uint32
+	// Window half-width in Pa (pascal).
+	RangePa uint8
+	// Amount of consecutive samples out of window required
+	// to signal interrupt: 1, 3, 7 or 15.
+	CountLimit int
+}
+
+// ConversionTimeInterface is implemented by sensors
+// with conversion duration depending on accuracy.
+type ConversionTimeInterface interface {
+	// ConversionTime return expected duration of pressure conversion.
+	ConversionTime(accuracy AccuracyMode) time.Duration
+}
+
+// ParallelModeInterface is implemented by sensors
+// running gas measurements through heater profile.
+type ParallelModeInterface interface {
+	// SetHeaterProfile write heater profile steps to sensor configuration.
+	SetHeaterProfile(bus Bus, profile HeaterProfile) error
+	// ReadParallelData read out new data fields measured in parallel mode.
+	ReadParallelData(bus Bus) ([]*Measurement, error)
+}
+
+// OORInterface is implemented by sensors
+// detecting pressure out of range.
+type OORInterface interface {
+	// SetPressureOutOfRange write pressure window to sensor configuration.
+	SetPressureOutOfRange(bus Bus, settings PressureOutOfRange) error
 }
 
 // BMP represent both sensors BMP180 and BMP280
 // implementing same approach to control and gather data.
 type BMP struct {
 	sensorType SensorType
-	i2c        *i2c.I2C
+	bus        Bus
 	bmp        SensorInterface
+	// Reference pressure at sea level in Pa and
+	// formula used for altitude calculation.
+	qnh           float64
+	altitudeModel AltitudeModel
 }
 
-// NewBMP creates new sensor object.
-func NewBMP(sensorType SensorType, i2c *i2c.I2C) (*BMP, error) {
-	v := &BMP{sensorType: sensorType, i2c: i2c}
+// newSensor creates driver implementation for specific sensor type.
+func newSensor(sensorType SensorType) (SensorInterface, error) {
 	switch sensorType {
-	case BMP180:
-		v.bmp = &SensorBMP180{}
+	case BMP180, BMP085:
+		return &SensorBMP180{variant: sensorType}, nil
 	case BMP280:
-		v.bmp = &SensorBMP280{}
+		return &SensorBMP280{}, nil
 	case BME280:
-		v.bmp = &SensorBME280{}
-	case BMP388:
-		v.bmp = &SensorBMP388{}
-	case BME680:
-		v.bmp = &SensorBME680{}
+		return &SensorBME280{}, nil
+	case BMP388, BMP390, BMP384:
+		return &SensorBMP388{variant: sensorType}, nil
+	case BME680, BME688:
+		return &SensorBME680{variant: sensorType}, nil
+	case BMP581, BMP585:
+		return &SensorBMP581{variant: sensorType}, nil
+	default:
+		return nil, &NotSupportedError{Sensor: sensorType,
+			Feature: fmt.Sprintf("sensor type %d", int(sensorType))}
+	}
+}
+
+// NewBMP creates new sensor object. Any Bus implementation
+// might be used to communicate with sensor, including
+// *i2c.I2C connection from github.com/d2r2/go-i2c.
+// Bus failures are reported as BusError.
+func NewBMP(sensorType SensorType, bus Bus) (*BMP, error) {
+	bmp, err := newSensor(sensorType)
+	if err != nil {
+		return nil, err
 	}
+	v := &BMP{sensorType: sensorType, bus: newErrorBus(bus), bmp: bmp,
+		qnh: SEA_LEVEL_PRESSURE_PA}
 
 	id, err := v.ReadSensorID()
 	if err != nil {
@@ -135,20 +434,155 @@ func NewBMP(sensorType SensorType, i2c *i2c.I2C) (*BMP, error) {
 	if err != nil {
 		return nil, err
 	}
-	err = v.bmp.ReadCoefficients(i2c)
+	err = v.bmp.ReadCoefficients(v.bus)
 	if err != nil {
 		return nil, err
 	}
 	return v, nil
 }
 
+// NewBMPAuto creates new sensor object, detecting sensor model
+// by its identifier (see DetectSensorType). Use SensorType
+// to know which sensor was found.
+func NewBMPAuto(bus Bus) (*BMP, error) {
+	bus = newErrorBus(bus)
+	sensorType, err := DetectSensorType(bus)
+	if err != nil {
+		return nil, err
+	}
+	return NewBMP(sensorType, bus)
+}
+
+// DetectSensorType probes all locations of chip identifier register:
+// 0xD0 used by BMP180, BMP280, BME280, BME680, BME688, 0x00 used by BMP388, BMP390
+// and 0x01 used by BMP581, BMP585, and returns sensor type recognized
+// by identifier found.
+func DetectSensorType(bus Bus) (SensorType, error) {
+	id, err := bus.ReadRegU8(BMP280_ID_REG)
+	if err != nil {
+		return 0, err
+	}
+	lg.Debugf("Chip ID at 0x%0X: 0x%0X", BMP280_ID_REG, id)
+	switch id {
+	case 0x55:
+		// BMP085 can't be distinguished from BMP180
+		// by chip ID, but it is driven the same way
+		return BMP180, nil
+	case 0x56, 0x57, 0x58:
+		return BMP280, nil
+	case 0x60:
+		return BME280, nil
+	case 0x61:
+		// BME688 share chip ID with BME680,
+		// but differ by variant ID
+		variant, err := bus.ReadRegU8(BME680_VARIANT_REG)
+		if err != nil {
+			return 0, err
+		}
+		if variant == BME680_VARIANT_GAS_HIGH {
+			return BME688, nil
+		}
+		return BME680, nil
+	}
+	id2, err := bus.ReadRegU8(BMP388_ID_REG)
+	if err != nil {
+		return 0, err
+	}
+	lg.Debugf("Chip ID at 0x%0X: 0x%0X", BMP388_ID_REG, id2)
+	switch id2 {
+	case 0x50:
+		// BMP384 can't be distinguished from BMP388
+		// by chip ID, but it is driven the same way
+		return BMP388, nil
+	case 0x60:
+		return BMP390, nil
+	}
+	id3, err := bus.ReadRegU8(BMP581_CHIP_ID_REG)
+	if err != nil {
+		return 0, err
+	}
+	lg.Debugf("Chip ID at 0x%0X: 0x%0X", BMP581_CHIP_ID_REG, id3)
+	switch id3 {
+	case 0x50:
+		return BMP581, nil
+	case 0x51:
+		return BMP585, nil
+	}
+	return 0, &SignatureError{Sensor: UNKNOWN_SENSOR, Signature: id}
+}
+
+// SensorType returns model of sensor, either specified
+// on creation or detected by NewBMPAuto.
+func (v *BMP) SensorType() SensorType {
+	return v.sensorType
+}
+
 // ReadSensorID reads sensor signature. It may be used for validation,
 // that proper code settings used for sensor data decoding.
 func (v *BMP) ReadSensorID() (uint8, error) {
-	id, err := v.bmp.ReadSensorID(v.i2c)
+	id, err := v.bmp.ReadSensorID(v.bus)
 	return id, err
 }
 
+// SetIIRFilter enable sensor built-in IIR filter with coefficient specified,
+// or disable it with IIR_FILTER_OFF. Filter remain active for all further
+// measurements. Return error, if sensor doesn't support filter or coefficient.
+func (v *BMP) SetIIRFilter(filter IIRFilter) error {
+	if s, ok := v.bmp.(IIRFilterInterface); ok {
+		return s.SetIIRFilter(v.bus, filter)
+	}
+	return &NotSupportedError{Sensor: v.sensorType, Feature: "IIR filter"}
+}
+
+// SetPowerMode switch sensor to operating mode specified. In POWER_MODE_NORMAL
+// sensor samples autonomously with accuracy from settings, and all further
+// read requests fetch latest sample without triggering conversion (accuracy
+// arguments are ignored then). POWER_MODE_PARALLEL (BME688 only) behave the
+// same way, see SetHeaterProfile. Use POWER_MODE_FORCED to return to default mode.
+func (v *BMP) SetPowerMode(mode PowerMode, settings MeasureSettings) error {
+	if s, ok := v.bmp.(PowerModeInterface); ok {
+		return s.SetPowerMode(v.bus, mode, settings)
+	}
+	return &NotSupportedError{Sensor: v.sensorType, Feature: "power mode selection"}
+}
+
+// SetStandbyTime setup inactive period between measurements in normal mode
+// (BMP280, BME280 only). Should be called before switching to normal mode.
+func (v *BMP) SetStandbyTime(standby StandbyTime) error {
+	if s, ok := v.bmp.(StandbyInterface); ok {
+		return s.SetStandbyTime(v.bus, standby)
+	}
+	return &NotSupportedError{Sensor: v.sensorType, Feature: "standby time"}
+}
+
+// SetOutputDataRate setup sampling frequency in normal mode (BMP3 family, BMP581).
+// Should be called before switching to normal mode.
+func (v *BMP) SetOutputDataRate(odr OutputDataRate) error {
+	if s, ok := v.bmp.(ODRInterface); ok {
+		return s.SetOutputDataRate(v.bus, odr)
+	}
+	return &NotSupportedError{Sensor: v.sensorType, Feature: "output data rate"}
+}
+
+// SetGasHeater setup gas sensor hot plate target temperature in C (celsius)
+// and heating duration (BME680 only). Once set up, gas resistance is measured
+// by Measure call along with other values. Zero temperature or duration turn
+// gas measurement off. Typical settings are 320*C and 150 ms.
+func (v *BMP) SetGasHeater(temperature int, duration time.Duration) error {
+	if s, ok := v.bmp.(GasHeaterInterface); ok {
+		return s.SetGasHeater(v.bus, temperature, duration)
+	}
+	return &NotSupportedError{Sensor: v.sensorType, Feature: "gas measurement"}
+}
+
+// ReloadCoefficients reads compensation coefficients from sensor again.
+// Coefficients are read once on creation and kept for all further
+// measurements, so reload might be needed only to recover after
+// sensor reset or communication failure.
+func (v *BMP) ReloadCoefficients() error {
+	return v.bmp.ReadCoefficients(v.bus)
+}
+
 func (v *BMP) IsValidCoefficients() error {
 	return v.bmp.IsValidCoefficients()
 }
@@ -156,13 +590,24 @@ func (v *BMP) IsValidCoefficients() error {
 // ReadTemperatureMult100C reads and calculates temrature in C (celsius) multiplied by 100.
 // Multiplication approach allow to keep result as integer amount.
 func (v *BMP) ReadTemperatureMult100C(accuracy AccuracyMode) (int32, error) {
-	t, err := v.bmp.ReadTemperatureMult100C(v.i2c, accuracy)
+	return v.ReadTemperatureMult100CContext(context.Background(), accuracy)
+}
+
+// ReadTemperatureMult100CContext is ReadTemperatureMult100C with context. Waiting for
+// conversion is interrupted, once context is done, and context error is returned.
+func (v *BMP) ReadTemperatureMult100CContext(ctx context.Context, accuracy AccuracyMode) (int32, error) {
+	t, err := v.bmp.ReadTemperatureMult100C(withContext(ctx, v.bus), accuracy)
 	return t, err
 }
 
 // ReadTemperatureC reads and calculates temrature in C (celsius).
 func (v *BMP) ReadTemperatureC(accuracy AccuracyMode) (float32, error) {
-	t, err := v.bmp.ReadTemperatureMult100C(v.i2c, accuracy)
+	return v.ReadTemperatureCContext(context.Background(), accuracy)
+}
+
+// ReadTemperatureCContext is ReadTemperatureC with context.
+func (v *BMP) ReadTemperatureCContext(ctx context.Context, accuracy AccuracyMode) (float32, error) {
+	t, err := v.bmp.ReadTemperatureMult100C(withContext(ctx, v.bus), accuracy)
 	if err != nil {
 		return 0, err
 	}
@@ -172,13 +617,23 @@ func (v *BMP) ReadTemperatureC(accuracy AccuracyMode) (float32, error) {
 // ReadPressureMult10Pa reads and calculates atmospheric pressure in Pa (Pascal) multiplied by 10.
 // Multiplication approach allow to keep result as integer amount.
 func (v *BMP) ReadPressureMult10Pa(accuracy AccuracyMode) (uint32, error) {
-	p, err := v.bmp.ReadPressureMult10Pa(v.i2c, accuracy)
+	return v.ReadPressureMult10PaContext(context.Background(), accuracy)
+}
+
+// ReadPressureMult10PaContext is ReadPressureMult10Pa with context.
+func (v *BMP) ReadPressureMult10PaContext(ctx context.Context, accuracy AccuracyMode) (uint32, error) {
+	p, err := v.bmp.ReadPressureMult10Pa(withContext(ctx, v.bus), accuracy)
 	return p, err
 }
 
 // ReadPressurePa reads and calculates atmospheric pressure in Pa (Pascal).
 func (v *BMP) ReadPressurePa(accuracy AccuracyMode) (float32, error) {
-	p, err := v.bmp.ReadPressureMult10Pa(v.i2c, accuracy)
+	return v.ReadPressurePaContext(context.Background(), accuracy)
+}
+
+// ReadPressurePaContext is ReadPressurePa with context.
+func (v *BMP) ReadPressurePaContext(ctx context.Context, accuracy AccuracyMode) (float32, error) {
+	p, err := v.bmp.ReadPressureMult10Pa(withContext(ctx, v.bus), accuracy)
 	if err != nil {
 		return 0, err
 	}
@@ -187,7 +642,12 @@ func (v *BMP) ReadPressurePa(accuracy AccuracyMode) (float32, error) {
 
 // ReadPressureMmHg reads and calculates atmospheric pressure in mmHg (millimeter of mercury).
 func (v *BMP) ReadPressureMmHg(accuracy AccuracyMode) (float32, error) {
-	p, err := v.bmp.ReadPressureMult10Pa(v.i2c, accuracy)
+	return v.ReadPressureMmHgContext(context.Background(), accuracy)
+}
+
+// ReadPressureMmHgContext is ReadPressureMmHg with context.
+func (v *BMP) ReadPressureMmHgContext(ctx context.Context, accuracy AccuracyMode) (float32, error) {
+	p, err := v.bmp.ReadPressureMult10Pa(withContext(ctx, v.bus), accuracy)
 	if err != nil {
 		return 0, err
 	}
@@ -198,9 +658,28 @@ func (v *BMP) ReadPressureMmHg(accuracy AccuracyMode) (float32, error) {
 	return p2, nil
 }
 
+// Measure runs single measurement cycle, obtaining temperature, pressure
+// and humidity (if supported) at once, so all values belong to the same instant.
+func (v *BMP) Measure(settings MeasureSettings) (*Measurement, error) {
+	return v.MeasureContext(context.Background(), settings)
+}
+
+// MeasureContext is Measure with context. Waiting for conversion (including
+// BME680 gas heating) is interrupted, once context is done, and context error
+// is returned, so it can be told apart from sensor ErrTimeout.
+func (v *BMP) MeasureContext(ctx context.Context, settings MeasureSettings) (*Measurement, error) {
+	m, err := v.bmp.Measure(withContext(ctx, v.bus), settings)
+	return m, err
+}
+
 // ReadHumidityRH reads and calculate humidity %RH.
 func (v *BMP) ReadHumidityRH(accuracy AccuracyMode) (bool, float32, error) {
-	supported, h, err := v.bmp.ReadHumidityMultQ2210(v.i2c, accuracy)
+	return v.ReadHumidityRHContext(context.Background(), accuracy)
+}
+
+// ReadHumidityRHContext is ReadHumidityRH with context.
+func (v *BMP) ReadHumidityRHContext(ctx context.Context, accuracy AccuracyMode) (bool, float32, error) {
+	supported, h, err := v.bmp.ReadHumidityMultQ2210(withContext(ctx, v.bus), accuracy)
 	if !supported {
 		return supported, 0, nil
 	}
@@ -211,17 +690,140 @@ func (v *BMP) ReadHumidityRH(accuracy AccuracyMode) (bool, float32, error) {
 	return supported, h2, nil
 }
 
-// ReadAltitude reads and calculates altitude above sea level, if we assume
-// that pressure at sea level is equal to 101325 Pa.
+// ReadAltitude reads and calculates altitude above sea level, using reference
+// pressure (101325 Pa by default, see SetQNH) and altitude model set up.
+// Use ReadAltitudeM to get result without rounding.
 func (v *BMP) ReadAltitude(accuracy AccuracyMode) (float32, error) {
-	p, err := v.bmp.ReadPressureMult10Pa(v.i2c, accuracy)
+	return v.ReadAltitudeContext(context.Background(), accuracy)
+}
+
+// ReadAltitudeContext is ReadAltitude with context.
+func (v *BMP) ReadAltitudeContext(ctx context.Context, accuracy AccuracyMode) (float32, error) {
+	a, err := v.ReadAltitudeMContext(ctx, accuracy)
 	if err != nil {
 		return 0, err
 	}
-	// Approximate atmospheric pressure at sea level in Pa
-	p0 := 1013250.0
-	a := 44330 * (1 - math.Pow(float64(p)/p0, 1/5.255))
 	// Round up to 2 decimals after point
 	a2 := float32(int(a*100)) / 100
 	return a2, nil
 }
+
+// SetFIFO write FIFO configuration (BMP3 family, BMP581). FIFO is filled only
+// in normal mode, so it should be followed by SetPowerMode call.
+func (v *BMP) SetFIFO(settings FIFOSettings) error {
+	if s, ok := v.bmp.(FIFOInterface); ok {
+		return s.SetFIFO(v.bus, settings)
+	}
+	return &NotSupportedError{Sensor: v.sensorType, Feature: "FIFO"}
+}
+
+// ReadFIFOLength return amount of bytes stored in FIFO.
+func (v *BMP) ReadFIFOLength() (int, error) {
+	if s, ok := v.bmp.(FIFOInterface); ok {
+		return s.ReadFIFOLength(v.bus)
+	}
+	return 0, &NotSupportedError{Sensor: v.sensorType, Feature: "FIFO"}
+}
+
+// FlushFIFO remove all data from FIFO.
+func (v *BMP) FlushFIFO() error {
+	if s, ok := v.bmp.(FIFOInterface); ok {
+		return s.FlushFIFO(v.bus)
+	}
+	return &NotSupportedError{Sensor: v.sensorType, Feature: "FIFO"}
+}
+
+// ReadFIFO drain FIFO and decode stored frames to compensated samples.
+func (v *BMP) ReadFIFO() (*FIFOData, error) {
+	if s, ok := v.bmp.(FIFOInterface); ok {
+		return s.ReadFIFO(v.bus)
+	}
+	return nil, &NotSupportedError{Sensor: v.sensorType, Feature: "FIFO"}
+}
+
+// SetInterrupt configure INT pin and enable events signaled
+// via interrupt line (BMP3 family, BMP581).
+func (v *BMP) SetInterrupt(settings InterruptSettings) error {
+	if s, ok := v.bmp.(InterruptInterface); ok {
+		return s.SetInterrupt(v.bus, settings)
+	}
+	return &NotSupportedError{Sensor: v.sensorType, Feature: "interrupts"}
+}
+
+// ReadInterruptStatus read interrupt status. Status is cleared on read,
+// which releases INT pin in latched mode.
+func (v *BMP) ReadInterruptStatus() (*InterruptStatus, error) {
+	if s, ok := v.bmp.(InterruptInterface); ok {
+		return s.ReadInterruptStatus(v.bus)
+	}
+	return nil, &NotSupportedError{Sensor: v.sensorType, Feature: "interrupts"}
+}
+
+// SetInterruptPin attach host input connected to sensor INT pin (BMP3 family, BMP581)
+// or EOC pin (BMP085, rising edge). Once attached, conversion completion is awaited
+// by interrupt, instead of status register polling, so data-ready interrupt should
+// be enabled with SetInterrupt as well. Interrupt status is read (thus cleared)
+// before each forced conversion, to release INT pin in latched mode.
+// Pass nil to go back to polling.
+func (v *BMP) SetInterruptPin(pin InterruptPin) error {
+	// BMP085 signal end of conversion by EOC pin
+	if _, ok := v.bmp.(InterruptInterface); !ok && v.sensorType != BMP085 {
+		return &NotSupportedError{Sensor: v.sensorType, Feature: "interrupts"}
+	}
+	if b, ok := v.bus.(*interruptBus); ok {
+		v.bus = b.Bus
+	}
+	if pin != nil {
+		v.bus = &interruptBus{Bus: v.bus, pin: pin}
+	}
+	return nil
+}
+
+// Specification return datasheet characteristics (absolute and relative
+// accuracy, temperature coefficient offset, noise) of BMP388, BMP390 or BMP384.
+func (v *BMP) Specification() (*Specification, error) {
+	if s, ok := v.bmp.(SpecificationInterface); ok {
+		spec := s.Specification()
+		return &spec, nil
+	}
+	return nil, &NotSupportedError{Sensor: v.sensorType, Feature: "specification"}
+}
+
+// SetPressureOutOfRange setup pressure window (BMP581 only). Once pressure
+// leaves window, out-of-range interrupt is signaled, if enabled with SetInterrupt.
+func (v *BMP) SetPressureOutOfRange(settings PressureOutOfRange) error {
+	if s, ok := v.bmp.(OORInterface); ok {
+		return s.SetPressureOutOfRange(v.bus, settings)
+	}
+	return &NotSupportedError{Sensor: v.sensorType, Feature: "pressure out-of-range detection"}
+}
+
+// ConversionTime return expected duration of pressure conversion in accuracy
+// mode specified (BMP180, BMP085 only). ACCURACY_HIGHEST select BMP180 advanced
+// resolution mode, where several conversions are averaged in software.
+func (v *BMP) ConversionTime(accuracy AccuracyMode) (time.Duration, error) {
+	if s, ok := v.bmp.(ConversionTimeInterface); ok {
+		return s.ConversionTime(accuracy), nil
+	}
+	return 0, &NotSupportedError{Sensor: v.sensorType, Feature: "conversion time"}
+}
+
+// SetHeaterProfile setup sequence of gas sensor heater steps (BME688 only),
+// which sensor goes through in parallel mode. Afterwards switch sensor
+// to parallel mode with SetPowerMode and collect data with ReadParallelData.
+func (v *BMP) SetHeaterProfile(profile HeaterProfile) error {
+	if s, ok := v.bmp.(ParallelModeInterface); ok {
+		return s.SetHeaterProfile(v.bus, profile)
+	}
+	return &NotSupportedError{Sensor: v.sensorType, Feature: "heater profile"}
+}
+
+// ReadParallelData read out measurements done in parallel mode since
+// previous call (BME688 only). Each measurement contain gas resistance
+// for heater profile step indicated by GasIndex.
+func (v *BMP) ReadParallelData() ([]*Measurement, error) {
+	if s, ok := v.bmp.(ParallelModeInterface); ok {
+		return s.ReadParallelData(v.bus)
+	}
+	return nil, &NotSupportedError{Sensor: v.sensorType, Feature: "parallel mode"}
+}
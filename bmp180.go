@@ -25,9 +25,7 @@ import (
 	"bytes"
 	"encoding/binary"
 	"errors"
-	"fmt"
-
-	i2c "github.com/d2r2/go-i2c"
+	"time"
 )
 
 // BMP180 sensors memory map
@@ -114,19 +112,31 @@ func (v *CoeffBMP180) dig_MD() int16 {
 	return int16(uint16(v.COEF_BE)<<8 | uint16(v.COEF_BF))
 }
 
-// SensorBMP180 specific type
+// SensorBMP180 specific type. It drives legacy BMP085 as well,
+// which is register compatible with BMP180.
 type SensorBMP180 struct {
 	Coeff *CoeffBMP180
+	// Sensor model driven: BMP180 or BMP085.
+	variant SensorType
 }
 
 // Static cast to verify at compile time
 // that type implement interface.
 var _ SensorInterface = &SensorBMP180{}
+var _ ConversionTimeInterface = &SensorBMP180{}
+
+// Variant return sensor model driven: BMP180 or BMP085.
+func (v *SensorBMP180) Variant() SensorType {
+	if v.variant == BMP085 {
+		return BMP085
+	}
+	return BMP180
+}
 
 // ReadSensorID reads sensor signature. It may be used for validation,
 // that proper code settings used for sensor data decoding.
-func (v *SensorBMP180) ReadSensorID(i2c *i2c.I2C) (uint8, error) {
-	id, err := i2c.ReadRegU8(BMP180_ID_REG)
+func (v *SensorBMP180) ReadSensorID(bus Bus) (uint8, error) {
+	id, err := bus.ReadRegU8(BMP180_ID_REG)
 	if err != nil {
 		return 0, err
 	}
@@ -134,13 +144,9 @@ func (v *SensorBMP180) ReadSensorID(i2c *i2c.I2C) (uint8, error) {
 }
 
 // ReadCoefficients reads compensation coefficients, unique for each sensor.
-func (v *SensorBMP180) ReadCoefficients(i2c *i2c.I2C) error {
-	_, err := i2c.WriteBytes([]byte{BMP180_COEF_START})
-	if err != nil {
-		return err
-	}
+func (v *SensorBMP180) ReadCoefficients(bus Bus) error {
 	var coef1 [BMP180_COEF_BYTES]byte
-	err = readDataToStruct(i2c, BMP180_COEF_BYTES,
+	err := readDataToStruct(bus, BMP180_COEF_START, BMP180_COEF_BYTES,
 		binary.LittleEndian, &coef1)
 	if err != nil {
 		return err
@@ -155,6 +161,15 @@ func (v *SensorBMP180) ReadCoefficients(i2c *i2c.I2C) error {
 	return nil
 }
 
+// loadCoefficients reads compensation coefficients only once,
+// since they never change. Use ReadCoefficients to force reload.
+func (v *SensorBMP180) loadCoefficients(bus Bus) error {
+	if v.Coeff == nil {
+		return v.ReadCoefficients(bus)
+	}
+	return nil
+}
+
 // IsValidCoefficients verify that compensate registers
 // are not empty, and thus are valid.
 func (v *SensorBMP180) IsValidCoefficients() error {
@@ -215,18 +230,18 @@ func (v *SensorBMP180) IsValidCoefficients() error {
 func (v *SensorBMP180) RecognizeSignature(signature uint8) (string, error) {
 	switch signature {
 	case 0x55:
-		return "BMP180", nil
+		return v.Variant().String(), nil
 	default:
-		return "", errors.New(fmt.Sprintf("signature 0x%x doesn't belong to BMP180 series", signature))
+		return "", &SignatureError{Sensor: v.Variant(), Signature: signature}
 	}
 }
 
 // IsBusy reads register 0xF4 for "busy" flag,
 // according to sensor specification.
-func (v *SensorBMP180) IsBusy(i2c *i2c.I2C) (busy bool, err error) {
+func (v *SensorBMP180) IsBusy(bus Bus) (busy bool, err error) {
 	// Check flag to know status of calculation, according
 	// to specification about SCO (Start of conversion) flag
-	b, err := i2c.ReadRegU8(BMP180_CNTR_MEAS_REG)
+	b, err := bus.ReadRegU8(BMP180_CNTR_MEAS_REG)
 	if err != nil {
 		return false, err
 	}
@@ -235,33 +250,89 @@ func (v *SensorBMP180) IsBusy(i2c *i2c.I2C) (busy bool, err error) {
 	return b != 0, nil
 }
 
+// conversionTime return maximum conversion time for oversampling oss,
+// according to BMP180 and BMP085 specification. Temperature conversion
+// lasts the same time as pressure conversion with oss=0.
+func (v *SensorBMP180) conversionTime(oss byte) time.Duration {
+	times := [...]time.Duration{4500, 7500, 13500, 25500}
+	return times[oss] * time.Microsecond
+}
+
+// waitConversion waits for conversion started to complete. Maximum conversion
+// time is passed first, then BMP180 is polled for SCO flag to be sure. BMP085
+// doesn't report conversion status in registers, so EOC pin rising edge
+// is awaited instead, if pin attached.
+func (v *SensorBMP180) waitConversion(bus Bus, oss byte) error {
+	d := v.conversionTime(oss)
+	if v.Variant() != BMP085 {
+		err := sleep(bus, d)
+		if err != nil {
+			return err
+		}
+		return waitForCompletion(v, bus)
+	}
+	if pin := busInterruptPin(bus); pin != nil {
+		edge, err := waitForEdge(bus, pin, d+interruptTimeout)
+		if err != nil {
+			return err
+		}
+		if !edge {
+			// conversion is definitely over by now
+			lg.Debugf("no EOC edge in %v", d+interruptTimeout)
+		}
+		return nil
+	}
+	return sleep(bus, d)
+}
+
+// ConversionTime return expected duration of pressure conversion in accuracy
+// mode specified, including all conversions averaged in advanced resolution
+// mode. Each pressure reading is preceded by temperature conversion (4.5 ms).
+func (v *SensorBMP180) ConversionTime(accuracy AccuracyMode) time.Duration {
+	oss := v.getOversamplingRation(accuracy)
+	return time.Duration(v.getSoftwareSamples(accuracy)) * v.conversionTime(oss)
+}
+
 // readUncompTemp reads uncompensated temprature from sensor.
-func (v *SensorBMP180) readUncompTemp(i2c *i2c.I2C) (int32, error) {
-	err := i2c.WriteRegU8(BMP180_CNTR_MEAS_REG, 0x2F)
+func (v *SensorBMP180) readUncompTemp(bus Bus) (int32, error) {
+	err := clearInterrupt(v, bus)
+	if err != nil {
+		return 0, err
+	}
+	err = bus.WriteRegU8(BMP180_CNTR_MEAS_REG, 0x2E)
 	if err != nil {
 		return 0, err
 	}
-	_, err = waitForCompletion(v, i2c)
+	err = v.waitConversion(bus, 0)
 	if err != nil {
 		return 0, err
 	}
-	w, err := i2c.ReadRegU16BE(BMP180_OUT_MSB_LSB_XLSB)
+	buf, _, err := bus.ReadRegBytes(BMP180_OUT_MSB_LSB_XLSB, 2)
 	if err != nil {
 		return 0, err
 	}
+	w := getU16BE(buf)
 	return int32(w), nil
 }
 
+// getOversamplingRation return oversampling setting (oss) of operation
+// mode, specified in BMP180 datasheet, corresponding to accuracy:
+//
+//	ultra low power (oss=0) - ACCURACY_ULTRA_LOW;
+//	standard (oss=1) - ACCURACY_LOW, ACCURACY_STANDARD;
+//	high resolution (oss=2) - ACCURACY_HIGH;
+//	ultra high resolution (oss=3) - ACCURACY_ULTRA_HIGH;
+//	advanced resolution (oss=3, averaged in software) - ACCURACY_HIGHEST.
 func (v *SensorBMP180) getOversamplingRation(accuracy AccuracyMode) byte {
 	var b byte
 	switch accuracy {
-	case ACCURACY_LOW, ACCURACY_ULTRA_LOW:
+	case ACCURACY_ULTRA_LOW:
 		b = 0
-	case ACCURACY_STANDARD:
+	case ACCURACY_LOW, ACCURACY_STANDARD:
 		b = 1
 	case ACCURACY_HIGH:
 		b = 2
-	case ACCURACY_ULTRA_HIGH:
+	case ACCURACY_ULTRA_HIGH, ACCURACY_HIGHEST:
 		b = 3
 	default:
 		// assign accuracy to lowest resolution by default
@@ -270,82 +341,70 @@ func (v *SensorBMP180) getOversamplingRation(accuracy AccuracyMode) byte {
 	return b
 }
 
+// getSoftwareSamples return amount of pressure conversions averaged
+// in software: 3 in advanced resolution mode, 1 otherwise.
+func (v *SensorBMP180) getSoftwareSamples(accuracy AccuracyMode) int {
+	if accuracy == ACCURACY_HIGHEST {
+		return 3
+	}
+	return 1
+}
+
 // readUncompPressure reads atmospheric uncompensated pressure from sensor.
-func (v *SensorBMP180) readUncompPressure(i2c *i2c.I2C, accuracy AccuracyMode) (int32, error) {
+// In advanced resolution mode several conversions are averaged.
+func (v *SensorBMP180) readUncompPressure(bus Bus, accuracy AccuracyMode) (int32, error) {
 	oss := v.getOversamplingRation(accuracy)
-	lg.Debugf("oss=%v", oss)
-	err := i2c.WriteRegU8(BMP180_CNTR_MEAS_REG, 0x34+(oss<<6))
-	if err != nil {
-		return 0, err
-	}
-	_, err = waitForCompletion(v, i2c)
-	if err != nil {
-		return 0, err
-	}
-	buf, _, err := i2c.ReadRegBytes(BMP180_OUT_MSB_LSB_XLSB, 3)
-	if err != nil {
-		return 0, err
+	samples := v.getSoftwareSamples(accuracy)
+	lg.Debugf("oss=%v, samples=%v", oss, samples)
+	var sum int32
+	for i := 0; i < samples; i++ {
+		err := clearInterrupt(v, bus)
+		if err != nil {
+			return 0, err
+		}
+		err = bus.WriteRegU8(BMP180_CNTR_MEAS_REG, 0x34+(oss<<6))
+		if err != nil {
+			return 0, err
+		}
+		err = v.waitConversion(bus, oss)
+		if err != nil {
+			return 0, err
+		}
+		buf, _, err := bus.ReadRegBytes(BMP180_OUT_MSB_LSB_XLSB, 3)
+		if err != nil {
+			return 0, err
+		}
+		sum += (int32(buf[0])<<16 + int32(buf[1])<<8 + int32(buf[2])) >> (8 - oss)
 	}
-	up := (int32(buf[0])<<16 + int32(buf[1])<<8 + int32(buf[2])) >> (8 - oss)
+	up := (sum + int32(samples)/2) / int32(samples)
 	return up, nil
 }
 
-// ReadTemperatureMult100C reads and calculates temprature in C (celsius) multiplied by 100.
-// Multiplication approach allow to keep result as integer number.
-func (v *SensorBMP180) ReadTemperatureMult100C(i2c *i2c.I2C, mode AccuracyMode) (int32, error) {
-	ut, err := v.readUncompTemp(i2c)
-	if err != nil {
-		return 0, err
-	}
-	err = v.ReadCoefficients(i2c)
-	if err != nil {
-		return 0, err
-	}
+// compensateTemperature calculates temprature in C (celsius) multiplied by 100
+// from uncompensated value ut. Returns B5 as well, required to compensate pressure.
+func (v *SensorBMP180) compensateTemperature(ut int32) (t int32, b5 int32) {
 	// Calculate temperature according to sensor specification
 	x1 := ((ut - int32(v.Coeff.dig_AC6())) * int32(v.Coeff.dig_AC5())) >> 15
 	lg.Debugf("x1=%v", x1)
 	x2 := (int32(v.Coeff.dig_MC()) << 11) / (x1 + int32(v.Coeff.dig_MD()))
 	lg.Debugf("x2=%v", x2)
-	b5 := x1 + x2
+	b5 = x1 + x2
 	lg.Debugf("b5=%v", b5)
-	t := ((b5 + 8) >> 4) * 10
+	t = ((b5 + 8) >> 4) * 10
 	lg.Debugf("t=%v", t)
-	return t, nil
+	return t, b5
 }
 
-// ReadPressureMult10Pa reads and calculates atmospheric pressure in Pa (Pascal) multiplied by 10.
-// Multiplication approach allow to keep result as integer number.
-func (v *SensorBMP180) ReadPressureMult10Pa(i2c *i2c.I2C, accuracy AccuracyMode) (uint32, error) {
-	oss := v.getOversamplingRation(accuracy)
-	ut, err := v.readUncompTemp(i2c)
-	if err != nil {
-		return 0, err
-	}
-	lg.Debugf("ut=%v", ut)
-
-	up, err := v.readUncompPressure(i2c, accuracy)
-	if err != nil {
-		return 0, err
-	}
-	lg.Debugf("up=%v", up)
-
-	err = v.ReadCoefficients(i2c)
-	if err != nil {
-		return 0, err
-	}
-
+// compensatePressure calculates atmospheric pressure in Pa (Pascal) multiplied by 10
+// from uncompensated value up, obtained with oversampling oss, and B5 value
+// obtained from temperature compensation.
+func (v *SensorBMP180) compensatePressure(up int32, b5 int32, oss byte) uint32 {
 	// Calculate pressure according to sensor specification
-	x1 := ((ut - int32(v.Coeff.dig_AC6())) * int32(v.Coeff.dig_AC5())) >> 15
-	lg.Debugf("x1=%v", x1)
-	x2 := (int32(v.Coeff.dig_MC()) << 11) / (x1 + int32(v.Coeff.dig_MD()))
-	lg.Debugf("x2=%v", x2)
-	b5 := x1 + x2
-	lg.Debugf("b5=%v", b5)
 	b6 := b5 - 4000
 	lg.Debugf("b6=%v", b6)
-	x1 = (int32(v.Coeff.dig_B2()) * ((b6 * b6) >> 12)) >> 11
+	x1 := (int32(v.Coeff.dig_B2()) * ((b6 * b6) >> 12)) >> 11
 	lg.Debugf("x1=%v", x1)
-	x2 = (int32(v.Coeff.dig_AC2()) * b6) >> 11
+	x2 := (int32(v.Coeff.dig_AC2()) * b6) >> 11
 	lg.Debugf("x2=%v", x2)
 	x3 := x1 + x2
 	lg.Debugf("x3=%v", x3)
@@ -353,7 +412,7 @@ func (v *SensorBMP180) ReadPressureMult10Pa(i2c *i2c.I2C, accuracy AccuracyMode)
 	lg.Debugf("b3=%v", b3)
 	x1 = (int32(v.Coeff.dig_AC3()) * b6) >> 13
 	lg.Debugf("x1=%v", x1)
-	x2 = ((int32(v.Coeff.dig_B1()) * (b6 * b6)) >> 12) >> 16
+	x2 = (int32(v.Coeff.dig_B1()) * ((b6 * b6) >> 12)) >> 16
 	lg.Debugf("x2=%v", x2)
 	x3 = ((x1 + x2) + 2) >> 2
 	lg.Debugf("x3=%v", x3)
@@ -377,11 +436,77 @@ func (v *SensorBMP180) ReadPressureMult10Pa(i2c *i2c.I2C, accuracy AccuracyMode)
 	p1 += (x1 + x2 + 3791) >> 4
 	lg.Debugf("p=%v", p1)
 	p := uint32(p1) * 10
+	return p
+}
+
+// ReadTemperatureMult100C reads and calculates temprature in C (celsius) multiplied by 100.
+// Multiplication approach allow to keep result as integer number.
+func (v *SensorBMP180) ReadTemperatureMult100C(bus Bus, mode AccuracyMode) (int32, error) {
+	ut, err := v.readUncompTemp(bus)
+	if err != nil {
+		return 0, err
+	}
+	err = v.loadCoefficients(bus)
+	if err != nil {
+		return 0, err
+	}
+	t, _ := v.compensateTemperature(ut)
+	return t, nil
+}
+
+// ReadPressureMult10Pa reads and calculates atmospheric pressure in Pa (Pascal) multiplied by 10.
+// Multiplication approach allow to keep result as integer number.
+func (v *SensorBMP180) ReadPressureMult10Pa(bus Bus, accuracy AccuracyMode) (uint32, error) {
+	oss := v.getOversamplingRation(accuracy)
+	ut, err := v.readUncompTemp(bus)
+	if err != nil {
+		return 0, err
+	}
+	lg.Debugf("ut=%v", ut)
+
+	up, err := v.readUncompPressure(bus, accuracy)
+	if err != nil {
+		return 0, err
+	}
+	lg.Debugf("up=%v", up)
+
+	err = v.loadCoefficients(bus)
+	if err != nil {
+		return 0, err
+	}
+	_, b5 := v.compensateTemperature(ut)
+	p := v.compensatePressure(up, b5, oss)
 	return p, nil
 }
 
 // ReadHumidityMultQ2210 does nothing. Humidity function is not applicable for BMP180.
-func (v *SensorBMP180) ReadHumidityMultQ2210(i2c *i2c.I2C, accuracy AccuracyMode) (bool, uint32, error) {
+func (v *SensorBMP180) ReadHumidityMultQ2210(bus Bus, accuracy AccuracyMode) (bool, uint32, error) {
 	// Not supported
 	return false, 0, nil
 }
+
+// Measure reads temperature and pressure one after another. BMP180 can't
+// convert both channels in one cycle, so two conversions run back to back.
+func (v *SensorBMP180) Measure(bus Bus, settings MeasureSettings) (*Measurement, error) {
+	err := v.loadCoefficients(bus)
+	if err != nil {
+		return nil, err
+	}
+	ut, err := v.readUncompTemp(bus)
+	if err != nil {
+		return nil, err
+	}
+	oss := v.getOversamplingRation(settings.Pressure)
+	up, err := v.readUncompPressure(bus, settings.Pressure)
+	if err != nil {
+		return nil, err
+	}
+	m := &Measurement{Time: time.Now()}
+	m.Raw.Temperature = ut
+	m.Raw.Pressure = up
+	lg.Debugf("ut=%v, up=%v", ut, up)
+	t, b5 := v.compensateTemperature(ut)
+	m.TemperatureMult100C = t
+	m.PressureMult10Pa = v.compensatePressure(up, b5, oss)
+	return m, nil
+}
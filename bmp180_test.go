@@ -0,0 +1,136 @@
+//--------------------------------------------------------------------------------------------------
+//
+// Copyright (c) 2018 Denis Dyakov
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy of this software and
+// associated documentation files (the "Software"), to deal in the Software without restriction,
+// including without limitation the rights to use, copy, modify, merge, publish, distribute, sublicense,
+// and/or sell copies of the Software, and to permit persons to whom the Software is furnished to do so,
+// subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all copies or substantial
+// portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR IMPLIED, INCLUDING
+// BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY, FITNESS FOR A PARTICULAR PURPOSE AND
+// NONINFRINGEMENT. IN NO EVENT SHALL THE AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM,
+// DAMAGES OR OTHER LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE SOFTWARE.
+//
+//--------------------------------------------------------------------------------------------------
+
+package bsbmp_test
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/d2r2/go-bsbmp"
+	"github.com/d2r2/go-bsbmp/sim"
+)
+
+func TestBMP180OperationModes(t *testing.T) {
+	for _, c := range []struct {
+		accuracy   bsbmp.AccuracyMode
+		command    byte
+		samples    int
+		conversion time.Duration
+	}{
+		// ultra low power
+		{bsbmp.ACCURACY_ULTRA_LOW, 0x34, 1, 4500 * time.Microsecond},
+		// standard
+		{bsbmp.ACCURACY_LOW, 0x74, 1, 7500 * time.Microsecond},
+		{bsbmp.ACCURACY_STANDARD, 0x74, 1, 7500 * time.Microsecond},
+		// high resolution
+		{bsbmp.ACCURACY_HIGH, 0xB4, 1, 13500 * time.Microsecond},
+		// ultra high resolution
+		{bsbmp.ACCURACY_ULTRA_HIGH, 0xF4, 1, 25500 * time.Microsecond},
+		// advanced resolution
+		{bsbmp.ACCURACY_HIGHEST, 0xF4, 3, 3 * 25500 * time.Microsecond},
+	} {
+		for _, sensorType := range []bsbmp.SensorType{bsbmp.BMP180, bsbmp.BMP085} {
+			name := fmt.Sprintf("%v/accuracy=%d", sensorType, c.accuracy)
+			dev := sim.NewBMP180()
+			if sensorType == bsbmp.BMP085 {
+				dev = sim.NewBMP085()
+			}
+			env := sim.Environment{Temperature: 8, Pressure: 91000}
+			dev.SetEnvironment(env)
+			bus := &writeLogBus{Device: dev}
+			sensor, err := bsbmp.NewBMP(sensorType, bus)
+			if err != nil {
+				t.Fatal(err)
+			}
+			d, err := sensor.ConversionTime(c.accuracy)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if d != c.conversion {
+				t.Errorf("%s: ConversionTime() = %v, want %v", name, d, c.conversion)
+			}
+			p, err := sensor.ReadPressurePa(c.accuracy)
+			if err != nil {
+				t.Fatal(err)
+			}
+			checkValue(t, name+" pressure", float64(p), env.Pressure, pressureTolerance)
+			// temperature conversion precede pressure conversions
+			want := []byte{0x2E}
+			for i := 0; i < c.samples; i++ {
+				want = append(want, c.command)
+			}
+			if writes := bus.writes[0xF4]; string(writes) != string(want) {
+				t.Errorf("%s: control register writes = %x, want %x", name, writes, want)
+			}
+		}
+	}
+	sensor, err := bsbmp.NewBMP(bsbmp.BMP280, sim.NewBMP280())
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = sensor.ConversionTime(bsbmp.ACCURACY_STANDARD)
+	if !errors.Is(err, bsbmp.ErrNotSupported) {
+		t.Errorf("BMP280: err = %v, want %v", err, bsbmp.ErrNotSupported)
+	}
+}
+
+// pressureStepBus change pressure before each pressure conversion.
+type pressureStepBus struct {
+	*sim.Device
+	pressures []float64
+}
+
+func (v *pressureStepBus) WriteRegU8(reg byte, value byte) error {
+	if reg == 0xF4 && value&0x3F == 0x34 && len(v.pressures) > 0 {
+		env := v.Device.Environment()
+		env.Pressure = v.pressures[0]
+		v.Device.SetEnvironment(env)
+		v.pressures = v.pressures[1:]
+	}
+	return v.Device.WriteRegU8(reg, value)
+}
+
+// Advanced resolution mode average 3 pressure conversions.
+func TestBMP180AdvancedResolution(t *testing.T) {
+	dev := sim.NewBMP180()
+	dev.SetEnvironment(sim.Environment{Temperature: 20, Pressure: 100000})
+	bus := &pressureStepBus{Device: dev}
+	sensor, err := bsbmp.NewBMP(bsbmp.BMP180, bus)
+	if err != nil {
+		t.Fatal(err)
+	}
+	bus.pressures = []float64{100000, 100150, 100030}
+	p, err := sensor.ReadPressurePa(bsbmp.ACCURACY_HIGHEST)
+	if err != nil {
+		t.Fatal(err)
+	}
+	checkValue(t, "averaged pressure", float64(p), 100060, pressureTolerance)
+	// the only conversion
+	bus.pressures = []float64{100090}
+	p, err = sensor.ReadPressurePa(bsbmp.ACCURACY_ULTRA_HIGH)
+	if err != nil {
+		t.Fatal(err)
+	}
+	checkValue(t, "pressure", float64(p), 100090, pressureTolerance)
+}
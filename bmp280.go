@@ -26,8 +26,7 @@ import (
 	"encoding/binary"
 	"errors"
 	"fmt"
-
-	i2c "github.com/d2r2/go-i2c"
+	"time"
 )
 
 // BMP280 sensors memory map
@@ -36,7 +35,7 @@ const (
 	BMP280_ID_REG        = 0xD0
 	BMP280_STATUS_REG    = 0xF3
 	BMP280_CNTR_MEAS_REG = 0xF4
-	BMP280_CONFIG        = 0xF5 // TODO: support IIR filter settings
+	BMP280_CONFIG        = 0xF5
 	BMP280_RESET         = 0xE0
 	// BMP280 specific compensation register's block
 	BMP280_COEF_START = 0x88
@@ -126,16 +125,20 @@ func (v *CoeffBMP280) dig_P9() int16 {
 // SensorBMP280 specific type
 type SensorBMP280 struct {
 	Coeff *CoeffBMP280
+	// Operating mode, forced by default
+	mode PowerMode
 }
 
 // Static cast to verify at compile time
 // that type implement interface.
 var _ SensorInterface = &SensorBMP280{}
+var _ PowerModeInterface = &SensorBMP280{}
+var _ StandbyInterface = &SensorBMP280{}
 
 // ReadSensorID reads sensor signature. It may be used for validation,
 // that proper code settings used for sensor data decoding.
-func (v *SensorBMP280) ReadSensorID(i2c *i2c.I2C) (uint8, error) {
-	id, err := i2c.ReadRegU8(BMP280_ID_REG)
+func (v *SensorBMP280) ReadSensorID(bus Bus) (uint8, error) {
+	id, err := bus.ReadRegU8(BMP280_ID_REG)
 	if err != nil {
 		return 0, err
 	}
@@ -143,13 +146,9 @@ func (v *SensorBMP280) ReadSensorID(i2c *i2c.I2C) (uint8, error) {
 }
 
 // ReadCoefficients reads compensation coefficients, unique for each sensor.
-func (v *SensorBMP280) ReadCoefficients(i2c *i2c.I2C) error {
-	_, err := i2c.WriteBytes([]byte{BMP280_COEF_START})
-	if err != nil {
-		return err
-	}
+func (v *SensorBMP280) ReadCoefficients(bus Bus) error {
 	var coef1 [BMP280_COEF_BYTES]byte
-	err = readDataToStruct(i2c, BMP280_COEF_BYTES,
+	err := readDataToStruct(bus, BMP280_COEF_START, BMP280_COEF_BYTES,
 		binary.LittleEndian, &coef1)
 	if err != nil {
 		return err
@@ -164,6 +163,15 @@ func (v *SensorBMP280) ReadCoefficients(i2c *i2c.I2C) error {
 	return nil
 }
 
+// loadCoefficients reads compensation coefficients only once,
+// since they never change. Use ReadCoefficients to force reload.
+func (v *SensorBMP280) loadCoefficients(bus Bus) error {
+	if v.Coeff == nil {
+		return v.ReadCoefficients(bus)
+	}
+	return nil
+}
+
 // IsValidCoefficients verify that compensate registers
 // are not empty, and thus are valid.
 func (v *SensorBMP280) IsValidCoefficients() error {
@@ -232,16 +240,16 @@ func (v *SensorBMP280) RecognizeSignature(signature uint8) (string, error) {
 	case 0x56, 0x57:
 		return "BMP280 (sample)", nil
 	default:
-		return "", errors.New(fmt.Sprintf("signature 0x%x doesn't belong to BMP280 series", signature))
+		return "", &SignatureError{Sensor: BMP280, Signature: signature}
 	}
 }
 
 // IsBusy reads register 0xF3 for "busy" flag,
 // according to sensor specification.
-func (v *SensorBMP280) IsBusy(i2c *i2c.I2C) (busy bool, err error) {
+func (v *SensorBMP280) IsBusy(bus Bus) (busy bool, err error) {
 	// Check flag to know status of calculation, according
 	// to specification about SCO (Start of conversion) flag
-	b, err := i2c.ReadRegU8(BMP280_STATUS_REG)
+	b, err := bus.ReadRegU8(BMP280_STATUS_REG)
 	if err != nil {
 		return false, err
 	}
@@ -250,6 +258,15 @@ func (v *SensorBMP280) IsBusy(i2c *i2c.I2C) (busy bool, err error) {
 	return b != 0, nil
 }
 
+// SetIIRFilter setup IIR filter coefficient in config register,
+// keeping standby time and SPI 3-wire settings unchanged.
+func (v *SensorBMP280) SetIIRFilter(bus Bus, filter IIRFilter) error {
+	if filter < IIR_FILTER_OFF || filter > IIR_FILTER_16 {
+		return &NotSupportedError{Sensor: BMP280, Feature: fmt.Sprintf("IIR filter %v", filter)}
+	}
+	return updateRegU8(bus, BMP280_CONFIG, 0x7<<2, byte(filter)<<2)
+}
+
 func (v *SensorBMP280) getOversamplingRation(accuracy AccuracyMode) byte {
 	var b byte
 	switch accuracy {
@@ -270,19 +287,63 @@ func (v *SensorBMP280) getOversamplingRation(accuracy AccuracyMode) byte {
 	return b
 }
 
-// readUncompTemprature reads uncompensated temprature from sensor.
-func (v *SensorBMP280) readUncompTemprature(i2c *i2c.I2C, accuracy AccuracyMode) (int32, error) {
+// triggerMeasurement starts forced conversion with oversampling specified
+// and waits for completion. Does nothing in normal mode, since sensor converts
+// data autonomously and data registers keep latest sample.
+func (v *SensorBMP280) triggerMeasurement(bus Bus, osrt, osrp byte) error {
+	if v.mode == POWER_MODE_NORMAL {
+		return nil
+	}
 	var power byte = 1 // Forced mode
-	osrt := v.getOversamplingRation(accuracy)
-	err := i2c.WriteRegU8(BMP280_CNTR_MEAS_REG, power|(osrt<<5))
+	err := bus.WriteRegU8(BMP280_CNTR_MEAS_REG, power|(osrt<<5)|(osrp<<2))
 	if err != nil {
-		return 0, err
+		return err
+	}
+	err = waitForCompletion(v, bus)
+	return err
+}
+
+// SetPowerMode switch sensor to sleep, forced or normal mode.
+// Oversampling from settings is used for normal mode.
+func (v *SensorBMP280) SetPowerMode(bus Bus, mode PowerMode, settings MeasureSettings) error {
+	var power byte
+	switch mode {
+	case POWER_MODE_SLEEP, POWER_MODE_FORCED:
+		// forced conversion is triggered on each read,
+		// sensor stays in sleep mode meanwhile
+		power = 0
+	case POWER_MODE_NORMAL:
+		power = 3
+	default:
+		return &NotSupportedError{Sensor: BMP280, Feature: fmt.Sprintf("power mode %v", mode)}
 	}
-	_, err = waitForCompletion(v, i2c)
+	osrt := v.getOversamplingRation(settings.Temperature)
+	osrp := v.getOversamplingRation(settings.Pressure)
+	err := bus.WriteRegU8(BMP280_CNTR_MEAS_REG, power|(osrt<<5)|(osrp<<2))
+	if err != nil {
+		return err
+	}
+	v.mode = mode
+	return nil
+}
+
+// SetStandbyTime setup standby time between measurements in normal mode,
+// keeping IIR filter and SPI 3-wire settings unchanged.
+func (v *SensorBMP280) SetStandbyTime(bus Bus, standby StandbyTime) error {
+	if standby < STANDBY_0_5_MS || standby > STANDBY_4000_MS {
+		return &NotSupportedError{Sensor: BMP280, Feature: fmt.Sprintf("standby time %v", standby.Duration())}
+	}
+	return updateRegU8(bus, BMP280_CONFIG, 0x7<<5, byte(standby)<<5)
+}
+
+// readUncompTemprature reads uncompensated temprature from sensor.
+func (v *SensorBMP280) readUncompTemprature(bus Bus, accuracy AccuracyMode) (int32, error) {
+	osrt := v.getOversamplingRation(accuracy)
+	err := v.triggerMeasurement(bus, osrt, 0)
 	if err != nil {
 		return 0, err
 	}
-	buf, _, err := i2c.ReadRegBytes(BMP280_TEMP_OUT_MSB_LSB_XLSB, 3)
+	buf, _, err := bus.ReadRegBytes(BMP280_TEMP_OUT_MSB_LSB_XLSB, 3)
 	if err != nil {
 		return 0, err
 	}
@@ -291,18 +352,13 @@ func (v *SensorBMP280) readUncompTemprature(i2c *i2c.I2C, accuracy AccuracyMode)
 }
 
 // readUncompPressure reads atmospheric uncompensated pressure from sensor.
-func (v *SensorBMP280) readUncompPressure(i2c *i2c.I2C, accuracy AccuracyMode) (int32, error) {
-	var power byte = 1 // Forced mode
+func (v *SensorBMP280) readUncompPressure(bus Bus, accuracy AccuracyMode) (int32, error) {
 	osrp := v.getOversamplingRation(accuracy)
-	err := i2c.WriteRegU8(BMP280_CNTR_MEAS_REG, power|(osrp<<2))
-	if err != nil {
-		return 0, err
-	}
-	_, err = waitForCompletion(v, i2c)
+	err := v.triggerMeasurement(bus, 0, osrp)
 	if err != nil {
 		return 0, err
 	}
-	buf, _, err := i2c.ReadRegBytes(BMP280_PRESS_OUT_MSB_LSB_XLSB, 3)
+	buf, _, err := bus.ReadRegBytes(BMP280_PRESS_OUT_MSB_LSB_XLSB, 3)
 	if err != nil {
 		return 0, err
 	}
@@ -314,25 +370,20 @@ func (v *SensorBMP280) readUncompPressure(i2c *i2c.I2C, accuracy AccuracyMode) (
 // atmospheric uncompensated pressure from sensor.
 // BMP280 allows to read temprature and pressure in one cycle,
 // BMP180 - doesn't.
-func (v *SensorBMP280) readUncompTempratureAndPressure(i2c *i2c.I2C,
+func (v *SensorBMP280) readUncompTempratureAndPressure(bus Bus,
 	accuracy AccuracyMode) (temprature int32, pressure int32, err error) {
-	var power byte = 1 // Forced mode
 	osrt := v.getOversamplingRation(ACCURACY_STANDARD)
 	osrp := v.getOversamplingRation(accuracy)
-	err = i2c.WriteRegU8(BMP280_CNTR_MEAS_REG, power|(osrt<<5)|(osrp<<2))
+	err = v.triggerMeasurement(bus, osrt, osrp)
 	if err != nil {
 		return 0, 0, err
 	}
-	_, err = waitForCompletion(v, i2c)
-	if err != nil {
-		return 0, 0, err
-	}
-	buf, _, err := i2c.ReadRegBytes(BMP280_TEMP_OUT_MSB_LSB_XLSB, 3)
+	buf, _, err := bus.ReadRegBytes(BMP280_TEMP_OUT_MSB_LSB_XLSB, 3)
 	if err != nil {
 		return 0, 0, err
 	}
 	ut := int32(buf[0])<<12 + int32(buf[1])<<4 + int32(buf[2]&0xF0)>>4
-	buf, _, err = i2c.ReadRegBytes(BMP280_PRESS_OUT_MSB_LSB_XLSB, 3)
+	buf, _, err = bus.ReadRegBytes(BMP280_PRESS_OUT_MSB_LSB_XLSB, 3)
 	if err != nil {
 		return 0, 0, err
 	}
@@ -340,50 +391,23 @@ func (v *SensorBMP280) readUncompTempratureAndPressure(i2c *i2c.I2C,
 	return ut, up, nil
 }
 
-// ReadTemperatureMult100C reads and calculates temrature in C (celsius) multiplied by 100.
-// Multiplication approach allow to keep result as integer number.
-func (v *SensorBMP280) ReadTemperatureMult100C(i2c *i2c.I2C, accuracy AccuracyMode) (int32, error) {
-	ut, err := v.readUncompTemprature(i2c, accuracy)
-	if err != nil {
-		return 0, err
-	}
-	err = v.ReadCoefficients(i2c)
-	if err != nil {
-		return 0, err
-	}
-
+// compensateTemperature calculates temrature in C (celsius) multiplied by 100
+// from uncompensated value ut. Returns t_fine as well, required to compensate pressure.
+func (v *SensorBMP280) compensateTemperature(ut int32) (t int32, tFine int32) {
 	var1 := ((ut>>3 - int32(v.Coeff.dig_T1())<<1) * int32(v.Coeff.dig_T2())) >> 11
 	lg.Debugf("var1=%v", var1)
 	var2 := (((ut>>4 - int32(v.Coeff.dig_T1())) * (ut>>4 - int32(v.Coeff.dig_T1()))) >> 12 *
 		int32(v.Coeff.dig_T3())) >> 14
-	lg.Debugf("var1=%v", var2)
-	tFine := var1 + var2
+	lg.Debugf("var2=%v", var2)
+	tFine = var1 + var2
 	lg.Debugf("t_fine=%v", tFine)
-	t := (tFine*5 + 128) >> 8
-	return t, nil
+	t = (tFine*5 + 128) >> 8
+	return t, tFine
 }
 
-// ReadPressureMult10Pa reads and calculates atmospheric pressure in Pa (Pascal) multiplied by 10.
-// Multiplication approach allow to keep result as integer number.
-func (v *SensorBMP280) ReadPressureMult10Pa(i2c *i2c.I2C, accuracy AccuracyMode) (uint32, error) {
-	ut, up, err := v.readUncompTempratureAndPressure(i2c, accuracy)
-	if err != nil {
-		return 0, err
-	}
-	lg.Debugf("ut=%v, up=%v", ut, up)
-
-	err = v.ReadCoefficients(i2c)
-	if err != nil {
-		return 0, err
-	}
-
-	var01 := ((ut>>3 - int32(v.Coeff.dig_T1())<<1) * int32(v.Coeff.dig_T2())) >> 11
-	lg.Debugf("var01=%v", var01)
-	var02 := (((ut>>4 - int32(v.Coeff.dig_T1())) * (ut>>4 - int32(v.Coeff.dig_T1()))) >> 12 *
-		int32(v.Coeff.dig_T3())) >> 14
-	lg.Debugf("var01=%v", var02)
-	tFine := var01 + var02
-
+// compensatePressure calculates atmospheric pressure in Pa (Pascal) multiplied by 10
+// from uncompensated value up and t_fine obtained from temperature compensation.
+func (v *SensorBMP280) compensatePressure(up int32, tFine int32) uint32 {
 	var1 := int64(tFine) - 128000
 	lg.Debugf("var1=%v", var1)
 	var2 := var1 * var1 * int64(v.Coeff.dig_P6())
@@ -395,7 +419,7 @@ func (v *SensorBMP280) ReadPressureMult10Pa(i2c *i2c.I2C, accuracy AccuracyMode)
 	var1 = ((int64(1)<<47 + var1) * int64(v.Coeff.dig_P1())) >> 33
 	lg.Debugf("var1=%v", var1)
 	if var1 == 0 {
-		return 0, nil
+		return 0
 	}
 	p1 := int64(1048576) - int64(up)
 	p1 = ((p1<<31 - var2) * 3125) / var1
@@ -403,13 +427,72 @@ func (v *SensorBMP280) ReadPressureMult10Pa(i2c *i2c.I2C, accuracy AccuracyMode)
 	var2 = (int64(v.Coeff.dig_P8()) * p1) >> 19
 	p1 = (p1+var1+var2)>>8 + int64(v.Coeff.dig_P7())<<4
 	p2 := p1 * 10 / 256
-	p := uint32(p2)
+	return uint32(p2)
+}
+
+// ReadTemperatureMult100C reads and calculates temrature in C (celsius) multiplied by 100.
+// Multiplication approach allow to keep result as integer number.
+func (v *SensorBMP280) ReadTemperatureMult100C(bus Bus, accuracy AccuracyMode) (int32, error) {
+	ut, err := v.readUncompTemprature(bus, accuracy)
+	if err != nil {
+		return 0, err
+	}
+	err = v.loadCoefficients(bus)
+	if err != nil {
+		return 0, err
+	}
+	t, _ := v.compensateTemperature(ut)
+	return t, nil
+}
+
+// ReadPressureMult10Pa reads and calculates atmospheric pressure in Pa (Pascal) multiplied by 10.
+// Multiplication approach allow to keep result as integer number.
+func (v *SensorBMP280) ReadPressureMult10Pa(bus Bus, accuracy AccuracyMode) (uint32, error) {
+	ut, up, err := v.readUncompTempratureAndPressure(bus, accuracy)
+	if err != nil {
+		return 0, err
+	}
+	lg.Debugf("ut=%v, up=%v", ut, up)
 
+	err = v.loadCoefficients(bus)
+	if err != nil {
+		return 0, err
+	}
+	_, tFine := v.compensateTemperature(ut)
+	p := v.compensatePressure(up, tFine)
 	return p, nil
 }
 
 // ReadHumidityMultQ2210 does nothing. Humidity function is not applicable for BMP280.
-func (v *SensorBMP280) ReadHumidityMultQ2210(i2c *i2c.I2C, accuracy AccuracyMode) (bool, uint32, error) {
+func (v *SensorBMP280) ReadHumidityMultQ2210(bus Bus, accuracy AccuracyMode) (bool, uint32, error) {
 	// Not supported
 	return false, 0, nil
 }
+
+// Measure runs single forced conversion of temperature and pressure,
+// and reads out both values in one burst read. In normal mode latest sample is read.
+func (v *SensorBMP280) Measure(bus Bus, settings MeasureSettings) (*Measurement, error) {
+	err := v.loadCoefficients(bus)
+	if err != nil {
+		return nil, err
+	}
+	osrt := v.getOversamplingRation(settings.Temperature)
+	osrp := v.getOversamplingRation(settings.Pressure)
+	err = v.triggerMeasurement(bus, osrt, osrp)
+	if err != nil {
+		return nil, err
+	}
+	// pressure and temperature registers go one by one
+	buf, _, err := bus.ReadRegBytes(BMP280_PRESS_OUT_MSB_LSB_XLSB, 6)
+	if err != nil {
+		return nil, err
+	}
+	m := &Measurement{Time: time.Now()}
+	m.Raw.Pressure = getU20BE(buf[0:3])
+	m.Raw.Temperature = getU20BE(buf[3:6])
+	lg.Debugf("ut=%v, up=%v", m.Raw.Temperature, m.Raw.Pressure)
+	t, tFine := v.compensateTemperature(m.Raw.Temperature)
+	m.TemperatureMult100C = t
+	m.PressureMult10Pa = v.compensatePressure(m.Raw.Pressure, tFine)
+	return m, nil
+}
@@ -27,8 +27,7 @@ import (
 	"encoding/binary"
 	"errors"
 	"fmt"
-
-	i2c "github.com/d2r2/go-i2c"
+	"time"
 )
 
 // BMP388 sensors memory map
@@ -39,13 +38,26 @@ const (
 	BMP388_ERR_REG    = 0x02
 	//	BMP388_CNTR_MEAS_REG = 0xF4  // No such reg in BMP388
 	BMP388_ODR_REG      = 0x1D // Data Rate control
-	BMP388_OSR_REG      = 0x1D // Over sample rate control
+	BMP388_OSR_REG      = 0x1C // Over sample rate control
 	BMP388_PWR_CTRL_REG = 0x1B // enable/disable press or temp, set operating mode
 	// CONFIG Register is used to set IIR Filter coefficent
-	BMP388_CONFIG = 0x1F // TODO: support IIR filter settings
+	BMP388_CONFIG = 0x1F
 	//	BMP388_RESET         = 0xE0 // TODO: '388 doesn't have a reset register
 	BMP388_CMD_REG = 0x7E
 	//  cmds - nop, extmode, clear FIFO, softreset
+	BMP388_CMD_FIFO_FLUSH = 0xB0
+	BMP388_CMD_SOFT_RESET = 0xB6
+	// FIFO registers
+	BMP388_FIFO_LENGTH_0 = 0x12 // FIFO fill level in bytes, 9 bits
+	BMP388_FIFO_LENGTH_1 = 0x13
+	BMP388_FIFO_DATA     = 0x14 // FIFO read port
+	BMP388_FIFO_WTM_0    = 0x15 // FIFO watermark, 9 bits
+	BMP388_FIFO_WTM_1    = 0x16
+	BMP388_FIFO_CONFIG_1 = 0x17 // FIFO mode, stop on full, frames content
+	BMP388_FIFO_CONFIG_2 = 0x18 // FIFO subsampling, data source
+	// Interrupt registers
+	BMP388_INT_STATUS = 0x11 // interrupt status, cleared on read
+	BMP388_INT_CTRL   = 0x19 // INT pin configuration, interrupt sources
 	// BMP388 specific compensation register's block
 	BMP388_COEF_START = 0x31
 	BMP388_COEF_BYTES = 21
@@ -59,13 +71,13 @@ const (
 
 	// IIR Filter coefficent
 	BMP388_coef_0   = 0 // bypass-mode
-	BMP388_coef_1   = 0
-	BMP388_coef_3   = 0
-	BMP388_coef_7   = 0
-	BMP388_coef_15  = 0
-	BMP388_coef_31  = 0
-	BMP388_coef_63  = 0
-	BMP388_coef_127 = 0
+	BMP388_coef_1   = 1
+	BMP388_coef_3   = 2
+	BMP388_coef_7   = 3
+	BMP388_coef_15  = 4
+	BMP388_coef_31  = 5
+	BMP388_coef_63  = 6
+	BMP388_coef_127 = 7
 )
 
 // Unique BMP388 calibration coefficients
@@ -150,19 +162,62 @@ func (v *CoeffBMP388) PAR_P11() int8 {
 	return int8(uint16(v.COEF_45))
 }
 
-// SensorBMP388 specific type
+// SensorBMP388 specific type. It drives BMP390 and BMP384 as well,
+// sharing with BMP388 the same register map and compensation.
 type SensorBMP388 struct {
 	Coeff *CoeffBMP388
+	// BMP3 family member, BMP388 by default
+	variant SensorType
+	// Operating mode, forced by default
+	mode PowerMode
 }
 
 // Static cast to verify at compile time
 // that type implement interface.
 var _ SensorInterface = &SensorBMP388{}
+var _ PowerModeInterface = &SensorBMP388{}
+var _ ODRInterface = &SensorBMP388{}
+var _ FIFOInterface = &SensorBMP388{}
+var _ InterruptInterface = &SensorBMP388{}
+var _ SpecificationInterface = &SensorBMP388{}
+
+// Datasheet characteristics of BMP3 family members.
+// All of them support output data rate up to 200 Hz.
+var (
+	bmp388Specification = Specification{AbsoluteAccuracyPa: 50,
+		RelativeAccuracyPa: 8, TempCoeffOffsetPa: 0.75, NoisePa: 0.03}
+	bmp390Specification = Specification{AbsoluteAccuracyPa: 50,
+		RelativeAccuracyPa: 3, TempCoeffOffsetPa: 0.6, NoisePa: 0.02}
+	bmp384Specification = Specification{AbsoluteAccuracyPa: 50,
+		RelativeAccuracyPa: 9, TempCoeffOffsetPa: 1, NoisePa: 0.02}
+)
+
+// Variant return BMP3 family member driven: BMP388, BMP390 or BMP384.
+func (v *SensorBMP388) Variant() SensorType {
+	switch v.variant {
+	case BMP390, BMP384:
+		return v.variant
+	default:
+		return BMP388
+	}
+}
+
+// Specification return datasheet characteristics of sensor variant.
+func (v *SensorBMP388) Specification() Specification {
+	switch v.Variant() {
+	case BMP390:
+		return bmp390Specification
+	case BMP384:
+		return bmp384Specification
+	default:
+		return bmp388Specification
+	}
+}
 
 // ReadSensorID reads sensor signature. It may be used for validation,
 // that proper code settings used for sensor data decoding.
-func (v *SensorBMP388) ReadSensorID(i2c *i2c.I2C) (uint8, error) {
-	id, err := i2c.ReadRegU8(BMP388_ID_REG)
+func (v *SensorBMP388) ReadSensorID(bus Bus) (uint8, error) {
+	id, err := bus.ReadRegU8(BMP388_ID_REG)
 	if err != nil {
 		return 0, err
 	}
@@ -170,13 +225,9 @@ func (v *SensorBMP388) ReadSensorID(i2c *i2c.I2C) (uint8, error) {
 }
 
 // ReadCoefficients reads compensation coefficients, unique for each sensor.
-func (v *SensorBMP388) ReadCoefficients(i2c *i2c.I2C) error {
-	_, err := i2c.WriteBytes([]byte{BMP388_COEF_START})
-	if err != nil {
-		return err
-	}
+func (v *SensorBMP388) ReadCoefficients(bus Bus) error {
 	var coef1 [BMP388_COEF_BYTES]byte
-	err = readDataToStruct(i2c, BMP388_COEF_BYTES,
+	err := readDataToStruct(bus, BMP388_COEF_START, BMP388_COEF_BYTES,
 		binary.LittleEndian, &coef1)
 	if err != nil {
 		return err
@@ -191,6 +242,15 @@ func (v *SensorBMP388) ReadCoefficients(i2c *i2c.I2C) error {
 	return nil
 }
 
+// loadCoefficients reads compensation coefficients only once,
+// since they never change. Use ReadCoefficients to force reload.
+func (v *SensorBMP388) loadCoefficients(bus Bus) error {
+	if v.Coeff == nil {
+		return v.ReadCoefficients(bus)
+	}
+	return nil
+}
+
 // IsValidCoefficients verify that compensate registers
 // are not empty, and thus are valid.
 func (v *SensorBMP388) IsValidCoefficients() error {
@@ -276,11 +336,15 @@ func (v *SensorBMP388) IsValidCoefficients() error {
 // RecognizeSignature returns description of signature if it valid,
 // otherwise - error.
 func (v *SensorBMP388) RecognizeSignature(signature uint8) (string, error) {
-	switch signature {
-	case 0x50:
-		return "BMP388", nil
+	variant := v.Variant()
+	switch {
+	case signature == 0x50 && variant != BMP390:
+		// BMP384 has the same chip identifier as BMP388
+		return variant.String(), nil
+	case signature == 0x60 && variant == BMP390:
+		return "BMP390", nil
 	default:
-		return "", errors.New(fmt.Sprintf("signature 0x%x doesn't belong to BMP388 series", signature))
+		return "", &SignatureError{Sensor: variant, Signature: signature}
 	}
 }
 
@@ -291,10 +355,10 @@ func (v *SensorBMP388) RecognizeSignature(signature uint8) (string, error) {
 //    busy/done bit.
 //    for now - we return TRUE when any of the done bits go true
 //   TODO: break out the busy polling
-func (v *SensorBMP388) IsBusy(i2c *i2c.I2C) (busy bool, err error) {
+func (v *SensorBMP388) IsBusy(bus Bus) (busy bool, err error) {
 	// Check flag to know status of calculation, according
 	// to specification about SCO (Start of conversion) flag
-	b, err := i2c.ReadRegU8(BMP388_STATUS_REG)
+	b, err := bus.ReadRegU8(BMP388_STATUS_REG)
 	if err != nil {
 		return false, err
 	}
@@ -303,6 +367,14 @@ func (v *SensorBMP388) IsBusy(i2c *i2c.I2C) (busy bool, err error) {
 	return b == 0, nil
 }
 
+// SetIIRFilter setup IIR filter coefficient in config register.
+func (v *SensorBMP388) SetIIRFilter(bus Bus, filter IIRFilter) error {
+	if filter < IIR_FILTER_OFF || filter > IIR_FILTER_128 {
+		return &NotSupportedError{Sensor: v.Variant(), Feature: fmt.Sprintf("IIR filter %v", filter)}
+	}
+	return updateRegU8(bus, BMP388_CONFIG, 0x7<<1, byte(filter)<<1)
+}
+
 func (v *SensorBMP388) getOversamplingRation(accuracy AccuracyMode) byte {
 	var b byte
 	switch accuracy {
@@ -325,63 +397,106 @@ func (v *SensorBMP388) getOversamplingRation(accuracy AccuracyMode) byte {
 	return b
 }
 
-// readUncompTemprature reads uncompensated temprature from sensor.
-func (v *SensorBMP388) readUncompTemprature(i2c *i2c.I2C, accuracy AccuracyMode) (int32, error) {
-	//  set IIR filter to bypass
-	err := i2c.WriteRegU8(BMP388_CONFIG, BMP388_coef_0<<1)
+// triggerMeasurement starts forced conversion of temperature and pressure
+// with oversampling specified and waits for completion. Does nothing in normal
+// mode, since sensor converts data autonomously and data registers keep latest sample.
+func (v *SensorBMP388) triggerMeasurement(bus Bus, osrt, osrp byte) error {
+	if v.mode == POWER_MODE_NORMAL {
+		return nil
+	}
+	// IIR filter is left as configured by SetIIRFilter
+	err := bus.WriteRegU8(BMP388_OSR_REG, (osrt<<3)|osrp)
 	if err != nil {
-		return 0, err
+		return err
 	}
-	//   set over sample rate to 1x
-	osrt := v.getOversamplingRation(accuracy)
-	err = i2c.WriteRegU8(BMP388_OSR_REG, osrt<<3)
+	err = clearInterrupt(v, bus)
 	if err != nil {
-		return 0, err
+		return err
 	}
-	// enable pres and temp measuremeent, start a measurment
+	// enable pres and temp measurement, start a measurement
 	var power byte = (BMP388_PWR_MODE_FORCED << 4) | 3 // enable pres, temp, FORCED operating mode
 	lg.Debugf("power=0x%0X", power)
-	err = i2c.WriteRegU8(BMP388_PWR_CTRL_REG, power)
+	err = bus.WriteRegU8(BMP388_PWR_CTRL_REG, power)
 	if err != nil {
-		return 0, err
+		return err
+	}
+	err = waitForCompletion(v, bus)
+	return err
+}
+
+// SetPowerMode switch sensor to sleep, forced or normal mode.
+// Oversampling from settings is used for normal mode.
+func (v *SensorBMP388) SetPowerMode(bus Bus, mode PowerMode, settings MeasureSettings) error {
+	var power byte
+	switch mode {
+	case POWER_MODE_SLEEP, POWER_MODE_FORCED:
+		// forced conversion is triggered on each read,
+		// sensor stays in sleep mode meanwhile
+		power = BMP388_PWR_MODE_SLEEP << 4
+	case POWER_MODE_NORMAL:
+		power = (BMP388_PWR_MODE_NORMAL << 4) | 3 // enable pres, temp, NORMAL operating mode
+	default:
+		return &NotSupportedError{Sensor: v.Variant(), Feature: fmt.Sprintf("power mode %v", mode)}
 	}
-	_, err = waitForCompletion(v, i2c)
+	// mode can't be changed from normal to forced and vice versa
+	// directly, so always go through sleep mode
+	err := bus.WriteRegU8(BMP388_PWR_CTRL_REG, BMP388_PWR_MODE_SLEEP<<4)
 	if err != nil {
-		return 0, err
+		return err
 	}
-	buf, _, err := i2c.ReadRegBytes(BMP388_TEMP_OUT_MSB_LSB_XLSB, 3)
+	osrt := v.getOversamplingRation(settings.Temperature)
+	osrp := v.getOversamplingRation(settings.Pressure)
+	err = bus.WriteRegU8(BMP388_OSR_REG, (osrt<<3)|osrp)
 	if err != nil {
-		return 0, err
+		return err
 	}
-	ut := int32(uint32(buf[0]) + uint32(buf[1])<<8 + uint32(buf[2])<<16)
-	return ut, nil
+	if power != BMP388_PWR_MODE_SLEEP<<4 {
+		err = bus.WriteRegU8(BMP388_PWR_CTRL_REG, power)
+		if err != nil {
+			return err
+		}
+	}
+	v.mode = mode
+	return nil
 }
 
-// readUncompPressure reads atmospheric uncompensated pressure from sensor.
-func (v *SensorBMP388) readUncompPressure(i2c *i2c.I2C, accuracy AccuracyMode) (int32, error) {
-	var power byte = (BMP388_PWR_MODE_FORCED << 4) | 3 // enable pres, temp, FORCED operating mode
-	err := i2c.WriteRegU8(BMP388_PWR_CTRL_REG, power)
-	if err != nil {
-		return 0, err
+// SetOutputDataRate setup sampling period in normal mode. Measurement time,
+// defined by oversampling, must fit sampling period, otherwise sensor
+// rejects configuration in normal mode.
+func (v *SensorBMP388) SetOutputDataRate(bus Bus, odr OutputDataRate) error {
+	if odr < ODR_200_HZ || odr > ODR_0_0015_HZ {
+		return &NotSupportedError{Sensor: v.Variant(), Feature: fmt.Sprintf("output data rate %d", odr)}
 	}
-	_, err = waitForCompletion(v, i2c)
+	return bus.WriteRegU8(BMP388_ODR_REG, byte(odr))
+}
+
+// readUncompTemprature reads uncompensated temprature from sensor.
+func (v *SensorBMP388) readUncompTemprature(bus Bus, accuracy AccuracyMode) (int32, error) {
+	osrt := v.getOversamplingRation(accuracy)
+	err := v.triggerMeasurement(bus, osrt, 0)
 	if err != nil {
 		return 0, err
 	}
-	osrp := v.getOversamplingRation(accuracy)
-	err = i2c.WriteRegU8(BMP388_OSR_REG, osrp)
+	buf, _, err := bus.ReadRegBytes(BMP388_TEMP_OUT_MSB_LSB_XLSB, 3)
 	if err != nil {
 		return 0, err
 	}
-	_, err = waitForCompletion(v, i2c)
+	ut := getU24LE(buf)
+	return ut, nil
+}
+
+// readUncompPressure reads atmospheric uncompensated pressure from sensor.
+func (v *SensorBMP388) readUncompPressure(bus Bus, accuracy AccuracyMode) (int32, error) {
+	osrp := v.getOversamplingRation(accuracy)
+	err := v.triggerMeasurement(bus, 0, osrp)
 	if err != nil {
 		return 0, err
 	}
-	buf, _, err := i2c.ReadRegBytes(BMP388_PRES_OUT_MSB_LSB_XLSB, 3)
+	buf, _, err := bus.ReadRegBytes(BMP388_PRES_OUT_MSB_LSB_XLSB, 3)
 	if err != nil {
 		return 0, err
 	}
-	up := int32(buf[0]) + int32(buf[1])<<8 + int32(buf[2])<<16
+	up := getU24LE(buf)
 	return up, nil
 }
 
@@ -389,53 +504,27 @@ func (v *SensorBMP388) readUncompPressure(i2c *i2c.I2C, accuracy AccuracyMode) (
 // atmospheric uncompensated pressure from sensor.
 // BMP388 allows to read temprature and pressure in one cycle,
 // BMP180 - doesn't.
-func (v *SensorBMP388) readUncompTempratureAndPressure(i2c *i2c.I2C,
+func (v *SensorBMP388) readUncompTempratureAndPressure(bus Bus,
 	accuracy AccuracyMode) (temprature int32, pressure int32, err error) {
-	var power byte = (BMP388_PWR_MODE_FORCED << 4) | 3 // enable pres, temp, FORCED operating mode
-	err = i2c.WriteRegU8(BMP388_PWR_CTRL_REG, power)
-	if err != nil {
-		return 0, 0, err
-	}
-	_, err = waitForCompletion(v, i2c)
-	if err != nil {
-		return 0, 0, err
-	}
 	osrt := v.getOversamplingRation(ACCURACY_STANDARD)
 	osrp := v.getOversamplingRation(accuracy)
-	err = i2c.WriteRegU8(BMP388_OSR_REG, (osrt<<3)|osrp)
+	err = v.triggerMeasurement(bus, osrt, osrp)
 	if err != nil {
 		return 0, 0, err
 	}
-	_, err = waitForCompletion(v, i2c)
+	// pressure and temperature registers go one by one
+	buf, _, err := bus.ReadRegBytes(BMP388_PRES_OUT_MSB_LSB_XLSB, 6)
 	if err != nil {
 		return 0, 0, err
 	}
-	buf, _, err := i2c.ReadRegBytes(BMP388_TEMP_OUT_MSB_LSB_XLSB, 3)
-	if err != nil {
-		return 0, 0, err
-	}
-	ut := int32(buf[0]) + int32(buf[1])<<8 + int32(buf[2])<<16
-	buf, _, err = i2c.ReadRegBytes(BMP388_PRES_OUT_MSB_LSB_XLSB, 3)
-	if err != nil {
-		return 0, 0, err
-	}
-	up := int32(buf[0]) + int32(buf[1])<<8 + int32(buf[2])<<16
+	up := getU24LE(buf[0:3])
+	ut := getU24LE(buf[3:6])
 	return ut, up, nil
 }
 
-// ReadTemperatureMult100C reads and calculates temrature in C (celsius) multiplied by 100.
-// Multiplication approach allow to keep result as integer number.
-func (v *SensorBMP388) ReadTemperatureMult100C(i2c *i2c.I2C, accuracy AccuracyMode) (int32, error) {
-
-	ut, err := v.readUncompTemprature(i2c, accuracy)
-	if err != nil {
-		return 0, err
-	}
-	err = v.ReadCoefficients(i2c)
-	if err != nil {
-		return 0, err
-	}
-
+// compensateTemperature calculates temrature in C (celsius) multiplied by 100
+// from uncompensated value ut. Returns t_lin as well, required to compensate pressure.
+func (v *SensorBMP388) compensateTemperature(ut int32) (t int32, tLin int64) {
 	//  comp formula - taken from BMP3 API on github
 	partial_data1 := uint64(ut - int32(256*int32(v.Coeff.PAR_T1())))
 	partial_data2 := uint64(v.Coeff.PAR_T2()) * partial_data1
@@ -443,7 +532,7 @@ func (v *SensorBMP388) ReadTemperatureMult100C(i2c *i2c.I2C, accuracy AccuracyMo
 	partial_data4 := int64(partial_data3) * int64(v.Coeff.PAR_T3())
 	partial_data5 := (int64(partial_data2*262144) + partial_data4)
 	partial_data6 := partial_data5 / 4294967269
-	t := int32(partial_data6 * 25 / 16384)
+	t = int32(partial_data6 * 25 / 16384)
 	lg.Debugf("ut=%v", ut)
 	lg.Debugf("d1=%v ", partial_data1)
 	lg.Debugf("p_d2=%v ", partial_data2)
@@ -451,36 +540,12 @@ func (v *SensorBMP388) ReadTemperatureMult100C(i2c *i2c.I2C, accuracy AccuracyMo
 	lg.Debugf("p_d4=%v ", partial_data4)
 	lg.Debugf("p_d5=%v ", partial_data5)
 	lg.Debugf("p_d6=%v ", partial_data6)
-	return t, nil
-
+	return t, partial_data6
 }
 
-// ReadPressureMult10Pa reads and calculates atmospheric pressure in Pa (Pascal) multiplied by 10.
-// Multiplication approach allow to keep result as integer number.
-func (v *SensorBMP388) ReadPressureMult10Pa(i2c *i2c.I2C, accuracy AccuracyMode) (uint32, error) {
-	ut, up, err := v.readUncompTempratureAndPressure(i2c, accuracy)
-	if err != nil {
-		return 0, err
-	}
-	lg.Debugf("ut=%v, up=%v", ut, up)
-
-	err = v.ReadCoefficients(i2c)
-	if err != nil {
-		return 0, err
-	}
-
-	//  Comp temp for use in pressure comp
-	//  comp formula - taken from BMP3 API on github
-	partial_data1_t := uint64(ut - int32(256*int32(v.Coeff.PAR_T1())))
-	partial_data2_t := uint64(v.Coeff.PAR_T2()) * partial_data1_t
-	partial_data3_t := partial_data1_t * partial_data1_t
-	partial_data4_t := int64(partial_data3_t) * int64(v.Coeff.PAR_T3())
-	partial_data5_t := (int64(partial_data2_t*262144) + partial_data4_t)
-	partial_data6_t := partial_data5_t / 4294967269
-	t_lin := partial_data6_t
-	lg.Debugf("t_lin=%v", t_lin)
-	lg.Debugf("----------")
-
+// compensatePressure calculates atmospheric pressure in Pa (Pascal) multiplied by 10
+// from uncompensated value up and t_lin obtained from temperature compensation.
+func (v *SensorBMP388) compensatePressure(up int32, t_lin int64) uint32 {
 	//  Compensate pressure - fixed point/integer arthmetic
 	//  taken form formulas written in github
 	partial_data1 := t_lin * t_lin
@@ -529,13 +594,79 @@ func (v *SensorBMP388) ReadPressureMult10Pa(i2c *i2c.I2C, accuracy AccuracyMode)
 	lg.Debugf("partial_data2=%v", partial_data2)
 	lg.Debugf("partial_data3=%v", partial_data3)
 	lg.Debugf("partial_data4=%v", partial_data4)
-	comp_press := uint32((uint64(partial_data4) * 25) / 1099511627776)
+	// compensated pressure is in Pa multiplied by 100,
+	// so reduce it to Pa multiplied by 10
+	comp_press := uint32((uint64(partial_data4) * 25) / 1099511627776 / 10)
+
+	return comp_press
+}
+
+// ReadTemperatureMult100C reads and calculates temrature in C (celsius) multiplied by 100.
+// Multiplication approach allow to keep result as integer number.
+func (v *SensorBMP388) ReadTemperatureMult100C(bus Bus, accuracy AccuracyMode) (int32, error) {
+
+	ut, err := v.readUncompTemprature(bus, accuracy)
+	if err != nil {
+		return 0, err
+	}
+	err = v.loadCoefficients(bus)
+	if err != nil {
+		return 0, err
+	}
+	t, _ := v.compensateTemperature(ut)
+	return t, nil
+
+}
+
+// ReadPressureMult10Pa reads and calculates atmospheric pressure in Pa (Pascal) multiplied by 10.
+// Multiplication approach allow to keep result as integer number.
+func (v *SensorBMP388) ReadPressureMult10Pa(bus Bus, accuracy AccuracyMode) (uint32, error) {
+	ut, up, err := v.readUncompTempratureAndPressure(bus, accuracy)
+	if err != nil {
+		return 0, err
+	}
+	lg.Debugf("ut=%v, up=%v", ut, up)
 
-	return comp_press, nil
+	err = v.loadCoefficients(bus)
+	if err != nil {
+		return 0, err
+	}
+	_, tLin := v.compensateTemperature(ut)
+	lg.Debugf("t_lin=%v", tLin)
+	p := v.compensatePressure(up, tLin)
+	return p, nil
 }
 
 // ReadHumidityMultQ2210 does nothing. Humidity function is not applicable for BMP388.
-func (v *SensorBMP388) ReadHumidityMultQ2210(i2c *i2c.I2C, accuracy AccuracyMode) (bool, uint32, error) {
+func (v *SensorBMP388) ReadHumidityMultQ2210(bus Bus, accuracy AccuracyMode) (bool, uint32, error) {
 	// Not supported
 	return false, 0, nil
 }
+
+// Measure runs single forced conversion of temperature and pressure,
+// and reads out both values in one burst read. In normal mode latest sample is read.
+func (v *SensorBMP388) Measure(bus Bus, settings MeasureSettings) (*Measurement, error) {
+	err := v.loadCoefficients(bus)
+	if err != nil {
+		return nil, err
+	}
+	osrt := v.getOversamplingRation(settings.Temperature)
+	osrp := v.getOversamplingRation(settings.Pressure)
+	err = v.triggerMeasurement(bus, osrt, osrp)
+	if err != nil {
+		return nil, err
+	}
+	// pressure and temperature registers go one by one
+	buf, _, err := bus.ReadRegBytes(BMP388_PRES_OUT_MSB_LSB_XLSB, 6)
+	if err != nil {
+		return nil, err
+	}
+	m := &Measurement{Time: time.Now()}
+	m.Raw.Pressure = getU24LE(buf[0:3])
+	m.Raw.Temperature = getU24LE(buf[3:6])
+	lg.Debugf("ut=%v, up=%v", m.Raw.Temperature, m.Raw.Pressure)
+	t, tLin := v.compensateTemperature(m.Raw.Temperature)
+	m.TemperatureMult100C = t
+	m.PressureMult10Pa = v.compensatePressure(m.Raw.Pressure, tLin)
+	return m, nil
+}
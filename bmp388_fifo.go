@@ -0,0 +1,277 @@
+//--------------------------------------------------------------------------------------------------
+//
+// Copyright (c) 2018 Denis Dyakov
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy of this software and
+// associated documentation files (the "Software"), to deal in the Software without restriction,
+// including without limitation the rights to use, copy, modify, merge, publish, distribute, sublicense,
+// and/or sell copies of the Software, and to permit persons to whom the Software is furnished to do so,
+// subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all copies or substantial
+// portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR IMPLIED, INCLUDING
+// BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY, FITNESS FOR A PARTICULAR PURPOSE AND
+// NONINFRINGEMENT. IN NO EVENT SHALL THE AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM,
+// DAMAGES OR OTHER LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE SOFTWARE.
+//
+//--------------------------------------------------------------------------------------------------
+
+package bsbmp
+
+import (
+	"errors"
+	"fmt"
+)
+
+// BMP388 FIFO buffer size in bytes.
+const BMP388_FIFO_SIZE = 512
+
+// BMP388 FIFO frame headers
+const (
+	BMP388_FIFO_TEMP_PRESS_FRAME   = 0x94 // temperature and pressure, 6 bytes
+	BMP388_FIFO_TEMP_FRAME         = 0x90 // temperature only, 3 bytes
+	BMP388_FIFO_PRESS_FRAME        = 0x84 // pressure only, 3 bytes
+	BMP388_FIFO_TIME_FRAME         = 0xA0 // sensortime, 3 bytes
+	BMP388_FIFO_EMPTY_FRAME        = 0x80 // no more data, 1 byte
+	BMP388_FIFO_CONFIG_CHANGE      = 0x48 // configuration changed, 1 byte
+	BMP388_FIFO_CONFIG_ERROR_FRAME = 0x44 // configuration error, 1 byte
+)
+
+// FIFOSettings describe which data sensor put to FIFO buffer in normal mode.
+type FIFOSettings struct {
+	// Enabled turns FIFO on, otherwise all other settings are ignored.
+	Enabled bool
+	// Pressure and Temperature define content of sensor frames.
+	Pressure    bool
+	Temperature bool
+	// SensorTime append sensortime frame when FIFO
+	// is read out past the last stored frame.
+	SensorTime bool
+	// Subsampling keep only each 2^Subsampling sample (0..7).
+	Subsampling uint8
+	// Filtered store IIR filtered data instead of unfiltered.
+	Filtered bool
+	// StopOnFull stop writing when FIFO is full, otherwise
+	// oldest frames are overwritten.
+	StopOnFull bool
+}
+
+// FIFOSample keep compensated values decoded from single sensor frame.
+type FIFOSample struct {
+	HasTemperature      bool
+	TemperatureMult100C int32
+	HasPressure         bool
+	PressureMult10Pa    uint32
+	// Uncompensated values as stored in FIFO.
+	Raw RawData
+}
+
+// TemperatureC return temperature in C (celsius).
+func (v *FIFOSample) TemperatureC() float32 {
+	return float32(v.TemperatureMult100C) / 100
+}
+
+// PressurePa return atmospheric pressure in Pa (pascal).
+func (v *FIFOSample) PressurePa() float32 {
+	return float32(v.PressureMult10Pa) / 10
+}
+
+// FIFOData is a result of FIFO buffer drain.
+type FIFOData struct {
+	// Samples in the order they were stored (oldest first).
+	Samples []FIFOSample
+	// HasSensorTime is true, if sensortime frame was found.
+	HasSensorTime bool
+	// SensorTime is 24-bit counter value at the moment FIFO
+	// was read out, ticking with 25.6 kHz (39.0625 usec).
+	SensorTime uint32
+	// Amount of configuration change frames found.
+	ConfigChanges int
+	// Amount of configuration error frames found.
+	ConfigErrors int
+}
+
+// SetFIFO write FIFO configuration. FIFO is filled
+// only in normal mode, see SetPowerMode.
+func (v *SensorBMP388) SetFIFO(bus Bus, settings FIFOSettings) error {
+	if settings.Subsampling > 7 {
+		return &NotSupportedError{Sensor: v.Variant(), Feature: fmt.Sprintf("FIFO subsampling %d", settings.Subsampling)}
+	}
+	var config1 byte
+	if settings.Enabled {
+		config1 |= 0x01
+		if settings.StopOnFull {
+			config1 |= 0x02
+		}
+		if settings.SensorTime {
+			config1 |= 0x04
+		}
+		if settings.Pressure {
+			config1 |= 0x08
+		}
+		if settings.Temperature {
+			config1 |= 0x10
+		}
+	}
+	config2 := settings.Subsampling
+	if settings.Filtered {
+		config2 |= 1 << 3
+	}
+	err := updateRegU8(bus, BMP388_FIFO_CONFIG_2, 0x1F, config2)
+	if err != nil {
+		return err
+	}
+	return bus.WriteRegU8(BMP388_FIFO_CONFIG_1, config1)
+}
+
+// ReadFIFOLength return amount of bytes stored in FIFO.
+func (v *SensorBMP388) ReadFIFOLength(bus Bus) (int, error) {
+	buf, _, err := bus.ReadRegBytes(BMP388_FIFO_LENGTH_0, 2)
+	if err != nil {
+		return 0, err
+	}
+	return int(getU16LE(buf) & 0x1FF), nil
+}
+
+// FlushFIFO remove all data from FIFO, keeping FIFO configuration.
+func (v *SensorBMP388) FlushFIFO(bus Bus) error {
+	return bus.WriteRegU8(BMP388_CMD_REG, BMP388_CMD_FIFO_FLUSH)
+}
+
+// ReadFIFO drain FIFO with single burst read and decode frames
+// to compensated samples.
+func (v *SensorBMP388) ReadFIFO(bus Bus) (*FIFOData, error) {
+	err := v.loadCoefficients(bus)
+	if err != nil {
+		return nil, err
+	}
+	config1, err := bus.ReadRegU8(BMP388_FIFO_CONFIG_1)
+	if err != nil {
+		return nil, err
+	}
+	n, err := v.ReadFIFOLength(bus)
+	if err != nil {
+		return nil, err
+	}
+	if config1&0x04 != 0 {
+		// read 4 extra bytes to fetch sensortime frame,
+		// which is appended after the last stored frame
+		n += 4
+	}
+	data := &FIFOData{}
+	if n == 0 {
+		return data, nil
+	}
+	var ut int32
+	if config1&0x18 == 0x08 {
+		// FIFO store pressure only, so take temperature
+		// for compensation from data registers
+		buf, _, err := bus.ReadRegBytes(BMP388_TEMP_OUT_MSB_LSB_XLSB, 3)
+		if err != nil {
+			return nil, err
+		}
+		ut = getU24LE(buf)
+	}
+	buf, _, err := bus.ReadRegBytes(BMP388_FIFO_DATA, n)
+	if err != nil {
+		return nil, err
+	}
+	if missing := v.fifoMissingBytes(buf); missing > 0 {
+		// extra bytes read for sensortime frame cut new frame
+		// stored meanwhile, so read the rest of it
+		rest, _, err := bus.ReadRegBytes(BMP388_FIFO_DATA, missing)
+		if err != nil {
+			return nil, err
+		}
+		buf = append(buf, rest...)
+	}
+	err = v.parseFIFO(buf, ut, data)
+	if err != nil {
+		return nil, err
+	}
+	return data, nil
+}
+
+// fifoFrameSize return FIFO frame size in bytes by frame header,
+// or 0 if header is unknown.
+func (v *SensorBMP388) fifoFrameSize(header byte) int {
+	switch header {
+	case BMP388_FIFO_TEMP_PRESS_FRAME:
+		return 7
+	case BMP388_FIFO_TEMP_FRAME, BMP388_FIFO_PRESS_FRAME, BMP388_FIFO_TIME_FRAME:
+		return 4
+	case BMP388_FIFO_EMPTY_FRAME, BMP388_FIFO_CONFIG_CHANGE, BMP388_FIFO_CONFIG_ERROR_FRAME:
+		return 2
+	default:
+		return 0
+	}
+}
+
+// fifoMissingBytes return amount of bytes missing in the last frame
+// of buffer drained from FIFO, if burst read ended within frame.
+func (v *SensorBMP388) fifoMissingBytes(buf []byte) int {
+	for i := 0; i < len(buf); {
+		size := v.fifoFrameSize(buf[i])
+		if size == 0 || buf[i] == BMP388_FIFO_EMPTY_FRAME {
+			return 0
+		}
+		if i+size > len(buf) {
+			return i + size - len(buf)
+		}
+		i += size
+	}
+	return 0
+}
+
+// parseFIFO decode frames from buffer drained from FIFO. Pressure is compensated
+// with the last temperature found, or with ut, when no temperature frame found yet.
+func (v *SensorBMP388) parseFIFO(buf []byte, ut int32, data *FIFOData) error {
+	_, tLin := v.compensateTemperature(ut)
+	for i := 0; i < len(buf); {
+		header := buf[i]
+		size := v.fifoFrameSize(header)
+		if size == 0 {
+			return errors.New(fmt.Sprintf("unexpected FIFO frame header 0x%X at offset %d",
+				header, i))
+		}
+		if header == BMP388_FIFO_EMPTY_FRAME {
+			// no more frames
+			return nil
+		}
+		if i+size > len(buf) {
+			// bytes read are drained from FIFO already,
+			// so the rest of frame can't be decoded anymore
+			return errors.New(fmt.Sprintf("FIFO frame 0x%X truncated at offset %d", header, i))
+		}
+		frame := buf[i+1 : i+size]
+		switch header {
+		case BMP388_FIFO_TEMP_PRESS_FRAME, BMP388_FIFO_TEMP_FRAME, BMP388_FIFO_PRESS_FRAME:
+			var sample FIFOSample
+			if header&0x10 != 0 {
+				// temperature always go first
+				sample.HasTemperature = true
+				sample.Raw.Temperature = getU24LE(frame)
+				sample.TemperatureMult100C, tLin = v.compensateTemperature(sample.Raw.Temperature)
+				frame = frame[3:]
+			}
+			if header&0x04 != 0 {
+				sample.HasPressure = true
+				sample.Raw.Pressure = getU24LE(frame)
+				sample.PressureMult10Pa = v.compensatePressure(sample.Raw.Pressure, tLin)
+			}
+			data.Samples = append(data.Samples, sample)
+		case BMP388_FIFO_TIME_FRAME:
+			data.HasSensorTime = true
+			data.SensorTime = uint32(getU24LE(frame))
+		case BMP388_FIFO_CONFIG_CHANGE:
+			data.ConfigChanges++
+		case BMP388_FIFO_CONFIG_ERROR_FRAME:
+			data.ConfigErrors++
+		}
+		i += size
+	}
+	return nil
+}
@@ -0,0 +1,223 @@
+//--------------------------------------------------------------------------------------------------
+//
+// Copyright (c) 2018 Denis Dyakov
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy of this software and
+// associated documentation files (the "Software"), to deal in the Software without restriction,
+// including without limitation the rights to use, copy, modify, merge, publish, distribute, sublicense,
+// and/or sell copies of the Software, and to permit persons to whom the Software is furnished to do so,
+// subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all copies or substantial
+// portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR IMPLIED, INCLUDING
+// BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY, FITNESS FOR A PARTICULAR PURPOSE AND
+// NONINFRINGEMENT. IN NO EVENT SHALL THE AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM,
+// DAMAGES OR OTHER LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE SOFTWARE.
+//
+//--------------------------------------------------------------------------------------------------
+
+package bsbmp_test
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/d2r2/go-bsbmp"
+	"github.com/d2r2/go-bsbmp/sim"
+)
+
+// newFIFOSensor return emulated BMP388 in normal mode with FIFO configured.
+func newFIFOSensor(t *testing.T, bus bsbmp.Bus, settings bsbmp.FIFOSettings) *bsbmp.BMP {
+	t.Helper()
+	sensor, err := bsbmp.NewBMP(bsbmp.BMP388, bus)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = sensor.SetPowerMode(bsbmp.POWER_MODE_NORMAL, bsbmp.MeasureSettings{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = sensor.SetFIFO(settings)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return sensor
+}
+
+// checkSamples verify samples read from FIFO against environments sampled.
+func checkSamples(t *testing.T, name string, samples []bsbmp.FIFOSample,
+	envs []sim.Environment, temperature, pressure bool) {
+	t.Helper()
+	if len(samples) != len(envs) {
+		t.Fatalf("%s: %d samples read, want %d", name, len(samples), len(envs))
+	}
+	for i, s := range samples {
+		if s.HasTemperature != temperature || s.HasPressure != pressure {
+			t.Errorf("%s: sample %d content = %v, %v, want %v, %v", name, i,
+				s.HasTemperature, s.HasPressure, temperature, pressure)
+		}
+		if s.HasTemperature {
+			checkValue(t, fmt.Sprintf("%s sample %d temperature", name, i),
+				float64(s.TemperatureC()), envs[i].Temperature, temperatureTolerance)
+		}
+		if s.HasPressure {
+			checkValue(t, fmt.Sprintf("%s sample %d pressure", name, i),
+				float64(s.PressurePa()), envs[i].Pressure, pressureTolerance)
+		}
+	}
+}
+
+func TestBMP388FIFO(t *testing.T) {
+	dev := sim.NewBMP388()
+	sensor := newFIFOSensor(t, dev, bsbmp.FIFOSettings{Enabled: true,
+		Temperature: true, Pressure: true, SensorTime: true})
+	odr := dev.Register(bsbmp.BMP388_ODR_REG)
+	envs := []sim.Environment{
+		{Temperature: 21.5, Pressure: 100500},
+		{Temperature: 30.25, Pressure: 95000},
+		{Temperature: -5, Pressure: 87654},
+	}
+	for i, env := range envs {
+		if i == 2 {
+			// configuration change frame is stored
+			err := sensor.SetOutputDataRate(bsbmp.ODR_50_HZ)
+			if err != nil {
+				t.Fatal(err)
+			}
+		}
+		dev.SetEnvironment(env)
+		dev.Sample(1)
+	}
+	n, err := sensor.ReadFIFOLength()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n != 3*7+2 {
+		t.Errorf("ReadFIFOLength() = %d, want %d", n, 3*7+2)
+	}
+	data, err := sensor.ReadFIFO()
+	if err != nil {
+		t.Fatal(err)
+	}
+	checkSamples(t, "FIFO", data.Samples, envs, true, true)
+	if data.ConfigChanges != 1 || data.ConfigErrors != 0 {
+		t.Errorf("configuration changes = %d, errors = %d, want 1, 0",
+			data.ConfigChanges, data.ConfigErrors)
+	}
+	// sensortime tick 39.0625 usec, while sampling period is 5 ms * 2^odr
+	sensorTime := uint32(2*128<<odr + 128<<bsbmp.ODR_50_HZ)
+	if !data.HasSensorTime || data.SensorTime != sensorTime {
+		t.Errorf("sensortime = %v, %d, want true, %d", data.HasSensorTime, data.SensorTime, sensorTime)
+	}
+	data, err = sensor.ReadFIFO()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(data.Samples) != 0 || data.ConfigChanges != 0 {
+		t.Errorf("%d samples, %d configuration changes read from empty FIFO",
+			len(data.Samples), data.ConfigChanges)
+	}
+}
+
+// Pressure stored alone is compensated with temperature from data registers.
+func TestBMP388FIFOPressure(t *testing.T) {
+	dev := sim.NewBMP388()
+	sensor := newFIFOSensor(t, dev, bsbmp.FIFOSettings{Enabled: true, Pressure: true})
+	env := sim.Environment{Temperature: 35, Pressure: 98000}
+	dev.SetEnvironment(env)
+	dev.Sample(2)
+	data, err := sensor.ReadFIFO()
+	if err != nil {
+		t.Fatal(err)
+	}
+	checkSamples(t, "pressure only", data.Samples, []sim.Environment{env, env}, false, true)
+}
+
+func TestBMP388FIFOSubsampling(t *testing.T) {
+	dev := sim.NewBMP388()
+	sensor := newFIFOSensor(t, dev, bsbmp.FIFOSettings{Enabled: true,
+		Temperature: true, Subsampling: 2})
+	var envs []sim.Environment
+	for i := 0; i < 8; i++ {
+		env := sim.Environment{Temperature: float64(i), Pressure: 100000}
+		dev.SetEnvironment(env)
+		dev.Sample(1)
+		// each 4th sample is stored
+		if i%4 == 3 {
+			envs = append(envs, env)
+		}
+	}
+	data, err := sensor.ReadFIFO()
+	if err != nil {
+		t.Fatal(err)
+	}
+	checkSamples(t, "subsampling", data.Samples, envs, true, false)
+}
+
+// FIFO keep 73 frames with both values. Once full, either the oldest
+// frames are overwritten, or new frames are dropped (stop-on-full).
+func TestBMP388FIFOFull(t *testing.T) {
+	for _, stopOnFull := range []bool{false, true} {
+		dev := sim.NewBMP388()
+		sensor := newFIFOSensor(t, dev, bsbmp.FIFOSettings{Enabled: true,
+			Temperature: true, Pressure: true, StopOnFull: stopOnFull})
+		var envs []sim.Environment
+		for i := 0; i < 100; i++ {
+			env := sim.Environment{Temperature: float64(i) / 2, Pressure: 100000}
+			dev.SetEnvironment(env)
+			dev.Sample(1)
+			envs = append(envs, env)
+		}
+		if stopOnFull {
+			envs = envs[:73]
+		} else {
+			envs = envs[len(envs)-73:]
+		}
+		data, err := sensor.ReadFIFO()
+		if err != nil {
+			t.Fatal(err)
+		}
+		checkSamples(t, fmt.Sprintf("stopOnFull=%v", stopOnFull), data.Samples, envs, true, true)
+	}
+}
+
+// sampleBus store new frame to FIFO right after FIFO length is read.
+type sampleBus struct {
+	*sim.Device
+}
+
+func (v *sampleBus) ReadRegBytes(reg byte, n int) ([]byte, int, error) {
+	buf, n, err := v.Device.ReadRegBytes(reg, n)
+	if reg == bsbmp.BMP388_FIFO_LENGTH_0 {
+		v.Device.Sample(1)
+	}
+	return buf, n, err
+}
+
+// Extra bytes read to fetch sensortime frame might cut frame
+// stored after FIFO length is read, which should not be lost.
+func TestBMP388FIFOSensorTimeRace(t *testing.T) {
+	dev := sim.NewBMP388()
+	env := sim.Environment{Temperature: 25, Pressure: 100000}
+	dev.SetEnvironment(env)
+	sensor := newFIFOSensor(t, &sampleBus{Device: dev}, bsbmp.FIFOSettings{Enabled: true,
+		Temperature: true, Pressure: true, SensorTime: true})
+	dev.Sample(2)
+	data, err := sensor.ReadFIFO()
+	if err != nil {
+		t.Fatal(err)
+	}
+	checkSamples(t, "first read", data.Samples, []sim.Environment{env, env, env}, true, true)
+	if data.HasSensorTime {
+		t.Error("sensortime frame found in place of data frame")
+	}
+	data, err = sensor.ReadFIFO()
+	if err != nil {
+		t.Fatal(err)
+	}
+	// one more frame is stored meanwhile
+	checkSamples(t, "second read", data.Samples, []sim.Environment{env}, true, true)
+}
@@ -0,0 +1,113 @@
+//--------------------------------------------------------------------------------------------------
+//
+// Copyright (c) 2018 Denis Dyakov
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy of this software and
+// associated documentation files (the "Software"), to deal in the Software without restriction,
+// including without limitation the rights to use, copy, modify, merge, publish, distribute, sublicense,
+// and/or sell copies of the Software, and to permit persons to whom the Software is furnished to do so,
+// subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all copies or substantial
+// portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR IMPLIED, INCLUDING
+// BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY, FITNESS FOR A PARTICULAR PURPOSE AND
+// NONINFRINGEMENT. IN NO EVENT SHALL THE AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM,
+// DAMAGES OR OTHER LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE SOFTWARE.
+//
+//--------------------------------------------------------------------------------------------------
+
+package bsbmp
+
+import (
+	"errors"
+	"fmt"
+)
+
+// InterruptSettings describe INT pin configuration
+// and events signaled via interrupt line.
+type InterruptSettings struct {
+	// OpenDrain configure INT pin as open-drain, otherwise push-pull.
+	OpenDrain bool
+	// ActiveHigh define INT pin active level, otherwise active low.
+	ActiveHigh bool
+	// Latch keep interrupt signaled until status is read,
+	// otherwise INT pin is pulsed.
+	Latch bool
+	// DataReady signal, when new sample is ready in data registers.
+	DataReady bool
+	// FIFOWatermark signal, when FIFO fill level reach Watermark.
+	FIFOWatermark bool
+	// FIFOFull signal, when FIFO is full.
+	FIFOFull bool
+	// PressureOutOfRange signal, when pressure leaves window
+	// set by SetPressureOutOfRange (BMP581 only).
+	PressureOutOfRange bool
+	// Watermark is a FIFO fill level in bytes (1..511),
+	// used only when FIFOWatermark enabled.
+	Watermark int
+}
+
+// InterruptStatus keep events signaled by sensor.
+type InterruptStatus struct {
+	DataReady          bool
+	FIFOWatermark      bool
+	FIFOFull           bool
+	PressureOutOfRange bool
+}
+
+// SetInterrupt write INT pin configuration, interrupt sources
+// and FIFO watermark level.
+func (v *SensorBMP388) SetInterrupt(bus Bus, settings InterruptSettings) error {
+	if settings.PressureOutOfRange {
+		return &NotSupportedError{Sensor: v.Variant(), Feature: "pressure out-of-range interrupt"}
+	}
+	var ctrl byte
+	if settings.OpenDrain {
+		ctrl |= 0x01
+	}
+	if settings.ActiveHigh {
+		ctrl |= 0x02
+	}
+	if settings.Latch {
+		ctrl |= 0x04
+	}
+	if settings.FIFOWatermark {
+		if settings.Watermark < 1 || settings.Watermark >= BMP388_FIFO_SIZE {
+			return errors.New(fmt.Sprintf("FIFO watermark %d is out of range [1..%d]",
+				settings.Watermark, BMP388_FIFO_SIZE-1))
+		}
+		err := bus.WriteRegU8(BMP388_FIFO_WTM_0, byte(settings.Watermark))
+		if err != nil {
+			return err
+		}
+		err = bus.WriteRegU8(BMP388_FIFO_WTM_1, byte(settings.Watermark>>8))
+		if err != nil {
+			return err
+		}
+		ctrl |= 0x08
+	}
+	if settings.FIFOFull {
+		ctrl |= 0x10
+	}
+	if settings.DataReady {
+		ctrl |= 0x40
+	}
+	return bus.WriteRegU8(BMP388_INT_CTRL, ctrl)
+}
+
+// ReadInterruptStatus read and clear interrupt status.
+func (v *SensorBMP388) ReadInterruptStatus(bus Bus) (*InterruptStatus, error) {
+	b, err := bus.ReadRegU8(BMP388_INT_STATUS)
+	if err != nil {
+		return nil, err
+	}
+	status := &InterruptStatus{
+		FIFOWatermark: b&0x01 != 0,
+		FIFOFull:      b&0x02 != 0,
+		DataReady:     b&0x08 != 0,
+	}
+	return status, nil
+}
@@ -0,0 +1,408 @@
+//--------------------------------------------------------------------------------------------------
+//
+// Copyright (c) 2018 Denis Dyakov
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy of this software and
+// associated documentation files (the "Software"), to deal in the Software without restriction,
+// including without limitation the rights to use, copy, modify, merge, publish, distribute, sublicense,
+// and/or sell copies of the Software, and to permit persons to whom the Software is furnished to do so,
+// subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all copies or substantial
+// portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR IMPLIED, INCLUDING
+// BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY, FITNESS FOR A PARTICULAR PURPOSE AND
+// NONINFRINGEMENT. IN NO EVENT SHALL THE AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM,
+// DAMAGES OR OTHER LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE SOFTWARE.
+//
+//--------------------------------------------------------------------------------------------------
+
+package bsbmp
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"time"
+)
+
+// BMP581 and BMP585 sensors memory map
+const (
+	// BMP581 general registers
+	BMP581_CHIP_ID_REG     = 0x01
+	BMP581_REV_ID_REG      = 0x02
+	BMP581_CHIP_STATUS_REG = 0x11
+	BMP581_STATUS_REG      = 0x28 // NVM ready and error flags
+	BMP581_CMD_REG         = 0x7E
+	BMP581_CMD_SOFT_RESET  = 0xB6
+	// Interrupt registers
+	BMP581_INT_CONFIG_REG = 0x14 // INT pin configuration
+	BMP581_INT_SOURCE_REG = 0x15 // interrupt sources
+	BMP581_INT_STATUS_REG = 0x27 // interrupt status, cleared on read
+	// FIFO registers
+	BMP581_FIFO_CONFIG_REG = 0x16 // FIFO threshold in frames, stop on full
+	BMP581_FIFO_COUNT_REG  = 0x17 // amount of frames stored
+	BMP581_FIFO_SEL_REG    = 0x18 // frames content, decimation
+	BMP581_FIFO_DATA_REG   = 0x29 // FIFO read port
+	// BMP581 3-byte compensated temperature and pressure, xlsb first
+	BMP581_TEMP_DATA_XLSB  = 0x1D
+	BMP581_PRESS_DATA_XLSB = 0x20
+	// Measurement configuration
+	BMP581_DSP_CONFIG_REG = 0x30 // IIR filter data selection
+	BMP581_DSP_IIR_REG    = 0x31 // IIR filter coefficients
+	BMP581_OSR_CONFIG_REG = 0x36 // oversampling, pressure enable
+	BMP581_ODR_CONFIG_REG = 0x37 // power mode, output data rate
+	BMP581_OSR_EFF_REG    = 0x38 // effective oversampling, ODR validity
+	// Pressure out-of-range detection
+	BMP581_OOR_THR_P_LSB_REG = 0x32
+	BMP581_OOR_THR_P_MSB_REG = 0x33
+	BMP581_OOR_RANGE_REG     = 0x34
+	BMP581_OOR_CONFIG_REG    = 0x35
+
+	BMP581_PWR_MODE_STANDBY    = 0
+	BMP581_PWR_MODE_NORMAL     = 1
+	BMP581_PWR_MODE_FORCED     = 2
+	BMP581_PWR_MODE_CONTINUOUS = 3
+
+	// Deep standby disable bit of ODR_CONFIG
+	BMP581_DEEP_DISABLE = 0x80
+	// Pressure measurement enable bit of OSR_CONFIG
+	BMP581_PRESS_EN = 0x40
+)
+
+// bmp581ODRFrequencies keep output data rates (Hz), which
+// BMP581 encode in bits 6:2 of ODR_CONFIG register.
+var bmp581ODRFrequencies = [...]float64{
+	240, 218.5, 199.1, 179.2, 160, 149.3, 140, 129.8,
+	120, 110.1, 100.2, 89.6, 80, 70, 60, 50,
+	45, 40, 35, 30, 25, 20, 15, 10,
+	5, 4, 3, 2, 1, 0.5, 0.25, 0.125,
+}
+
+// SensorBMP581 specific type. Drives BMP585 as well,
+// which is BMP581 in robust gel-filled package.
+type SensorBMP581 struct {
+	// BMP581 or BMP585
+	variant SensorType
+	// Operating mode, forced by default
+	mode PowerMode
+}
+
+// Static cast to verify at compile time
+// that type implement interface.
+var _ SensorInterface = &SensorBMP581{}
+var _ IIRFilterInterface = &SensorBMP581{}
+var _ PowerModeInterface = &SensorBMP581{}
+var _ ODRInterface = &SensorBMP581{}
+
+// Variant return sensor model driven: BMP581 or BMP585.
+func (v *SensorBMP581) Variant() SensorType {
+	if v.variant == BMP585 {
+		return BMP585
+	}
+	return BMP581
+}
+
+// ReadSensorID reads sensor signature. It may be used for validation,
+// that proper code settings used for sensor data decoding.
+func (v *SensorBMP581) ReadSensorID(bus Bus) (uint8, error) {
+	id, err := bus.ReadRegU8(BMP581_CHIP_ID_REG)
+	if err != nil {
+		return 0, err
+	}
+	return id, nil
+}
+
+// ReadCoefficients does nothing but NVM status check: BMP581 compensates
+// values on-chip, so no coefficients required on host side.
+func (v *SensorBMP581) ReadCoefficients(bus Bus) error {
+	status, err := bus.ReadRegU8(BMP581_STATUS_REG)
+	if err != nil {
+		return err
+	}
+	lg.Debugf("NVM status=0x%0X", status)
+	// nvm_rdy must be set, nvm_err and nvm_cmd_err cleared
+	if status&0x0E != 0x02 {
+		return errors.New(fmt.Sprintf("%v NVM is not ready or corrupted, status: 0x%X",
+			v.Variant(), status))
+	}
+	return nil
+}
+
+// IsValidCoefficients always succeed, since BMP581 doesn't need coefficients.
+func (v *SensorBMP581) IsValidCoefficients() error {
+	return nil
+}
+
+// RecognizeSignature returns description of signature if it valid,
+// otherwise - error.
+func (v *SensorBMP581) RecognizeSignature(signature uint8) (string, error) {
+	variant := v.Variant()
+	switch {
+	case signature == 0x50 && variant == BMP581:
+		return "BMP581", nil
+	case signature == 0x51 && variant == BMP585:
+		return "BMP585", nil
+	default:
+		return "", &SignatureError{Sensor: variant, Signature: signature}
+	}
+}
+
+// IsBusy reads register 0x37 (ODR_CONFIG) to understand whether forced
+// conversion is still in progress: sensor returns to standby mode once done.
+func (v *SensorBMP581) IsBusy(bus Bus) (busy bool, err error) {
+	b, err := bus.ReadRegU8(BMP581_ODR_CONFIG_REG)
+	if err != nil {
+		return false, err
+	}
+	lg.Debugf("Busy flag=0x%0X", b)
+	return b&0x03 == BMP581_PWR_MODE_FORCED, nil
+}
+
+func (v *SensorBMP581) getOversamplingRation(accuracy AccuracyMode) byte {
+	var b byte
+	switch accuracy {
+	case ACCURACY_ULTRA_LOW:
+		b = 0
+	case ACCURACY_LOW:
+		b = 1
+	case ACCURACY_STANDARD:
+		b = 2
+	case ACCURACY_HIGH:
+		b = 3
+	case ACCURACY_ULTRA_HIGH:
+		b = 4
+	case ACCURACY_HIGHEST:
+		b = 5
+	default:
+		// assign accuracy to lowest resolution by default
+		b = 0
+	}
+	return b
+}
+
+// standby switch sensor to standby mode, required to change most
+// of configuration registers. Returns previous ODR_CONFIG value.
+func (v *SensorBMP581) standby(bus Bus) (byte, error) {
+	odr, err := bus.ReadRegU8(BMP581_ODR_CONFIG_REG)
+	if err != nil {
+		return 0, err
+	}
+	if odr&0x03 != BMP581_PWR_MODE_STANDBY {
+		err = bus.WriteRegU8(BMP581_ODR_CONFIG_REG,
+			odr&^0x03|BMP581_DEEP_DISABLE|BMP581_PWR_MODE_STANDBY)
+		if err != nil {
+			return 0, err
+		}
+		// mode change takes effect in a while
+		err = sleep(bus, 2500*time.Microsecond)
+		if err != nil {
+			return 0, err
+		}
+	}
+	return odr, nil
+}
+
+// restore write back ODR_CONFIG value obtained from standby call.
+func (v *SensorBMP581) restore(bus Bus, odr byte) error {
+	if odr&0x03 == BMP581_PWR_MODE_STANDBY {
+		return nil
+	}
+	return bus.WriteRegU8(BMP581_ODR_CONFIG_REG, odr)
+}
+
+// SetIIRFilter write IIR filter coefficient for both temperature and pressure,
+// and select filtered data to appear in data registers.
+func (v *SensorBMP581) SetIIRFilter(bus Bus, filter IIRFilter) error {
+	if filter < IIR_FILTER_OFF || filter > IIR_FILTER_128 {
+		return &NotSupportedError{Sensor: v.Variant(), Feature: fmt.Sprintf("IIR filter %v", filter)}
+	}
+	odr, err := v.standby(bus)
+	if err != nil {
+		return err
+	}
+	var dsp byte
+	if filter != IIR_FILTER_OFF {
+		// shdw_sel_iir_t, shdw_sel_iir_p, iir_flush_forced_en
+		dsp = 0x2C
+	}
+	err = updateRegU8(bus, BMP581_DSP_CONFIG_REG, 0x2C, dsp)
+	if err != nil {
+		return err
+	}
+	err = bus.WriteRegU8(BMP581_DSP_IIR_REG, byte(filter)<<3|byte(filter))
+	if err != nil {
+		return err
+	}
+	return v.restore(bus, odr)
+}
+
+// triggerMeasurement start forced conversion and wait for completion.
+// In normal mode does nothing, since data registers keep latest sample.
+func (v *SensorBMP581) triggerMeasurement(bus Bus, osrt, osrp byte) error {
+	if v.mode == POWER_MODE_NORMAL {
+		return nil
+	}
+	err := bus.WriteRegU8(BMP581_OSR_CONFIG_REG, BMP581_PRESS_EN|(osrp<<3)|osrt)
+	if err != nil {
+		return err
+	}
+	err = clearInterrupt(v, bus)
+	if err != nil {
+		return err
+	}
+	err = updateRegU8(bus, BMP581_ODR_CONFIG_REG, 0x83,
+		BMP581_DEEP_DISABLE|BMP581_PWR_MODE_FORCED)
+	if err != nil {
+		return err
+	}
+	err = waitForCompletion(v, bus)
+	return err
+}
+
+// SetPowerMode switch sensor to power mode specified. Oversampling from settings
+// is written to sensor for normal mode. If oversampling doesn't fit output data rate,
+// sensor reduce oversampling itself.
+func (v *SensorBMP581) SetPowerMode(bus Bus, mode PowerMode, settings MeasureSettings) error {
+	var power byte
+	switch mode {
+	case POWER_MODE_SLEEP, POWER_MODE_FORCED:
+		// forced conversion is triggered on each read,
+		// sensor stays in standby mode meanwhile
+		power = BMP581_PWR_MODE_STANDBY
+	case POWER_MODE_NORMAL:
+		power = BMP581_PWR_MODE_NORMAL
+	default:
+		return &NotSupportedError{Sensor: v.Variant(), Feature: fmt.Sprintf("power mode %v", mode)}
+	}
+	odr, err := v.standby(bus)
+	if err != nil {
+		return err
+	}
+	osrt := v.getOversamplingRation(settings.Temperature)
+	osrp := v.getOversamplingRation(settings.Pressure)
+	err = bus.WriteRegU8(BMP581_OSR_CONFIG_REG, BMP581_PRESS_EN|(osrp<<3)|osrt)
+	if err != nil {
+		return err
+	}
+	if power != BMP581_PWR_MODE_STANDBY {
+		err = bus.WriteRegU8(BMP581_ODR_CONFIG_REG, odr&^0x03|BMP581_DEEP_DISABLE|power)
+		if err != nil {
+			return err
+		}
+		eff, err := bus.ReadRegU8(BMP581_OSR_EFF_REG)
+		if err != nil {
+			return err
+		}
+		if eff&0x80 == 0 {
+			lg.Debugf("oversampling doesn't fit output data rate, effective: 0x%0X", eff)
+		}
+	}
+	v.mode = mode
+	return nil
+}
+
+// SetOutputDataRate write output data rate closest to odr, since BMP581
+// supports own set of rates in range 240..0.125 Hz.
+func (v *SensorBMP581) SetOutputDataRate(bus Bus, odr OutputDataRate) error {
+	if odr < ODR_200_HZ || odr > ODR_0_0015_HZ {
+		return &NotSupportedError{Sensor: v.Variant(), Feature: fmt.Sprintf("output data rate %d", odr)}
+	}
+	target := 200 / float64(uint(1)<<uint(odr))
+	var code int
+	for i, f := range bmp581ODRFrequencies {
+		if math.Abs(math.Log(f/target)) <
+			math.Abs(math.Log(bmp581ODRFrequencies[code]/target)) {
+			code = i
+		}
+	}
+	lg.Debugf("ODR %v Hz selected for %v Hz requested", bmp581ODRFrequencies[code], target)
+	odr2, err := v.standby(bus)
+	if err != nil {
+		return err
+	}
+	odr2 = odr2&^0x7C | byte(code)<<2
+	if odr2&0x03 == BMP581_PWR_MODE_STANDBY {
+		odr2 |= BMP581_DEEP_DISABLE
+	}
+	return bus.WriteRegU8(BMP581_ODR_CONFIG_REG, odr2)
+}
+
+// compensateTemperature convert on-chip compensated value
+// to temperature in C (celsius) multiplied by 100.
+func (v *SensorBMP581) compensateTemperature(ut int32) int32 {
+	// value is in 1/65536 C, round to nearest
+	return int32((int64(ut)*100 + 32768) >> 16)
+}
+
+// compensatePressure convert on-chip compensated value
+// to pressure in Pa (Pascal) multiplied by 10.
+func (v *SensorBMP581) compensatePressure(up int32) uint32 {
+	// value is in 1/64 Pa, round to nearest
+	return uint32((int64(up)*10 + 32) >> 6)
+}
+
+// ReadTemperatureMult100C reads and calculates temrature in C (celsius) multiplied by 100.
+// Multiplication approach allow to keep result as integer number.
+func (v *SensorBMP581) ReadTemperatureMult100C(bus Bus, accuracy AccuracyMode) (int32, error) {
+	osrt := v.getOversamplingRation(accuracy)
+	err := v.triggerMeasurement(bus, osrt, 0)
+	if err != nil {
+		return 0, err
+	}
+	buf, _, err := bus.ReadRegBytes(BMP581_TEMP_DATA_XLSB, 3)
+	if err != nil {
+		return 0, err
+	}
+	ut := getS24LE(buf)
+	lg.Debugf("ut=%v", ut)
+	return v.compensateTemperature(ut), nil
+}
+
+// ReadPressureMult10Pa reads and calculates atmospheric pressure in Pa (Pascal) multiplied by 10.
+// Multiplication approach allow to keep result as integer number.
+func (v *SensorBMP581) ReadPressureMult10Pa(bus Bus, accuracy AccuracyMode) (uint32, error) {
+	osrp := v.getOversamplingRation(accuracy)
+	err := v.triggerMeasurement(bus, 0, osrp)
+	if err != nil {
+		return 0, err
+	}
+	buf, _, err := bus.ReadRegBytes(BMP581_PRESS_DATA_XLSB, 3)
+	if err != nil {
+		return 0, err
+	}
+	up := getU24LE(buf)
+	lg.Debugf("up=%v", up)
+	return v.compensatePressure(up), nil
+}
+
+// ReadHumidityMultQ2210 does nothing. Humidity function is not applicable for BMP581.
+func (v *SensorBMP581) ReadHumidityMultQ2210(bus Bus, accuracy AccuracyMode) (bool, uint32, error) {
+	// Not supported
+	return false, 0, nil
+}
+
+// Measure runs single forced conversion of temperature and pressure,
+// and reads out both values in one burst read. In normal mode latest sample is read.
+func (v *SensorBMP581) Measure(bus Bus, settings MeasureSettings) (*Measurement, error) {
+	osrt := v.getOversamplingRation(settings.Temperature)
+	osrp := v.getOversamplingRation(settings.Pressure)
+	err := v.triggerMeasurement(bus, osrt, osrp)
+	if err != nil {
+		return nil, err
+	}
+	// temperature and pressure registers go one by one
+	buf, _, err := bus.ReadRegBytes(BMP581_TEMP_DATA_XLSB, 6)
+	if err != nil {
+		return nil, err
+	}
+	m := &Measurement{Time: time.Now()}
+	m.Raw.Temperature = getS24LE(buf[0:3])
+	m.Raw.Pressure = getU24LE(buf[3:6])
+	lg.Debugf("ut=%v, up=%v", m.Raw.Temperature, m.Raw.Pressure)
+	m.TemperatureMult100C = v.compensateTemperature(m.Raw.Temperature)
+	m.PressureMult10Pa = v.compensatePressure(m.Raw.Pressure)
+	return m, nil
+}
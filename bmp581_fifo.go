@@ -0,0 +1,179 @@
+//--------------------------------------------------------------------------------------------------
+//
+// Copyright (c) 2018 Denis Dyakov
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy of this software and
+// associated documentation files (the "Software"), to deal in the Software without restriction,
+// including without limitation the rights to use, copy, modify, merge, publish, distribute, sublicense,
+// and/or sell copies of the Software, and to permit persons to whom the Software is furnished to do so,
+// subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all copies or substantial
+// portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR IMPLIED, INCLUDING
+// BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY, FITNESS FOR A PARTICULAR PURPOSE AND
+// NONINFRINGEMENT. IN NO EVENT SHALL THE AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM,
+// DAMAGES OR OTHER LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE SOFTWARE.
+//
+//--------------------------------------------------------------------------------------------------
+
+package bsbmp
+
+import (
+	"fmt"
+)
+
+// BMP581 FIFO depth: 32 frames with single value,
+// or 16 frames with both temperature and pressure.
+const BMP581_FIFO_SIZE = 96
+
+// BMP581 returns this value on FIFO read, once FIFO is empty.
+const BMP581_FIFO_EMPTY_FRAME = 0x7F7F7F
+
+// Static cast to verify at compile time
+// that type implement interface.
+var _ FIFOInterface = &SensorBMP581{}
+
+// fifoFrameSize return frame size in bytes by FIFO_SEL register value.
+func (v *SensorBMP581) fifoFrameSize(sel byte) int {
+	switch sel & 0x03 {
+	case 1, 2:
+		// temperature or pressure only
+		return 3
+	case 3:
+		return 6
+	default:
+		// FIFO disabled
+		return 0
+	}
+}
+
+// SetFIFO write FIFO configuration. FIFO is filled only in normal mode.
+// Sensortime frames are not supported by BMP581.
+func (v *SensorBMP581) SetFIFO(bus Bus, settings FIFOSettings) error {
+	if settings.Subsampling > 7 {
+		return &NotSupportedError{Sensor: v.Variant(), Feature: fmt.Sprintf("FIFO subsampling %d", settings.Subsampling)}
+	}
+	if settings.SensorTime {
+		return &NotSupportedError{Sensor: v.Variant(), Feature: "sensortime"}
+	}
+	var sel byte
+	if settings.Enabled {
+		if settings.Temperature {
+			sel |= 0x01
+		}
+		if settings.Pressure {
+			sel |= 0x02
+		}
+	}
+	sel |= settings.Subsampling << 2
+	var config, dsp byte
+	if settings.StopOnFull {
+		config = 0x20
+	}
+	if settings.Filtered {
+		// fifo_sel_iir_t, fifo_sel_iir_p
+		dsp = 0x50
+	}
+	odr, err := v.standby(bus)
+	if err != nil {
+		return err
+	}
+	err = updateRegU8(bus, BMP581_DSP_CONFIG_REG, 0x50, dsp)
+	if err != nil {
+		return err
+	}
+	err = updateRegU8(bus, BMP581_FIFO_CONFIG_REG, 0x20, config)
+	if err != nil {
+		return err
+	}
+	err = bus.WriteRegU8(BMP581_FIFO_SEL_REG, sel)
+	if err != nil {
+		return err
+	}
+	return v.restore(bus, odr)
+}
+
+// ReadFIFOLength return amount of bytes stored in FIFO.
+func (v *SensorBMP581) ReadFIFOLength(bus Bus) (int, error) {
+	sel, err := bus.ReadRegU8(BMP581_FIFO_SEL_REG)
+	if err != nil {
+		return 0, err
+	}
+	count, err := bus.ReadRegU8(BMP581_FIFO_COUNT_REG)
+	if err != nil {
+		return 0, err
+	}
+	return int(count&0x3F) * v.fifoFrameSize(sel), nil
+}
+
+// FlushFIFO remove all data from FIFO, disabling FIFO for a while,
+// since BMP581 doesn't have flush command.
+func (v *SensorBMP581) FlushFIFO(bus Bus) error {
+	odr, err := v.standby(bus)
+	if err != nil {
+		return err
+	}
+	sel, err := bus.ReadRegU8(BMP581_FIFO_SEL_REG)
+	if err != nil {
+		return err
+	}
+	err = bus.WriteRegU8(BMP581_FIFO_SEL_REG, sel&^0x03)
+	if err != nil {
+		return err
+	}
+	err = bus.WriteRegU8(BMP581_FIFO_SEL_REG, sel)
+	if err != nil {
+		return err
+	}
+	return v.restore(bus, odr)
+}
+
+// ReadFIFO drain FIFO with single burst read and decode frames
+// to samples. Values are compensated on-chip already.
+func (v *SensorBMP581) ReadFIFO(bus Bus) (*FIFOData, error) {
+	sel, err := bus.ReadRegU8(BMP581_FIFO_SEL_REG)
+	if err != nil {
+		return nil, err
+	}
+	size := v.fifoFrameSize(sel)
+	data := &FIFOData{}
+	if size == 0 {
+		return data, nil
+	}
+	count, err := bus.ReadRegU8(BMP581_FIFO_COUNT_REG)
+	if err != nil {
+		return nil, err
+	}
+	n := int(count&0x3F) * size
+	if n == 0 {
+		return data, nil
+	}
+	buf, _, err := bus.ReadRegBytes(BMP581_FIFO_DATA_REG, n)
+	if err != nil {
+		return nil, err
+	}
+	for i := 0; i+size <= len(buf); i += size {
+		frame := buf[i : i+size]
+		if getU24LE(frame) == BMP581_FIFO_EMPTY_FRAME {
+			break
+		}
+		var sample FIFOSample
+		if sel&0x01 != 0 {
+			// temperature always go first
+			sample.HasTemperature = true
+			sample.Raw.Temperature = getS24LE(frame)
+			sample.TemperatureMult100C = v.compensateTemperature(sample.Raw.Temperature)
+			frame = frame[3:]
+		}
+		if sel&0x02 != 0 {
+			sample.HasPressure = true
+			sample.Raw.Pressure = getU24LE(frame)
+			sample.PressureMult10Pa = v.compensatePressure(sample.Raw.Pressure)
+		}
+		data.Samples = append(data.Samples, sample)
+	}
+	return data, nil
+}
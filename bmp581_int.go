@@ -0,0 +1,146 @@
+//--------------------------------------------------------------------------------------------------
+//
+// Copyright (c) 2018 Denis Dyakov
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy of this software and
+// associated documentation files (the "Software"), to deal in the Software without restriction,
+// including without limitation the rights to use, copy, modify, merge, publish, distribute, sublicense,
+// and/or sell copies of the Software, and to permit persons to whom the Software is furnished to do so,
+// subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all copies or substantial
+// portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR IMPLIED, INCLUDING
+// BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY, FITNESS FOR A PARTICULAR PURPOSE AND
+// NONINFRINGEMENT. IN NO EVENT SHALL THE AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM,
+// DAMAGES OR OTHER LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE SOFTWARE.
+//
+//--------------------------------------------------------------------------------------------------
+
+package bsbmp
+
+import (
+	"errors"
+	"fmt"
+)
+
+// Static cast to verify at compile time
+// that type implement interface.
+var _ InterruptInterface = &SensorBMP581{}
+var _ OORInterface = &SensorBMP581{}
+
+// SetInterrupt write INT pin configuration, interrupt sources and
+// FIFO threshold. BMP581 keep FIFO threshold in frames, so watermark
+// is converted to frames of size configured by SetFIFO.
+func (v *SensorBMP581) SetInterrupt(bus Bus, settings InterruptSettings) error {
+	var source byte
+	if settings.DataReady {
+		source |= 0x01
+	}
+	if settings.FIFOFull {
+		source |= 0x02
+	}
+	if settings.FIFOWatermark {
+		sel, err := bus.ReadRegU8(BMP581_FIFO_SEL_REG)
+		if err != nil {
+			return err
+		}
+		size := v.fifoFrameSize(sel)
+		if size == 0 {
+			return errors.New("FIFO should be configured before watermark interrupt")
+		}
+		frames := settings.Watermark / size
+		if frames < 1 || frames > BMP581_FIFO_SIZE/size-1 {
+			return errors.New(fmt.Sprintf("FIFO watermark %d is out of range [%d..%d]",
+				settings.Watermark, size, BMP581_FIFO_SIZE-size))
+		}
+		odr, err := v.standby(bus)
+		if err != nil {
+			return err
+		}
+		err = updateRegU8(bus, BMP581_FIFO_CONFIG_REG, 0x1F, byte(frames))
+		if err != nil {
+			return err
+		}
+		err = v.restore(bus, odr)
+		if err != nil {
+			return err
+		}
+		source |= 0x04
+	}
+	if settings.PressureOutOfRange {
+		source |= 0x08
+	}
+	var config byte
+	if settings.Latch {
+		config |= 0x01
+	}
+	if settings.ActiveHigh {
+		config |= 0x02
+	}
+	if settings.OpenDrain {
+		config |= 0x04
+	}
+	if source != 0 {
+		config |= 0x08
+	}
+	err := bus.WriteRegU8(BMP581_INT_SOURCE_REG, source)
+	if err != nil {
+		return err
+	}
+	return updateRegU8(bus, BMP581_INT_CONFIG_REG, 0x0F, config)
+}
+
+// ReadInterruptStatus read and clear interrupt status.
+func (v *SensorBMP581) ReadInterruptStatus(bus Bus) (*InterruptStatus, error) {
+	b, err := bus.ReadRegU8(BMP581_INT_STATUS_REG)
+	if err != nil {
+		return nil, err
+	}
+	status := &InterruptStatus{
+		DataReady:          b&0x01 != 0,
+		FIFOFull:           b&0x02 != 0,
+		FIFOWatermark:      b&0x04 != 0,
+		PressureOutOfRange: b&0x08 != 0,
+	}
+	return status, nil
+}
+
+// SetPressureOutOfRange write pressure window, outside of which
+// out-of-range interrupt is signaled.
+func (v *SensorBMP581) SetPressureOutOfRange(bus Bus, settings PressureOutOfRange) error {
+	if settings.ThresholdPa > 0x1FFFF {
+		return errors.New(fmt.Sprintf("pressure threshold %d Pa is out of range [0..%d]",
+			settings.ThresholdPa, 0x1FFFF))
+	}
+	var limit byte
+	switch settings.CountLimit {
+	case 0, 1:
+		limit = 0
+	case 3:
+		limit = 1
+	case 7:
+		limit = 2
+	case 15:
+		limit = 3
+	default:
+		return errors.New(fmt.Sprintf("count limit %d is not supported by %v, use 1, 3, 7 or 15",
+			settings.CountLimit, v.Variant()))
+	}
+	err := bus.WriteRegU8(BMP581_OOR_THR_P_LSB_REG, byte(settings.ThresholdPa))
+	if err != nil {
+		return err
+	}
+	err = bus.WriteRegU8(BMP581_OOR_THR_P_MSB_REG, byte(settings.ThresholdPa>>8))
+	if err != nil {
+		return err
+	}
+	err = bus.WriteRegU8(BMP581_OOR_RANGE_REG, settings.RangePa)
+	if err != nil {
+		return err
+	}
+	return updateRegU8(bus, BMP581_OOR_CONFIG_REG, 0xC1,
+		limit<<6|byte(settings.ThresholdPa>>16))
+}
@@ -0,0 +1,187 @@
+//--------------------------------------------------------------------------------------------------
+//
+// Copyright (c) 2018 Denis Dyakov
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy of this software and
+// associated documentation files (the "Software"), to deal in the Software without restriction,
+// including without limitation the rights to use, copy, modify, merge, publish, distribute, sublicense,
+// and/or sell copies of the Software, and to permit persons to whom the Software is furnished to do so,
+// subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all copies or substantial
+// portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR IMPLIED, INCLUDING
+// BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY, FITNESS FOR A PARTICULAR PURPOSE AND
+// NONINFRINGEMENT. IN NO EVENT SHALL THE AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM,
+// DAMAGES OR OTHER LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE SOFTWARE.
+//
+//--------------------------------------------------------------------------------------------------
+
+package bsbmp_test
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/d2r2/go-bsbmp"
+	"github.com/d2r2/go-bsbmp/sim"
+)
+
+func TestBMP581PressureOutOfRange(t *testing.T) {
+	dev := sim.NewBMP581()
+	sensor, err := bsbmp.NewBMP(bsbmp.BMP581, dev)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// threshold above 65535 Pa take bit 16 from OOR_CONFIG register
+	err = sensor.SetPressureOutOfRange(bsbmp.PressureOutOfRange{
+		ThresholdPa: 101325, RangePa: 100, CountLimit: 3})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for reg, want := range map[byte]byte{0x32: 0xCD, 0x33: 0x8B, 0x34: 100, 0x35: 0x41} {
+		if b := dev.Register(reg); b != want {
+			t.Errorf("register 0x%X = 0x%X, want 0x%X", reg, b, want)
+		}
+	}
+	for _, c := range []struct {
+		pressure float64
+		oor      bool
+	}{
+		{101325, false},
+		{101400, false},
+		{101500, true},
+		{99000, true},
+	} {
+		dev.SetEnvironment(sim.Environment{Temperature: 20, Pressure: c.pressure})
+		_, err = sensor.ReadPressurePa(bsbmp.ACCURACY_STANDARD)
+		if err != nil {
+			t.Fatal(err)
+		}
+		status, err := sensor.ReadInterruptStatus()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if status.PressureOutOfRange != c.oor {
+			t.Errorf("out-of-range at %v Pa = %v, want %v",
+				c.pressure, status.PressureOutOfRange, c.oor)
+		}
+	}
+	for _, settings := range []bsbmp.PressureOutOfRange{
+		{ThresholdPa: 0x20000, RangePa: 10},
+		{ThresholdPa: 101325, RangePa: 10, CountLimit: 5},
+	} {
+		err = sensor.SetPressureOutOfRange(settings)
+		if err == nil {
+			t.Errorf("%+v accepted", settings)
+		}
+	}
+}
+
+func TestBMP581FIFO(t *testing.T) {
+	envs := []sim.Environment{
+		{Temperature: 21.5, Pressure: 100500},
+		{Temperature: -5.25, Pressure: 87654},
+	}
+	for _, c := range []struct {
+		settings  bsbmp.FIFOSettings
+		frameSize int
+	}{
+		{bsbmp.FIFOSettings{Enabled: true, Temperature: true, Pressure: true}, 6},
+		{bsbmp.FIFOSettings{Enabled: true, Pressure: true}, 3},
+		{bsbmp.FIFOSettings{Enabled: true, Temperature: true}, 3},
+	} {
+		name := fmt.Sprintf("T=%v,P=%v", c.settings.Temperature, c.settings.Pressure)
+		dev := sim.NewBMP581()
+		sensor, err := bsbmp.NewBMP(bsbmp.BMP581, dev)
+		if err != nil {
+			t.Fatal(err)
+		}
+		err = sensor.SetFIFO(c.settings)
+		if err != nil {
+			t.Fatal(err)
+		}
+		err = sensor.SetPowerMode(bsbmp.POWER_MODE_NORMAL, bsbmp.MeasureSettings{})
+		if err != nil {
+			t.Fatal(err)
+		}
+		for _, env := range envs {
+			dev.SetEnvironment(env)
+			dev.Sample(1)
+		}
+		n, err := sensor.ReadFIFOLength()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if n != 2*c.frameSize {
+			t.Errorf("%s: ReadFIFOLength() = %d, want %d", name, n, 2*c.frameSize)
+		}
+		data, err := sensor.ReadFIFO()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(data.Samples) != len(envs) {
+			t.Fatalf("%s: %d samples read, want %d", name, len(data.Samples), len(envs))
+		}
+		for i, s := range data.Samples {
+			if s.HasTemperature != c.settings.Temperature || s.HasPressure != c.settings.Pressure {
+				t.Errorf("%s: sample %d content = %v, %v", name, i, s.HasTemperature, s.HasPressure)
+			}
+			if s.HasTemperature {
+				checkValue(t, name+" temperature", float64(s.TemperatureC()),
+					envs[i].Temperature, temperatureTolerance)
+			}
+			if s.HasPressure {
+				checkValue(t, name+" pressure", float64(s.PressurePa()),
+					envs[i].Pressure, pressureTolerance)
+			}
+		}
+		n, err = sensor.ReadFIFOLength()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if n != 0 {
+			t.Errorf("%s: ReadFIFOLength() = %d after drain, want 0", name, n)
+		}
+	}
+}
+
+// BMP581 FIFO keep 16 frames with both values. Once full, either the oldest
+// frames are overwritten, or new frames are dropped (stop-on-full).
+func TestBMP581FIFOFull(t *testing.T) {
+	for _, stopOnFull := range []bool{false, true} {
+		dev := sim.NewBMP581()
+		sensor, err := bsbmp.NewBMP(bsbmp.BMP581, dev)
+		if err != nil {
+			t.Fatal(err)
+		}
+		err = sensor.SetFIFO(bsbmp.FIFOSettings{Enabled: true, Temperature: true,
+			Pressure: true, StopOnFull: stopOnFull})
+		if err != nil {
+			t.Fatal(err)
+		}
+		err = sensor.SetPowerMode(bsbmp.POWER_MODE_NORMAL, bsbmp.MeasureSettings{})
+		if err != nil {
+			t.Fatal(err)
+		}
+		for i := 0; i < 20; i++ {
+			dev.SetEnvironment(sim.Environment{Temperature: float64(i), Pressure: 100000})
+			dev.Sample(1)
+		}
+		data, err := sensor.ReadFIFO()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(data.Samples) != 16 {
+			t.Fatalf("stopOnFull=%v: %d samples read, want 16", stopOnFull, len(data.Samples))
+		}
+		first := 4.0
+		if stopOnFull {
+			first = 0
+		}
+		checkValue(t, fmt.Sprintf("stopOnFull=%v first sample temperature", stopOnFull),
+			float64(data.Samples[0].TemperatureC()), first, temperatureTolerance)
+	}
+}
@@ -0,0 +1,394 @@
+//--------------------------------------------------------------------------------------------------
+//
+// Copyright (c) 2018 Denis Dyakov
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy of this software and
+// associated documentation files (the "Software"), to deal in the Software without restriction,
+// including without limitation the rights to use, copy, modify, merge, publish, distribute, sublicense,
+// and/or sell copies of the Software, and to permit persons to whom the Software is furnished to do so,
+// subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all copies or substantial
+// portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR IMPLIED, INCLUDING
+// BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY, FITNESS FOR A PARTICULAR PURPOSE AND
+// NONINFRINGEMENT. IN NO EVENT SHALL THE AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM,
+// DAMAGES OR OTHER LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE SOFTWARE.
+//
+//--------------------------------------------------------------------------------------------------
+
+package bsbmp_test
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"testing"
+
+	"github.com/d2r2/go-bsbmp"
+	"github.com/d2r2/go-bsbmp/sim"
+)
+
+// Tolerances of values read from emulated sensors,
+// caused by integer compensation and rounding.
+const (
+	temperatureTolerance = 0.11
+	pressureTolerance    = 10.0
+	humidityTolerance    = 0.5
+)
+
+// Environments emulated, covering operating range of sensors.
+var testEnvironments = []sim.Environment{
+	sim.DefaultEnvironment,
+	{Temperature: -10, Pressure: 80000, Humidity: 20, GasResistance: 5000},
+	{Temperature: 40, Pressure: 105000, Humidity: 90, GasResistance: 200000},
+	{Temperature: 18.3, Pressure: 98765, Humidity: 63, GasResistance: 1200},
+}
+
+// Sensors emulated with signature and humidity support expected.
+var testSensors = []struct {
+	sensorType bsbmp.SensorType
+	newDevice  func() *sim.Device
+	signature  uint8
+	humidity   bool
+}{
+	{bsbmp.BMP180, sim.NewBMP180, 0x55, false},
+	{bsbmp.BMP085, sim.NewBMP085, 0x55, false},
+	{bsbmp.BMP280, sim.NewBMP280, 0x58, false},
+	{bsbmp.BME280, sim.NewBME280, 0x60, true},
+	{bsbmp.BMP388, sim.NewBMP388, 0x50, false},
+	{bsbmp.BME680, sim.NewBME680, 0x61, true},
+	{bsbmp.BMP581, sim.NewBMP581, 0x50, false},
+	{bsbmp.BME688, sim.NewBME688, 0x61, true},
+}
+
+var testAccuracies = []bsbmp.AccuracyMode{
+	bsbmp.ACCURACY_ULTRA_LOW,
+	bsbmp.ACCURACY_STANDARD,
+	bsbmp.ACCURACY_ULTRA_HIGH,
+}
+
+// checkValue report error, if value differs from expected more than tolerance.
+func checkValue(t *testing.T, name string, got, want, tolerance float64) {
+	t.Helper()
+	if math.Abs(got-want) > tolerance {
+		t.Errorf("%s = %v, want %v (±%v)", name, got, want, tolerance)
+	}
+}
+
+func TestNewBMP(t *testing.T) {
+	for _, ts := range testSensors {
+		t.Run(ts.sensorType.String(), func(t *testing.T) {
+			sensor, err := bsbmp.NewBMP(ts.sensorType, ts.newDevice())
+			if err != nil {
+				t.Fatal(err)
+			}
+			if sensor.SensorType() != ts.sensorType {
+				t.Errorf("SensorType() = %v, want %v", sensor.SensorType(), ts.sensorType)
+			}
+			id, err := sensor.ReadSensorID()
+			if err != nil {
+				t.Fatal(err)
+			}
+			if id != ts.signature {
+				t.Errorf("ReadSensorID() = 0x%x, want 0x%x", id, ts.signature)
+			}
+			err = sensor.ReloadCoefficients()
+			if err != nil {
+				t.Fatal(err)
+			}
+			err = sensor.IsValidCoefficients()
+			if err != nil {
+				t.Fatal(err)
+			}
+		})
+	}
+}
+
+func TestNewBMPAuto(t *testing.T) {
+	for _, c := range []struct {
+		newDevice  func() *sim.Device
+		sensorType bsbmp.SensorType
+	}{
+		{sim.NewBMP180, bsbmp.BMP180},
+		// BMP085 share chip ID with BMP180
+		{sim.NewBMP085, bsbmp.BMP180},
+		{sim.NewBMP280, bsbmp.BMP280},
+		{sim.NewBME280, bsbmp.BME280},
+		{sim.NewBMP388, bsbmp.BMP388},
+		// BMP390 chip ID 0x60 at 0x00 equal to BME280 one at 0xD0
+		{sim.NewBMP390, bsbmp.BMP390},
+		// BMP384 share chip ID with BMP388
+		{sim.NewBMP384, bsbmp.BMP388},
+		{sim.NewBMP581, bsbmp.BMP581},
+		{sim.NewBMP585, bsbmp.BMP585},
+		// BME688 share chip ID with BME680, but differ by variant ID
+		{sim.NewBME680, bsbmp.BME680},
+		{sim.NewBME688, bsbmp.BME688},
+	} {
+		dev := c.newDevice()
+		sensorType, err := bsbmp.DetectSensorType(dev)
+		if err != nil {
+			t.Fatalf("%s: %v", dev.Name(), err)
+		}
+		if sensorType != c.sensorType {
+			t.Errorf("%s: DetectSensorType() = %v, want %v", dev.Name(), sensorType, c.sensorType)
+		}
+		sensor, err := bsbmp.NewBMPAuto(dev)
+		if err != nil {
+			t.Fatalf("%s: %v", dev.Name(), err)
+		}
+		if sensor.SensorType() != c.sensorType {
+			t.Errorf("%s: NewBMPAuto() sensor type = %v, want %v", dev.Name(), sensor.SensorType(), c.sensorType)
+		}
+	}
+	// no chip ID recognized
+	dev := sim.NewBMP280()
+	dev.SetRegister(0xD0, 0x42)
+	_, err := bsbmp.NewBMPAuto(dev)
+	var e *bsbmp.SignatureError
+	if !errors.Is(err, bsbmp.ErrInvalidSignature) || !errors.As(err, &e) ||
+		e.Sensor != bsbmp.UNKNOWN_SENSOR || e.Signature != 0x42 {
+		t.Errorf("err = %v, want SignatureError", err)
+	}
+}
+
+func TestRead(t *testing.T) {
+	for _, ts := range testSensors {
+		for _, env := range testEnvironments {
+			dev := ts.newDevice()
+			dev.SetEnvironment(env)
+			sensor, err := bsbmp.NewBMP(ts.sensorType, dev)
+			if err != nil {
+				t.Fatalf("%v: %v", ts.sensorType, err)
+			}
+			for _, accuracy := range testAccuracies {
+				name := fmt.Sprintf("%v/accuracy=%d", ts.sensorType, accuracy)
+				t100, err := sensor.ReadTemperatureMult100C(accuracy)
+				if err != nil {
+					t.Fatalf("%s: %v", name, err)
+				}
+				checkValue(t, name+" ReadTemperatureMult100C", float64(t100)/100,
+					env.Temperature, temperatureTolerance)
+				tc, err := sensor.ReadTemperatureC(accuracy)
+				if err != nil {
+					t.Fatalf("%s: %v", name, err)
+				}
+				checkValue(t, name+" ReadTemperatureC", float64(tc),
+					env.Temperature, temperatureTolerance)
+				p10, err := sensor.ReadPressureMult10Pa(accuracy)
+				if err != nil {
+					t.Fatalf("%s: %v", name, err)
+				}
+				checkValue(t, name+" ReadPressureMult10Pa", float64(p10)/10,
+					env.Pressure, pressureTolerance)
+				p, err := sensor.ReadPressurePa(accuracy)
+				if err != nil {
+					t.Fatalf("%s: %v", name, err)
+				}
+				checkValue(t, name+" ReadPressurePa", float64(p),
+					env.Pressure, pressureTolerance)
+				mmHg, err := sensor.ReadPressureMmHg(accuracy)
+				if err != nil {
+					t.Fatalf("%s: %v", name, err)
+				}
+				checkValue(t, name+" ReadPressureMmHg", float64(mmHg),
+					env.Pressure/133.322, 0.1)
+				supported, h, err := sensor.ReadHumidityRH(accuracy)
+				if err != nil {
+					t.Fatalf("%s: %v", name, err)
+				}
+				if supported != ts.humidity {
+					t.Errorf("%s: humidity supported = %v, want %v", name, supported, ts.humidity)
+				}
+				if supported {
+					checkValue(t, name+" ReadHumidityRH", float64(h),
+						env.Humidity, humidityTolerance)
+				}
+			}
+		}
+	}
+}
+
+func TestMeasure(t *testing.T) {
+	settings := bsbmp.MeasureSettings{
+		Temperature: bsbmp.ACCURACY_HIGH,
+		Pressure:    bsbmp.ACCURACY_HIGH,
+		Humidity:    bsbmp.ACCURACY_STANDARD,
+	}
+	for _, ts := range testSensors {
+		for _, env := range testEnvironments {
+			dev := ts.newDevice()
+			dev.SetEnvironment(env)
+			sensor, err := bsbmp.NewBMP(ts.sensorType, dev)
+			if err != nil {
+				t.Fatalf("%v: %v", ts.sensorType, err)
+			}
+			m, err := sensor.Measure(settings)
+			if err != nil {
+				t.Fatalf("%v: %v", ts.sensorType, err)
+			}
+			name := ts.sensorType.String() + " Measure"
+			checkValue(t, name+" temperature", float64(m.TemperatureC()),
+				env.Temperature, temperatureTolerance)
+			checkValue(t, name+" pressure", float64(m.PressurePa()),
+				env.Pressure, pressureTolerance)
+			if m.HumiditySupported != ts.humidity {
+				t.Errorf("%s: humidity supported = %v, want %v", name, m.HumiditySupported, ts.humidity)
+			}
+			if m.HumiditySupported {
+				checkValue(t, name+" humidity", float64(m.HumidityRH()),
+					env.Humidity, humidityTolerance)
+			}
+		}
+	}
+}
+
+// writeLogBus record values written to sensor registers.
+type writeLogBus struct {
+	*sim.Device
+	writes map[byte][]byte
+}
+
+func (v *writeLogBus) WriteRegU8(reg byte, value byte) error {
+	if v.writes == nil {
+		v.writes = make(map[byte][]byte)
+	}
+	v.writes[reg] = append(v.writes[reg], value)
+	return v.Device.WriteRegU8(reg, value)
+}
+
+// BMP180 temperature conversion is started by 0x2E command,
+// while 0x2F is ignored by sensor, leaving stale data in output registers.
+func TestBMP180TemperatureCommand(t *testing.T) {
+	dev := sim.NewBMP180()
+	dev.SetEnvironment(sim.Environment{Temperature: 37.5, Pressure: 101325})
+	bus := &writeLogBus{Device: dev}
+	sensor, err := bsbmp.NewBMP(bsbmp.BMP180, bus)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tc, err := sensor.ReadTemperatureC(bsbmp.ACCURACY_STANDARD)
+	if err != nil {
+		t.Fatal(err)
+	}
+	checkValue(t, "temperature", float64(tc), 37.5, temperatureTolerance)
+	writes := bus.writes[0xF4]
+	if len(writes) != 1 || writes[0] != 0x2E {
+		t.Errorf("control register writes = %x, want [2e]", writes)
+	}
+}
+
+// BMP180 pressure compensation calculates B1*B6^2, which overflow int32,
+// once temperature is far enough from 25 °C, unless B6^2 is reduced first.
+func TestBMP180PressureOverflow(t *testing.T) {
+	for _, temperature := range []float64{-40, -10, 60, 85} {
+		dev := sim.NewBMP180()
+		dev.SetEnvironment(sim.Environment{Temperature: temperature, Pressure: 95000})
+		sensor, err := bsbmp.NewBMP(bsbmp.BMP180, dev)
+		if err != nil {
+			t.Fatal(err)
+		}
+		p, err := sensor.ReadPressurePa(bsbmp.ACCURACY_ULTRA_HIGH)
+		if err != nil {
+			t.Fatal(err)
+		}
+		checkValue(t, fmt.Sprintf("pressure at %v °C", temperature),
+			float64(p), 95000, pressureTolerance)
+	}
+}
+
+// BME280 ctrl_hum changes become effective only after ctrl_meas write,
+// so humidity oversampling should be written first, otherwise
+// humidity is skipped in the very first conversion.
+func TestBME280HumidityOrder(t *testing.T) {
+	dev := sim.NewBME280()
+	dev.SetEnvironment(sim.Environment{Temperature: 22, Pressure: 101325, Humidity: 35})
+	sensor, err := bsbmp.NewBMP(bsbmp.BME280, dev)
+	if err != nil {
+		t.Fatal(err)
+	}
+	supported, h, err := sensor.ReadHumidityRH(bsbmp.ACCURACY_STANDARD)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !supported {
+		t.Fatal("humidity is not supported")
+	}
+	checkValue(t, "humidity", float64(h), 35, humidityTolerance)
+}
+
+// BMP388 compensation return pressure in Pa multiplied by 100,
+// which should be reduced to Pa multiplied by 10.
+func TestBMP388PressureScale(t *testing.T) {
+	dev := sim.NewBMP388()
+	dev.SetEnvironment(sim.Environment{Temperature: 25, Pressure: 90000})
+	sensor, err := bsbmp.NewBMP(bsbmp.BMP388, dev)
+	if err != nil {
+		t.Fatal(err)
+	}
+	p10, err := sensor.ReadPressureMult10Pa(bsbmp.ACCURACY_STANDARD)
+	if err != nil {
+		t.Fatal(err)
+	}
+	checkValue(t, "pressure multiplied by 10", float64(p10), 900000, 10*pressureTolerance)
+}
+
+// BME680 pressure compensation calculates cube of pressure, which overflow
+// int32 above ~106 kPa, while sensor operating range is up to 110 kPa.
+func TestBME680PressureRangeTop(t *testing.T) {
+	for _, env := range []sim.Environment{
+		{Temperature: 40, Pressure: 110000, Humidity: 50, GasResistance: 50000},
+		{Temperature: -40, Pressure: 110000, Humidity: 50, GasResistance: 50000},
+		{Temperature: 25, Pressure: 30000, Humidity: 50, GasResistance: 50000},
+	} {
+		dev := sim.NewBME680()
+		dev.SetEnvironment(env)
+		sensor, err := bsbmp.NewBMP(bsbmp.BME680, dev)
+		if err != nil {
+			t.Fatal(err)
+		}
+		p, err := sensor.ReadPressurePa(bsbmp.ACCURACY_HIGH)
+		if err != nil {
+			t.Fatal(err)
+		}
+		checkValue(t, fmt.Sprintf("pressure at %v °C", env.Temperature),
+			float64(p), env.Pressure, pressureTolerance)
+	}
+}
+
+func TestBMP3Variants(t *testing.T) {
+	for _, c := range []struct {
+		sensorType bsbmp.SensorType
+		newDevice  func() *sim.Device
+		noisePa    float32
+	}{
+		{bsbmp.BMP388, sim.NewBMP388, 0.03},
+		{bsbmp.BMP390, sim.NewBMP390, 0.02},
+		{bsbmp.BMP384, sim.NewBMP384, 0.02},
+	} {
+		sensor, err := bsbmp.NewBMP(c.sensorType, c.newDevice())
+		if err != nil {
+			t.Fatalf("%v: %v", c.sensorType, err)
+		}
+		spec, err := sensor.Specification()
+		if err != nil {
+			t.Fatalf("%v: %v", c.sensorType, err)
+		}
+		if spec.NoisePa != c.noisePa {
+			t.Errorf("%v: noise = %v Pa, want %v Pa", c.sensorType, spec.NoisePa, c.noisePa)
+		}
+		for _, odr := range []bsbmp.OutputDataRate{bsbmp.ODR_200_HZ, bsbmp.ODR_0_0015_HZ} {
+			err = sensor.SetOutputDataRate(odr)
+			if err != nil {
+				t.Errorf("%v: SetOutputDataRate(%v): %v", c.sensorType, odr, err)
+			}
+		}
+		err = sensor.SetOutputDataRate(bsbmp.ODR_0_0015_HZ + 1)
+		if !errors.Is(err, bsbmp.ErrNotSupported) {
+			t.Errorf("%v: err = %v, want %v", c.sensorType, err, bsbmp.ErrNotSupported)
+		}
+	}
+}
@@ -0,0 +1,44 @@
+//--------------------------------------------------------------------------------------------------
+//
+// Copyright (c) 2018 Denis Dyakov
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy of this software and
+// associated documentation files (the "Software"), to deal in the Software without restriction,
+// including without limitation the rights to use, copy, modify, merge, publish, distribute, sublicense,
+// and/or sell copies of the Software, and to permit persons to whom the Software is furnished to do so,
+// subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all copies or substantial
+// portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR IMPLIED, INCLUDING
+// BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY, FITNESS FOR A PARTICULAR PURPOSE AND
+// NONINFRINGEMENT. IN NO EVENT SHALL THE AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM,
+// DAMAGES OR OTHER LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE SOFTWARE.
+//
+//--------------------------------------------------------------------------------------------------
+
+package bsbmp
+
+import (
+	i2c "github.com/d2r2/go-i2c"
+)
+
+// Bus is a register level transport used to talk to the sensor.
+// Any implementation providing access to sensor registers
+// (i2c, spi, emulated device and so on) can be used.
+type Bus interface {
+	// ReadRegU8 reads single byte from register reg.
+	ReadRegU8(reg byte) (byte, error)
+	// WriteRegU8 writes single byte value to register reg.
+	WriteRegU8(reg byte, value byte) error
+	// ReadRegBytes reads block of n bytes starting from register reg.
+	// Returns buffer and amount of bytes actually read.
+	ReadRegBytes(reg byte, n int) ([]byte, int, error)
+}
+
+// Static cast to verify at compile time that connection
+// to i2c-bus from github.com/d2r2/go-i2c can be used as Bus
+// as is, so existing code passing *i2c.I2C keeps working.
+var _ Bus = &i2c.I2C{}
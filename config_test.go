@@ -0,0 +1,251 @@
+//--------------------------------------------------------------------------------------------------
+//
+// Copyright (c) 2018 Denis Dyakov
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy of this software and
+// associated documentation files (the "Software"), to deal in the Software without restriction,
+// including without limitation the rights to use, copy, modify, merge, publish, distribute, sublicense,
+// and/or sell copies of the Software, and to permit persons to whom the Software is furnished to do so,
+// subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all copies or substantial
+// portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR IMPLIED, INCLUDING
+// BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY, FITNESS FOR A PARTICULAR PURPOSE AND
+// NONINFRINGEMENT. IN NO EVENT SHALL THE AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM,
+// DAMAGES OR OTHER LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE SOFTWARE.
+//
+//--------------------------------------------------------------------------------------------------
+
+package bsbmp_test
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/d2r2/go-bsbmp"
+	"github.com/d2r2/go-bsbmp/sim"
+)
+
+func TestSetIIRFilter(t *testing.T) {
+	for _, c := range []struct {
+		sensorType bsbmp.SensorType
+		newDevice  func() *sim.Device
+		reg        byte
+		shift      uint
+		max        bsbmp.IIRFilter
+	}{
+		{bsbmp.BMP280, sim.NewBMP280, bsbmp.BMP280_CONFIG, 2, bsbmp.IIR_FILTER_16},
+		{bsbmp.BME280, sim.NewBME280, bsbmp.BME280_CONFIG, 2, bsbmp.IIR_FILTER_16},
+		{bsbmp.BMP388, sim.NewBMP388, bsbmp.BMP388_CONFIG, 1, bsbmp.IIR_FILTER_128},
+		{bsbmp.BME680, sim.NewBME680, bsbmp.BME680_CONFIG, 2, bsbmp.IIR_FILTER_128},
+	} {
+		dev := c.newDevice()
+		env := sim.Environment{Temperature: 12.5, Pressure: 99000, Humidity: 70}
+		dev.SetEnvironment(env)
+		sensor, err := bsbmp.NewBMP(c.sensorType, dev)
+		if err != nil {
+			t.Fatal(err)
+		}
+		// bits around filter coefficient are kept
+		others := byte(0xFF) &^ (0x07 << c.shift)
+		dev.SetRegister(c.reg, others)
+		for filter := bsbmp.IIR_FILTER_OFF; filter <= bsbmp.IIR_FILTER_128; filter++ {
+			name := fmt.Sprintf("%v/%v", c.sensorType, filter)
+			err = sensor.SetIIRFilter(filter)
+			want := others | byte(filter)<<c.shift
+			if filter > c.max {
+				if !errors.Is(err, bsbmp.ErrNotSupported) {
+					t.Errorf("%s: err = %v, want %v", name, err, bsbmp.ErrNotSupported)
+				}
+				want = others | byte(c.max)<<c.shift
+			} else if err != nil {
+				t.Fatalf("%s: %v", name, err)
+			}
+			if b := dev.Register(c.reg); b != want {
+				t.Errorf("%s: register 0x%X = 0x%X, want 0x%X", name, c.reg, b, want)
+			}
+		}
+		// forced conversion keep filter configured
+		tc, err := sensor.ReadTemperatureC(bsbmp.ACCURACY_STANDARD)
+		if err != nil {
+			t.Fatal(err)
+		}
+		checkValue(t, c.sensorType.String()+" temperature", float64(tc), env.Temperature, temperatureTolerance)
+		if want := others | byte(c.max)<<c.shift; dev.Register(c.reg) != want {
+			t.Errorf("%v: register 0x%X = 0x%X after conversion, want 0x%X",
+				c.sensorType, c.reg, dev.Register(c.reg), want)
+		}
+	}
+	sensor, err := bsbmp.NewBMP(bsbmp.BMP180, sim.NewBMP180())
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = sensor.SetIIRFilter(bsbmp.IIR_FILTER_2)
+	if !errors.Is(err, bsbmp.ErrNotSupported) {
+		t.Errorf("BMP180: err = %v, want %v", err, bsbmp.ErrNotSupported)
+	}
+}
+
+func TestSetPowerModeNormal(t *testing.T) {
+	settings := bsbmp.MeasureSettings{
+		Temperature: bsbmp.ACCURACY_HIGH,
+		Pressure:    bsbmp.ACCURACY_ULTRA_HIGH,
+		Humidity:    bsbmp.ACCURACY_STANDARD,
+	}
+	for _, c := range []struct {
+		sensorType bsbmp.SensorType
+		newDevice  func() *sim.Device
+		regs       map[byte]byte
+	}{
+		// osrs_t, osrs_p, mode
+		{bsbmp.BMP280, sim.NewBMP280, map[byte]byte{0xF4: 4<<5 | 5<<2 | 3}},
+		// osrs_h as well
+		{bsbmp.BME280, sim.NewBME280, map[byte]byte{0xF4: 4<<5 | 5<<2 | 3, 0xF2: 3}},
+		// mode, temperature and pressure enabled, osr_t, osr_p
+		{bsbmp.BMP388, sim.NewBMP388, map[byte]byte{0x1B: 0x33, 0x1C: 3<<3 | 4}},
+	} {
+		dev := c.newDevice()
+		bus := &writeLogBus{Device: dev}
+		sensor, err := bsbmp.NewBMP(c.sensorType, bus)
+		if err != nil {
+			t.Fatal(err)
+		}
+		err = sensor.SetPowerMode(bsbmp.POWER_MODE_NORMAL, settings)
+		if err != nil {
+			t.Fatal(err)
+		}
+		for reg, want := range c.regs {
+			if b := dev.Register(reg); b != want {
+				t.Errorf("%v: register 0x%X = 0x%X, want 0x%X", c.sensorType, reg, b, want)
+			}
+		}
+		// sensor sample autonomously, so no conversion is triggered on read
+		bus.writes = nil
+		for _, env := range testEnvironments {
+			dev.SetEnvironment(env)
+			dev.Sample(1)
+			m, err := sensor.Measure(bsbmp.MeasureSettings{})
+			if err != nil {
+				t.Fatal(err)
+			}
+			name := c.sensorType.String() + " normal mode"
+			checkValue(t, name+" temperature", float64(m.TemperatureC()),
+				env.Temperature, temperatureTolerance)
+			checkValue(t, name+" pressure", float64(m.PressurePa()),
+				env.Pressure, pressureTolerance)
+			if m.HumiditySupported {
+				checkValue(t, name+" humidity", float64(m.HumidityRH()),
+					env.Humidity, humidityTolerance)
+			}
+		}
+		if len(bus.writes) != 0 {
+			t.Errorf("%v: registers written in normal mode %v", c.sensorType, bus.writes)
+		}
+		// back to forced mode
+		err = sensor.SetPowerMode(bsbmp.POWER_MODE_FORCED, settings)
+		if err != nil {
+			t.Fatal(err)
+		}
+		dev.SetEnvironment(sim.DefaultEnvironment)
+		tc, err := sensor.ReadTemperatureC(bsbmp.ACCURACY_STANDARD)
+		if err != nil {
+			t.Fatal(err)
+		}
+		checkValue(t, c.sensorType.String()+" forced mode temperature", float64(tc),
+			sim.DefaultEnvironment.Temperature, temperatureTolerance)
+	}
+	// BME680 doesn't have normal mode
+	sensor, err := bsbmp.NewBMP(bsbmp.BME680, sim.NewBME680())
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = sensor.SetPowerMode(bsbmp.POWER_MODE_NORMAL, settings)
+	if !errors.Is(err, bsbmp.ErrNotSupported) {
+		t.Errorf("BME680: err = %v, want %v", err, bsbmp.ErrNotSupported)
+	}
+}
+
+func TestSetStandbyTime(t *testing.T) {
+	for _, c := range []struct {
+		sensorType bsbmp.SensorType
+		newDevice  func() *sim.Device
+		standby    bsbmp.StandbyTime
+		// t_sb field value, negative if not supported
+		tsb int
+	}{
+		{bsbmp.BMP280, sim.NewBMP280, bsbmp.STANDBY_0_5_MS, 0},
+		{bsbmp.BMP280, sim.NewBMP280, bsbmp.STANDBY_250_MS, 3},
+		{bsbmp.BMP280, sim.NewBMP280, bsbmp.STANDBY_4000_MS, 7},
+		{bsbmp.BMP280, sim.NewBMP280, bsbmp.STANDBY_10_MS, -1},
+		{bsbmp.BME280, sim.NewBME280, bsbmp.STANDBY_1000_MS, 5},
+		// BME280 replace 2000 ms and 4000 ms with 10 ms and 20 ms
+		{bsbmp.BME280, sim.NewBME280, bsbmp.STANDBY_10_MS, 6},
+		{bsbmp.BME280, sim.NewBME280, bsbmp.STANDBY_20_MS, 7},
+		{bsbmp.BME280, sim.NewBME280, bsbmp.STANDBY_2000_MS, -1},
+		// sampling period is set up by output data rate
+		{bsbmp.BMP388, sim.NewBMP388, bsbmp.STANDBY_0_5_MS, -1},
+		{bsbmp.BME680, sim.NewBME680, bsbmp.STANDBY_0_5_MS, -1},
+	} {
+		name := fmt.Sprintf("%v/%v", c.sensorType, c.standby.Duration())
+		dev := c.newDevice()
+		sensor, err := bsbmp.NewBMP(c.sensorType, dev)
+		if err != nil {
+			t.Fatal(err)
+		}
+		// filter coefficient and SPI 3-wire bit are kept
+		dev.SetRegister(0xF5, 0x1D)
+		err = sensor.SetStandbyTime(c.standby)
+		if c.tsb < 0 {
+			if !errors.Is(err, bsbmp.ErrNotSupported) {
+				t.Errorf("%s: err = %v, want %v", name, err, bsbmp.ErrNotSupported)
+			}
+			continue
+		}
+		if err != nil {
+			t.Fatalf("%s: %v", name, err)
+		}
+		if b, want := dev.Register(0xF5), byte(c.tsb<<5|0x1D); b != want {
+			t.Errorf("%s: config register = 0x%X, want 0x%X", name, b, want)
+		}
+	}
+}
+
+func TestSetOutputDataRate(t *testing.T) {
+	dev := sim.NewBMP388()
+	sensor, err := bsbmp.NewBMP(bsbmp.BMP388, dev)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, odr := range []bsbmp.OutputDataRate{bsbmp.ODR_200_HZ, bsbmp.ODR_12_5_HZ, bsbmp.ODR_0_0015_HZ} {
+		err = sensor.SetOutputDataRate(odr)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if b := dev.Register(bsbmp.BMP388_ODR_REG); b != byte(odr) {
+			t.Errorf("ODR register = 0x%X, want 0x%X", b, byte(odr))
+		}
+	}
+	err = sensor.SetOutputDataRate(bsbmp.ODR_0_0015_HZ + 1)
+	if !errors.Is(err, bsbmp.ErrNotSupported) {
+		t.Errorf("BMP388: err = %v, want %v", err, bsbmp.ErrNotSupported)
+	}
+	// sampling period of BMx280 is set up by standby time,
+	// while BME680 doesn't have normal mode
+	for _, ts := range testSensors {
+		if ts.sensorType != bsbmp.BMP280 && ts.sensorType != bsbmp.BME280 && ts.sensorType != bsbmp.BME680 {
+			continue
+		}
+		sensor, err := bsbmp.NewBMP(ts.sensorType, ts.newDevice())
+		if err != nil {
+			t.Fatal(err)
+		}
+		err = sensor.SetOutputDataRate(bsbmp.ODR_50_HZ)
+		if !errors.Is(err, bsbmp.ErrNotSupported) {
+			t.Errorf("%v: err = %v, want %v", ts.sensorType, err, bsbmp.ErrNotSupported)
+		}
+	}
+}
@@ -0,0 +1,141 @@
+//--------------------------------------------------------------------------------------------------
+//
+// Copyright (c) 2018 Denis Dyakov
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy of this software and
+// associated documentation files (the "Software"), to deal in the Software without restriction,
+// including without limitation the rights to use, copy, modify, merge, publish, distribute, sublicense,
+// and/or sell copies of the Software, and to permit persons to whom the Software is furnished to do so,
+// subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all copies or substantial
+// portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR IMPLIED, INCLUDING
+// BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY, FITNESS FOR A PARTICULAR PURPOSE AND
+// NONINFRINGEMENT. IN NO EVENT SHALL THE AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM,
+// DAMAGES OR OTHER LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE SOFTWARE.
+//
+//--------------------------------------------------------------------------------------------------
+
+package bsbmp
+
+import (
+	"context"
+	"time"
+)
+
+// contextBus attach context to the bus, so waiting for conversion
+// completion honors context cancellation and deadline. Register access
+// is refused as well, once context is done.
+type contextBus struct {
+	Bus
+	ctx context.Context
+}
+
+// withContext attach context to the bus. Context which
+// can't be cancelled (context.Background) is not attached.
+func withContext(ctx context.Context, bus Bus) Bus {
+	if ctx.Done() == nil {
+		return bus
+	}
+	return &contextBus{Bus: bus, ctx: ctx}
+}
+
+// ReadRegU8 implement Bus interface.
+func (v *contextBus) ReadRegU8(reg byte) (byte, error) {
+	if err := v.ctx.Err(); err != nil {
+		return 0, err
+	}
+	return v.Bus.ReadRegU8(reg)
+}
+
+// WriteRegU8 implement Bus interface.
+func (v *contextBus) WriteRegU8(reg byte, value byte) error {
+	if err := v.ctx.Err(); err != nil {
+		return err
+	}
+	return v.Bus.WriteRegU8(reg, value)
+}
+
+// ReadRegBytes implement Bus interface.
+func (v *contextBus) ReadRegBytes(reg byte, n int) ([]byte, int, error) {
+	if err := v.ctx.Err(); err != nil {
+		return nil, 0, err
+	}
+	return v.Bus.ReadRegBytes(reg, n)
+}
+
+// busContext return context attached to the bus,
+// or context.Background if none.
+func busContext(bus Bus) context.Context {
+	if b, ok := bus.(*contextBus); ok {
+		return b.ctx
+	}
+	return context.Background()
+}
+
+// busInterruptPin return interrupt pin attached to the bus, if any.
+func busInterruptPin(bus Bus) InterruptPin {
+	for {
+		switch b := bus.(type) {
+		case *contextBus:
+			bus = b.Bus
+		case *interruptBus:
+			return b.pin
+		default:
+			return nil
+		}
+	}
+}
+
+// How long to wait for interrupt edge at once,
+// before checking context attached to the bus.
+const edgeWaitSlice = 5 * time.Millisecond
+
+// waitForEdge wait for interrupt edge on pin up to timeout. Returns false
+// on timeout. Waiting is split to short slices, so context attached
+// to the bus is checked meanwhile, and context error is returned once done.
+func waitForEdge(bus Bus, pin InterruptPin, timeout time.Duration) (bool, error) {
+	ctx := busContext(bus)
+	if ctx.Done() == nil {
+		return pin.WaitForEdge(timeout)
+	}
+	deadline := time.Now().Add(timeout)
+	for {
+		err := ctx.Err()
+		if err != nil {
+			return false, err
+		}
+		left := time.Until(deadline)
+		if left <= 0 {
+			return false, nil
+		}
+		if left > edgeWaitSlice {
+			left = edgeWaitSlice
+		}
+		edge, err := pin.WaitForEdge(left)
+		if err != nil || edge {
+			return edge, err
+		}
+	}
+}
+
+// sleep pause for duration d, unless context
+// attached to the bus is done earlier.
+func sleep(bus Bus, d time.Duration) error {
+	ctx := busContext(bus)
+	if ctx.Done() == nil {
+		time.Sleep(d)
+		return nil
+	}
+	t := time.NewTimer(d)
+	defer t.Stop()
+	select {
+	case <-t.C:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
@@ -0,0 +1,172 @@
+//--------------------------------------------------------------------------------------------------
+//
+// Copyright (c) 2018 Denis Dyakov
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy of this software and
+// associated documentation files (the "Software"), to deal in the Software without restriction,
+// including without limitation the rights to use, copy, modify, merge, publish, distribute, sublicense,
+// and/or sell copies of the Software, and to permit persons to whom the Software is furnished to do so,
+// subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all copies or substantial
+// portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR IMPLIED, INCLUDING
+// BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY, FITNESS FOR A PARTICULAR PURPOSE AND
+// NONINFRINGEMENT. IN NO EVENT SHALL THE AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM,
+// DAMAGES OR OTHER LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE SOFTWARE.
+//
+//--------------------------------------------------------------------------------------------------
+
+package bsbmp
+
+import (
+	"errors"
+	"fmt"
+)
+
+// Errors returned by sensor drivers and BMP methods. Use errors.Is
+// to check error kind, and errors.As to get typed error details.
+var (
+	// ErrTimeout is returned when sensor doesn't complete conversion in time,
+	// so data registers might keep stale values.
+	ErrTimeout = errors.New("sensor doesn't complete conversion in time")
+	// ErrInvalidSignature is matched by SignatureError.
+	ErrInvalidSignature = errors.New("invalid sensor signature")
+	// ErrInvalidCoefficient is matched by CoefficientError.
+	ErrInvalidCoefficient = errors.New("invalid compensation coefficient")
+	// ErrNotSupported is matched by NotSupportedError.
+	ErrNotSupported = errors.New("not supported by sensor")
+	// ErrBus is matched by BusError.
+	ErrBus = errors.New("sensor bus failure")
+	// ErrInsufficientHistory is returned when pressure history
+	// doesn't cover period requested.
+	ErrInsufficientHistory = errors.New("pressure history doesn't cover period requested")
+)
+
+// SignatureError is returned when sensor signature (chip ID)
+// doesn't belong to sensor model expected.
+type SignatureError struct {
+	// Sensor model expected, or UNKNOWN_SENSOR
+	// if sensor model is being detected.
+	Sensor SensorType
+	// Signature read from sensor.
+	Signature uint8
+}
+
+// Error implement error interface.
+func (v *SignatureError) Error() string {
+	if v.Sensor == UNKNOWN_SENSOR {
+		return fmt.Sprintf("can't recognize sensor by signature 0x%x", v.Signature)
+	}
+	return fmt.Sprintf("signature 0x%x doesn't belong to %v series", v.Signature, v.Sensor)
+}
+
+// Is match ErrInvalidSignature.
+func (v *SignatureError) Is(target error) bool {
+	return target == ErrInvalidSignature
+}
+
+// CoefficientError is returned when compensation
+// coefficient read from sensor NVM looks invalid.
+type CoefficientError struct {
+	// Coefficient name from datasheet.
+	Name string
+	// Value read from sensor.
+	Value uint16
+}
+
+// Error implement error interface.
+func (v *CoefficientError) Error() string {
+	return fmt.Sprintf("Coefficient %s is invalid: 0x%X", v.Name, v.Value)
+}
+
+// Is match ErrInvalidCoefficient.
+func (v *CoefficientError) Is(target error) bool {
+	return target == ErrInvalidCoefficient
+}
+
+// NotSupportedError is returned when sensor lacks feature,
+// or doesn't support setting value requested.
+type NotSupportedError struct {
+	Sensor SensorType
+	// Feature description, optionally with setting value.
+	Feature string
+}
+
+// Error implement error interface.
+func (v *NotSupportedError) Error() string {
+	return fmt.Sprintf("%s is not supported by %v", v.Feature, v.Sensor)
+}
+
+// Is match ErrNotSupported.
+func (v *NotSupportedError) Is(target error) bool {
+	return target == ErrNotSupported
+}
+
+// BusError is returned when register access fails.
+// It wraps original error returned by Bus implementation.
+type BusError struct {
+	// Operation: "read" or "write".
+	Op string
+	// Register address accessed.
+	Reg byte
+	Err error
+}
+
+// Error implement error interface.
+func (v *BusError) Error() string {
+	return fmt.Sprintf("%s register 0x%0X: %v", v.Op, v.Reg, v.Err)
+}
+
+// Unwrap return original error.
+func (v *BusError) Unwrap() error {
+	return v.Err
+}
+
+// Is match ErrBus.
+func (v *BusError) Is(target error) bool {
+	return target == ErrBus
+}
+
+// errorBus wraps errors returned by underlying bus into BusError.
+type errorBus struct {
+	Bus
+}
+
+// newErrorBus wraps bus, unless it's already wrapped.
+func newErrorBus(bus Bus) Bus {
+	switch bus.(type) {
+	case *errorBus, *interruptBus:
+		return bus
+	}
+	return &errorBus{Bus: bus}
+}
+
+// ReadRegU8 implement Bus interface.
+func (v *errorBus) ReadRegU8(reg byte) (byte, error) {
+	b, err := v.Bus.ReadRegU8(reg)
+	if err != nil {
+		return 0, &BusError{Op: "read", Reg: reg, Err: err}
+	}
+	return b, nil
+}
+
+// WriteRegU8 implement Bus interface.
+func (v *errorBus) WriteRegU8(reg byte, value byte) error {
+	err := v.Bus.WriteRegU8(reg, value)
+	if err != nil {
+		return &BusError{Op: "write", Reg: reg, Err: err}
+	}
+	return nil
+}
+
+// ReadRegBytes implement Bus interface.
+func (v *errorBus) ReadRegBytes(reg byte, n int) ([]byte, int, error) {
+	buf, m, err := v.Bus.ReadRegBytes(reg, n)
+	if err != nil {
+		return nil, 0, &BusError{Op: "read", Reg: reg, Err: err}
+	}
+	return buf, m, nil
+}
@@ -0,0 +1,155 @@
+//--------------------------------------------------------------------------------------------------
+//
+// Copyright (c) 2018 Denis Dyakov
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy of this software and
+// associated documentation files (the "Software"), to deal in the Software without restriction,
+// including without limitation the rights to use, copy, modify, merge, publish, distribute, sublicense,
+// and/or sell copies of the Software, and to permit persons to whom the Software is furnished to do so,
+// subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all copies or substantial
+// portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR IMPLIED, INCLUDING
+// BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY, FITNESS FOR A PARTICULAR PURPOSE AND
+// NONINFRINGEMENT. IN NO EVENT SHALL THE AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM,
+// DAMAGES OR OTHER LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE SOFTWARE.
+//
+//--------------------------------------------------------------------------------------------------
+
+package bsbmp_test
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/d2r2/go-bsbmp"
+	"github.com/d2r2/go-bsbmp/sim"
+)
+
+var errIO = errors.New("i/o error")
+
+// failBus fails block reads, once fail is set.
+type failBus struct {
+	bsbmp.Bus
+	fail bool
+}
+
+func (v *failBus) ReadRegBytes(reg byte, n int) ([]byte, int, error) {
+	if v.fail {
+		return nil, 0, errIO
+	}
+	return v.Bus.ReadRegBytes(reg, n)
+}
+
+// idOnlyBus fails all reads, except chip ID register,
+// so coefficients can't be read.
+type idOnlyBus struct {
+	bsbmp.Bus
+}
+
+func (v *idOnlyBus) ReadRegBytes(reg byte, n int) ([]byte, int, error) {
+	return nil, 0, errIO
+}
+
+func TestErrorTimeout(t *testing.T) {
+	dev := sim.NewBMP280()
+	sensor, err := bsbmp.NewBMP(bsbmp.BMP280, dev)
+	if err != nil {
+		t.Fatal(err)
+	}
+	dev.SetBusyPolls(1000)
+	_, err = sensor.ReadTemperatureC(bsbmp.ACCURACY_LOW)
+	if !errors.Is(err, bsbmp.ErrTimeout) {
+		t.Errorf("err = %v, want %v", err, bsbmp.ErrTimeout)
+	}
+}
+
+func TestErrorNotSupported(t *testing.T) {
+	sensor, err := bsbmp.NewBMP(bsbmp.BMP280, sim.NewBMP280())
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = sensor.SetFIFO(bsbmp.FIFOSettings{})
+	var e *bsbmp.NotSupportedError
+	if !errors.Is(err, bsbmp.ErrNotSupported) || !errors.As(err, &e) || e.Sensor != bsbmp.BMP280 {
+		t.Errorf("err = %v, want NotSupportedError", err)
+	}
+}
+
+func TestErrorNotSupportedSensor(t *testing.T) {
+	_, err := bsbmp.NewBMP(bsbmp.SensorType(100), sim.NewBMP280())
+	var e *bsbmp.NotSupportedError
+	if !errors.Is(err, bsbmp.ErrNotSupported) || !errors.As(err, &e) || e.Sensor != 100 {
+		t.Errorf("err = %v, want NotSupportedError", err)
+	}
+}
+
+func TestErrorSignature(t *testing.T) {
+	for _, c := range []struct {
+		sensorType bsbmp.SensorType
+		dev        *sim.Device
+		signature  uint8
+	}{
+		{bsbmp.BMP280, sim.NewBMP180(), 0x55},
+		// BME680 variant ID differ from BME688 one
+		{bsbmp.BME688, sim.NewBME680(), 0x00},
+	} {
+		_, err := bsbmp.NewBMP(c.sensorType, c.dev)
+		var e *bsbmp.SignatureError
+		if !errors.Is(err, bsbmp.ErrInvalidSignature) || !errors.As(err, &e) ||
+			e.Sensor != c.sensorType || e.Signature != c.signature {
+			t.Errorf("%v: err = %v, want SignatureError", c.sensorType, err)
+		}
+	}
+}
+
+func TestErrorCoefficient(t *testing.T) {
+	dev := sim.NewBMP280()
+	// corrupted NVM
+	dev.SetRegister(0x88, 0)
+	dev.SetRegister(0x89, 0)
+	sensor, err := bsbmp.NewBMP(bsbmp.BMP280, dev)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = sensor.IsValidCoefficients()
+	var e *bsbmp.CoefficientError
+	if !errors.Is(err, bsbmp.ErrInvalidCoefficient) || !errors.As(err, &e) {
+		t.Errorf("err = %v, want CoefficientError", err)
+	}
+}
+
+func TestErrorBus(t *testing.T) {
+	bus := &failBus{Bus: sim.NewBMP280()}
+	sensor, err := bsbmp.NewBMP(bsbmp.BMP280, bus)
+	if err != nil {
+		t.Fatal(err)
+	}
+	bus.fail = true
+	_, err = sensor.ReadPressurePa(bsbmp.ACCURACY_LOW)
+	var e *bsbmp.BusError
+	if !errors.Is(err, bsbmp.ErrBus) || !errors.Is(err, errIO) || !errors.As(err, &e) {
+		t.Errorf("err = %v, want BusError wrapping %v", err, errIO)
+	}
+}
+
+// Coefficients read failure in NewBMP is reported as BusError as well.
+func TestErrorBusCoefficients(t *testing.T) {
+	for _, c := range []struct {
+		sensorType bsbmp.SensorType
+		newDevice  func() *sim.Device
+	}{
+		{bsbmp.BMP180, sim.NewBMP180},
+		{bsbmp.BMP280, sim.NewBMP280},
+		{bsbmp.BMP388, sim.NewBMP388},
+	} {
+		_, err := bsbmp.NewBMP(c.sensorType, &idOnlyBus{Bus: c.newDevice()})
+		var e *bsbmp.BusError
+		if !errors.Is(err, bsbmp.ErrBus) || !errors.Is(err, errIO) || !errors.As(err, &e) {
+			t.Errorf("%v: err = %v, want BusError wrapping %v", c.sensorType, err, errIO)
+		}
+	}
+}
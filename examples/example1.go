@@ -29,10 +29,13 @@ func main() {
 	logger.ChangePackageLogLevel("i2c", logger.InfoLevel)
 	logger.ChangePackageLogLevel("bsbmp", logger.InfoLevel)
 
+	// sensor, err := bsbmp.NewBMP(bsbmp.BMP085, i2c) // signature=0x55
 	// sensor, err := bsbmp.NewBMP(bsbmp.BMP180, i2c) // signature=0x55
 	sensor, err := bsbmp.NewBMP(bsbmp.BMP280, i2c) // signature=0x58
 	// sensor, err := bsbmp.NewBMP(bsbmp.BME280, i2c) // signature=0x60
 	// sensor, err := bsbmp.NewBMP(bsbmp.BMP388, i2c) // signature=0x50
+	// sensor, err := bsbmp.NewBMP(bsbmp.BMP390, i2c) // signature=0x60
+	// sensor, err := bsbmp.NewBMP(bsbmp.BMP581, i2c) // signature=0x50 at 0x01
 	if err != nil {
 		lg.Fatal(err)
 	}
@@ -85,4 +88,20 @@ func main() {
 		lg.Fatal(err)
 	}
 	lg.Infof("Altitude = %v m", a)
+
+	// Read temperature, pressure and humidity (if supported)
+	// in single measurement cycle
+	m, err := sensor.Measure(bsbmp.MeasureSettings{
+		Temperature: bsbmp.ACCURACY_STANDARD,
+		Pressure:    bsbmp.ACCURACY_STANDARD,
+		Humidity:    bsbmp.ACCURACY_STANDARD,
+	})
+	if err != nil {
+		lg.Fatal(err)
+	}
+	lg.Infof("Measured at %v: temperature = %v*C, pressure = %v Pa",
+		m.Time.Format("15:04:05.000"), m.TemperatureC(), m.PressurePa())
+	if m.HumiditySupported {
+		lg.Infof("Measured humidity = %v %%", m.HumidityRH())
+	}
 }
@@ -0,0 +1,215 @@
+//--------------------------------------------------------------------------------------------------
+//
+// Copyright (c) 2018 Denis Dyakov
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy of this software and
+// associated documentation files (the "Software"), to deal in the Software without restriction,
+// including without limitation the rights to use, copy, modify, merge, publish, distribute, sublicense,
+// and/or sell copies of the Software, and to permit persons to whom the Software is furnished to do so,
+// subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all copies or substantial
+// portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR IMPLIED, INCLUDING
+// BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY, FITNESS FOR A PARTICULAR PURPOSE AND
+// NONINFRINGEMENT. IN NO EVENT SHALL THE AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM,
+// DAMAGES OR OTHER LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE SOFTWARE.
+//
+//--------------------------------------------------------------------------------------------------
+
+package bsbmp
+
+import (
+	"math"
+	"time"
+)
+
+// WindDirection define wind direction on 16-point compass rose,
+// used to correct weather forecast.
+type WindDirection int
+
+const (
+	// Wind direction is unknown or calm; no correction applied.
+	WIND_UNKNOWN WindDirection = iota
+	WIND_N
+	WIND_NNE
+	WIND_NE
+	WIND_ENE
+	WIND_E
+	WIND_ESE
+	WIND_SE
+	WIND_SSE
+	WIND_S
+	WIND_SSW
+	WIND_SW
+	WIND_WSW
+	WIND_W
+	WIND_WNW
+	WIND_NW
+	WIND_NNW
+)
+
+// String define stringer interface.
+func (v WindDirection) String() string {
+	names := [...]string{"Unknown", "N", "NNE", "NE", "ENE", "E", "ESE", "SE",
+		"SSE", "S", "SSW", "SW", "WSW", "W", "WNW", "NW", "NNW"}
+	if v < 0 || int(v) >= len(names) {
+		return "<unknown>"
+	}
+	return names[v]
+}
+
+// Season define season, used to correct weather forecast.
+type Season int
+
+const (
+	// Season is derived from month of latest reading and hemisphere.
+	SEASON_AUTO Season = iota
+	SEASON_SUMMER
+	SEASON_WINTER
+)
+
+// String define stringer interface.
+func (v Season) String() string {
+	switch v {
+	case SEASON_AUTO:
+		return "Auto"
+	case SEASON_SUMMER:
+		return "Summer"
+	case SEASON_WINTER:
+		return "Winter"
+	default:
+		return "<unknown>"
+	}
+}
+
+// ForecastHints contains local conditions, which improve
+// accuracy of Zambretti forecast.
+type ForecastHints struct {
+	// Elevation of station in meters, used to reduce pressure
+	// to sea level. Keep 0, if history is fed with sea level pressure.
+	ElevationM float64
+	// Wind direction.
+	Wind WindDirection
+	// Season; summer is April to September in northern hemisphere.
+	Season Season
+	// SouthernHemisphere is true for stations south of equator.
+	SouthernHemisphere bool
+}
+
+// Forecast contains Zambretti forecast.
+type Forecast struct {
+	// Forecast letter from A (settled fine) to Z (stormy, much rain).
+	Letter byte
+	// Forecast description.
+	Text string
+	// Sea level pressure in Pa (pascal) forecast is based on.
+	SeaLevelPressurePa float64
+	// Pressure trend forecast is based on.
+	Trend PressureTrend
+}
+
+// String define stringer interface.
+func (v *Forecast) String() string {
+	return v.Text
+}
+
+// Zambretti forecasts descriptions by letter.
+var zambrettiTexts = [...]string{
+	"Settled fine", "Fine weather", "Becoming fine", "Fine, becoming less settled",
+	"Fine, possible showers", "Fairly fine, improving", "Fairly fine, possible showers early",
+	"Fairly fine, showery later", "Showery early, improving", "Changeable, mending",
+	"Fairly fine, showers likely", "Rather unsettled clearing later", "Unsettled, probably improving",
+	"Showery, bright intervals", "Showery, becoming less settled", "Changeable, some rain",
+	"Unsettled, short fine intervals", "Unsettled, rain later", "Unsettled, some rain",
+	"Mostly very unsettled", "Occasional rain, worsening", "Rain at times, very unsettled",
+	"Rain at frequent intervals", "Rain, very unsettled", "Stormy, may improve", "Stormy, much rain",
+}
+
+// Zambretti forecast letters for falling, steady and rising pressure,
+// from high pressure to low one.
+const (
+	zambrettiFalling = "ABDHORUXZ"
+	zambrettiSteady  = "ABEKNPSWXZ"
+	zambrettiRising  = "ABCFGIJLMQTYZ"
+)
+
+// Sea level pressure correction in hPa for wind direction (northern
+// hemisphere), according to Negretti & Zambra forecaster, which expects
+// pressure in range 950..1050 hPa.
+var zambrettiWind = [...]float64{0, 6, 5, 5, 2, -0.5, -2, -5, -8.5,
+	-12, -10, -6, -4.5, -3, -0.5, 1.5, 3}
+
+// Zambretti return weather forecast for next 12 hours
+// from sea level pressure and pressure trend, by Zambretti algorithm.
+// Season and wind direction specified in hints are taken into account,
+// elevation is ignored. SEASON_AUTO is treated as no season correction.
+func Zambretti(seaLevelPa float64, trend PressureTrend, hints ForecastHints) *Forecast {
+	hpa := seaLevelPa / 100
+	wind := hints.Wind
+	if hints.SouthernHemisphere && wind != WIND_UNKNOWN {
+		// wind directions are mirrored south of equator
+		wind = (wind-WIND_N+8)%16 + WIND_N
+	}
+	if wind >= 0 && int(wind) < len(zambrettiWind) {
+		hpa += zambrettiWind[wind]
+	}
+	if hints.Season == SEASON_SUMMER {
+		switch trend {
+		case TREND_RISING:
+			hpa += 7
+		case TREND_FALLING:
+			hpa -= 7
+		}
+	}
+	var letters string
+	var z float64
+	switch trend {
+	case TREND_FALLING:
+		letters, z = zambrettiFalling, 127-0.12*hpa
+	case TREND_RISING:
+		letters, z = zambrettiRising, 185-0.16*hpa-19
+	default:
+		letters, z = zambrettiSteady, 144-0.13*hpa-9
+	}
+	i := int(math.Round(z)) - 1
+	if i < 0 {
+		i = 0
+	} else if i >= len(letters) {
+		i = len(letters) - 1
+	}
+	letter := letters[i]
+	f := &Forecast{Letter: letter, Text: zambrettiTexts[letter-'A'],
+		SeaLevelPressurePa: seaLevelPa, Trend: trend}
+	return f
+}
+
+// isSummer return true, if month is in summer half of year.
+func isSummer(month time.Month, southernHemisphere bool) bool {
+	summer := month >= time.April && month <= time.September
+	return summer != southernHemisphere
+}
+
+// Forecast return Zambretti weather forecast, based on latest pressure
+// reading and 3 hours pressure trend. Return ErrInsufficientHistory,
+// if history doesn't cover 3 hours yet.
+func (v *PressureHistory) Forecast(hints ForecastHints) (*Forecast, error) {
+	t, err := v.Tendency(3 * time.Hour)
+	if err != nil {
+		return nil, err
+	}
+	latest, _ := v.Latest()
+	p := latest.PressurePa
+	if hints.ElevationM != 0 {
+		p = QNHFromElevation(p, hints.ElevationM)
+	}
+	if hints.Season == SEASON_AUTO {
+		hints.Season = SEASON_WINTER
+		if isSummer(latest.Time.Month(), hints.SouthernHemisphere) {
+			hints.Season = SEASON_SUMMER
+		}
+	}
+	return Zambretti(p, t.Trend, hints), nil
+}
@@ -0,0 +1,121 @@
+//go:build linux
+// +build linux
+
+//--------------------------------------------------------------------------------------------------
+//
+// Copyright (c) 2018 Denis Dyakov
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy of this software and
+// associated documentation files (the "Software"), to deal in the Software without restriction,
+// including without limitation the rights to use, copy, modify, merge, publish, distribute, sublicense,
+// and/or sell copies of the Software, and to permit persons to whom the Software is furnished to do so,
+// subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all copies or substantial
+// portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR IMPLIED, INCLUDING
+// BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY, FITNESS FOR A PARTICULAR PURPOSE AND
+// NONINFRINGEMENT. IN NO EVENT SHALL THE AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM,
+// DAMAGES OR OTHER LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE SOFTWARE.
+//
+//--------------------------------------------------------------------------------------------------
+
+package bsbmp
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"syscall"
+	"time"
+	"unsafe"
+)
+
+// Linux gpio character device ioctl requests and flags, see linux/gpio.h.
+const (
+	gpioGetLineEventIOCTL  = 0xC030B404
+	gpioHandleRequestInput = 1 << 0
+	// Size of struct gpioevent_data
+	gpioEventDataSize = 16
+)
+
+// gpioEventRequest mirrors struct gpioevent_request from linux/gpio.h.
+type gpioEventRequest struct {
+	lineOffset    uint32
+	handleFlags   uint32
+	eventFlags    uint32
+	consumerLabel [32]byte
+	fd            int32
+}
+
+// NewGPIOPin request line of gpio chip /dev/gpiochip<chip> as input
+// reporting edge events. Use GPIO_EDGE_RISING for INT pin configured
+// active high and GPIO_EDGE_FALLING for active low.
+func NewGPIOPin(chip, line int, edge Edge) (*GPIOPin, error) {
+	if edge < GPIO_EDGE_RISING || edge > GPIO_EDGE_BOTH {
+		return nil, errors.New(fmt.Sprintf("unknown GPIO edge %d", edge))
+	}
+	path := fmt.Sprintf("/dev/gpiochip%d", chip)
+	f, err := os.OpenFile(path, os.O_RDWR, 0)
+	if err != nil {
+		return nil, err
+	}
+	// line event descriptor stays valid after chip closed
+	defer f.Close()
+	req := gpioEventRequest{
+		lineOffset:  uint32(line),
+		handleFlags: gpioHandleRequestInput,
+		eventFlags:  uint32(edge),
+	}
+	copy(req.consumerLabel[:], "bsbmp")
+	_, _, errno := syscall.Syscall(syscall.SYS_IOCTL, f.Fd(),
+		gpioGetLineEventIOCTL, uintptr(unsafe.Pointer(&req)))
+	if errno != 0 {
+		return nil, errno
+	}
+	lg.Debugf("GPIO line %d of %s requested for %v", line, path, edge)
+	return &GPIOPin{fd: int(req.fd)}, nil
+}
+
+// WaitForEdge implements InterruptPin interface.
+func (v *GPIOPin) WaitForEdge(timeout time.Duration) (bool, error) {
+	var fds syscall.FdSet
+	bits := uint(unsafe.Sizeof(fds.Bits[0])) * 8
+	if uint(v.fd) >= uint(len(fds.Bits))*bits {
+		return false, errors.New(fmt.Sprintf("GPIO line descriptor %d is out of select range", v.fd))
+	}
+	deadline := time.Now().Add(timeout)
+	for {
+		fds = syscall.FdSet{}
+		fds.Bits[uint(v.fd)/bits] |= 1 << (uint(v.fd) % bits)
+		left := time.Until(deadline)
+		if left < 0 {
+			left = 0
+		}
+		tv := syscall.NsecToTimeval(left.Nanoseconds())
+		n, err := syscall.Select(v.fd+1, &fds, nil, nil, &tv)
+		if err == syscall.EINTR {
+			continue
+		}
+		if err != nil {
+			return false, err
+		}
+		if n == 0 {
+			return false, nil
+		}
+		// consume event, to not report it again
+		var event [gpioEventDataSize]byte
+		_, err = syscall.Read(v.fd, event[:])
+		if err != nil {
+			return false, err
+		}
+		return true, nil
+	}
+}
+
+// Close release GPIO line.
+func (v *GPIOPin) Close() error {
+	return syscall.Close(v.fd)
+}
@@ -0,0 +1,48 @@
+//go:build !linux
+// +build !linux
+
+//--------------------------------------------------------------------------------------------------
+//
+// Copyright (c) 2018 Denis Dyakov
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy of this software and
+// associated documentation files (the "Software"), to deal in the Software without restriction,
+// including without limitation the rights to use, copy, modify, merge, publish, distribute, sublicense,
+// and/or sell copies of the Software, and to permit persons to whom the Software is furnished to do so,
+// subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all copies or substantial
+// portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR IMPLIED, INCLUDING
+// BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY, FITNESS FOR A PARTICULAR PURPOSE AND
+// NONINFRINGEMENT. IN NO EVENT SHALL THE AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM,
+// DAMAGES OR OTHER LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE SOFTWARE.
+//
+//--------------------------------------------------------------------------------------------------
+
+package bsbmp
+
+import (
+	"errors"
+	"time"
+)
+
+var errGPIONotSupported = errors.New("GPIO interrupt pin is not supported on this platform")
+
+// NewGPIOPin is available only on Linux, where gpio character device exists.
+// Implement InterruptPin interface to use interrupts elsewhere.
+func NewGPIOPin(chip, line int, edge Edge) (*GPIOPin, error) {
+	return nil, errGPIONotSupported
+}
+
+// WaitForEdge implements InterruptPin interface.
+func (v *GPIOPin) WaitForEdge(timeout time.Duration) (bool, error) {
+	return false, errGPIONotSupported
+}
+
+// Close release GPIO line.
+func (v *GPIOPin) Close() error {
+	return errGPIONotSupported
+}
@@ -0,0 +1,333 @@
+//--------------------------------------------------------------------------------------------------
+//
+// Copyright (c) 2018 Denis Dyakov
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy of this software and
+// associated documentation files (the "Software"), to deal in the Software without restriction,
+// including without limitation the rights to use, copy, modify, merge, publish, distribute, sublicense,
+// and/or sell copies of the Software, and to permit persons to whom the Software is furnished to do so,
+// subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all copies or substantial
+// portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR IMPLIED, INCLUDING
+// BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY, FITNESS FOR A PARTICULAR PURPOSE AND
+// NONINFRINGEMENT. IN NO EVENT SHALL THE AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM,
+// DAMAGES OR OTHER LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE SOFTWARE.
+//
+//--------------------------------------------------------------------------------------------------
+
+package bsbmp
+
+import (
+	"encoding/json"
+	"io"
+	"math"
+	"sort"
+	"sync"
+	"time"
+)
+
+// Pressure change thresholds used to classify pressure tendency.
+const (
+	// Net pressure change in Pa during 3 hours, below which pressure
+	// trend is treated as steady (1.6 hPa, which is still "slowly"
+	// changing pressure in terms of marine forecasts).
+	TREND_STEADY_PA = 160.0
+	// Pressure change in Pa, below which pressure is treated as
+	// unchanged by WMO tendency characteristic (0.1 hPa, resolution
+	// of synoptic reports).
+	CHARACTERISTIC_STEADY_PA = 10.0
+)
+
+// PressureTrend define direction of pressure change.
+type PressureTrend int
+
+const (
+	TREND_STEADY PressureTrend = iota
+	TREND_RISING
+	TREND_FALLING
+)
+
+// String define stringer interface.
+func (v PressureTrend) String() string {
+	switch v {
+	case TREND_STEADY:
+		return "Steady"
+	case TREND_RISING:
+		return "Rising"
+	case TREND_FALLING:
+		return "Falling"
+	default:
+		return "<unknown>"
+	}
+}
+
+// TendencyCharacteristic define characteristic of pressure tendency
+// during 3 hours, according to WMO code table 0200.
+type TendencyCharacteristic int
+
+const (
+	// Increasing, then decreasing; pressure the same or higher than before.
+	TENDENCY_INCREASING_DECREASING TendencyCharacteristic = iota
+	// Increasing, then steady; or increasing, then increasing more slowly.
+	TENDENCY_INCREASING_STEADY
+	// Increasing (steadily or unsteadily).
+	TENDENCY_INCREASING
+	// Decreasing or steady, then increasing; or increasing,
+	// then increasing more rapidly.
+	TENDENCY_STEADY_INCREASING
+	// Steady; pressure the same as before.
+	TENDENCY_STEADY
+	// Decreasing, then increasing; pressure the same or lower than before.
+	TENDENCY_DECREASING_INCREASING
+	// Decreasing, then steady; or decreasing, then decreasing more slowly.
+	TENDENCY_DECREASING_STEADY
+	// Decreasing (steadily or unsteadily).
+	TENDENCY_DECREASING
+	// Steady or increasing, then decreasing; or decreasing,
+	// then decreasing more rapidly.
+	TENDENCY_STEADY_DECREASING
+)
+
+// String define stringer interface.
+func (v TendencyCharacteristic) String() string {
+	switch v {
+	case TENDENCY_INCREASING_DECREASING:
+		return "Increasing, then decreasing"
+	case TENDENCY_INCREASING_STEADY:
+		return "Increasing, then steady"
+	case TENDENCY_INCREASING:
+		return "Increasing"
+	case TENDENCY_STEADY_INCREASING:
+		return "Steady, then increasing"
+	case TENDENCY_STEADY:
+		return "Steady"
+	case TENDENCY_DECREASING_INCREASING:
+		return "Decreasing, then increasing"
+	case TENDENCY_DECREASING_STEADY:
+		return "Decreasing, then steady"
+	case TENDENCY_DECREASING:
+		return "Decreasing"
+	case TENDENCY_STEADY_DECREASING:
+		return "Steady, then decreasing"
+	default:
+		return "<unknown>"
+	}
+}
+
+// Tendency contains pressure change during period.
+type Tendency struct {
+	// Period pressure change is calculated over.
+	Period time.Duration
+	// Net pressure change in Pa (pascal).
+	ChangePa float64
+	// Direction of pressure change.
+	Trend PressureTrend
+	// WMO characteristic of pressure change, taking into account
+	// pressure change in both halves of period.
+	Characteristic TendencyCharacteristic
+}
+
+// PressureSample is single pressure reading kept in history.
+type PressureSample struct {
+	Time       time.Time
+	PressurePa float64
+}
+
+// PressureHistory keeps pressure readings for period specified,
+// to calculate pressure tendency and weather forecast.
+// It is safe for concurrent use.
+type PressureHistory struct {
+	mu      sync.Mutex
+	maxAge  time.Duration
+	samples []PressureSample
+}
+
+// NewPressureHistory return history keeping pressure readings
+// not older than maxAge (related to latest reading). Keep at least
+// 3 hours to be able to calculate 3 hours tendency.
+func NewPressureHistory(maxAge time.Duration) *PressureHistory {
+	v := &PressureHistory{maxAge: maxAge}
+	return v
+}
+
+// Add append pressure reading in Pa (pascal) taken at time t.
+// Readings older than history keeps are dropped.
+func (v *PressureHistory) Add(t time.Time, pressurePa float64) {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+	v.insert(PressureSample{Time: t, PressurePa: pressurePa})
+	v.prune()
+}
+
+// AddMeasurement append pressure reading from measurement.
+func (v *PressureHistory) AddMeasurement(m *Measurement) {
+	v.Add(m.Time, float64(m.PressureMult10Pa)/10)
+}
+
+// insert put sample keeping samples sorted by time.
+func (v *PressureHistory) insert(s PressureSample) {
+	i := sort.Search(len(v.samples), func(i int) bool {
+		return v.samples[i].Time.After(s.Time)
+	})
+	v.samples = append(v.samples, PressureSample{})
+	copy(v.samples[i+1:], v.samples[i:])
+	v.samples[i] = s
+}
+
+// prune drop samples older than maxAge related to latest one.
+func (v *PressureHistory) prune() {
+	if len(v.samples) == 0 || v.maxAge <= 0 {
+		return
+	}
+	limit := v.samples[len(v.samples)-1].Time.Add(-v.maxAge)
+	i := sort.Search(len(v.samples), func(i int) bool {
+		return !v.samples[i].Time.Before(limit)
+	})
+	v.samples = append(v.samples[:0], v.samples[i:]...)
+}
+
+// Samples return copy of readings kept, sorted by time.
+func (v *PressureHistory) Samples() []PressureSample {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+	return append([]PressureSample(nil), v.samples...)
+}
+
+// Latest return latest reading. Second value is false, if history is empty.
+func (v *PressureHistory) Latest() (PressureSample, bool) {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+	if len(v.samples) == 0 {
+		return PressureSample{}, false
+	}
+	return v.samples[len(v.samples)-1], true
+}
+
+// pressureAt return pressure at time t, interpolated between neighbouring
+// samples. Second value is false, if history doesn't cover time t.
+func (v *PressureHistory) pressureAt(t time.Time) (float64, bool) {
+	i := sort.Search(len(v.samples), func(i int) bool {
+		return !v.samples[i].Time.Before(t)
+	})
+	if i == len(v.samples) {
+		return 0, false
+	}
+	s2 := v.samples[i]
+	if s2.Time.Equal(t) {
+		return s2.PressurePa, true
+	}
+	if i == 0 {
+		return 0, false
+	}
+	s1 := v.samples[i-1]
+	k := float64(t.Sub(s1.Time)) / float64(s2.Time.Sub(s1.Time))
+	return s1.PressurePa + (s2.PressurePa-s1.PressurePa)*k, true
+}
+
+// Tendency calculates pressure change during period ending with latest
+// reading, usually 1 or 3 hours. Return ErrInsufficientHistory,
+// if history doesn't cover period.
+func (v *PressureHistory) Tendency(period time.Duration) (*Tendency, error) {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+	if len(v.samples) == 0 || period <= 0 {
+		return nil, ErrInsufficientHistory
+	}
+	end := v.samples[len(v.samples)-1]
+	p0, ok := v.pressureAt(end.Time.Add(-period))
+	if !ok {
+		return nil, ErrInsufficientHistory
+	}
+	p1, _ := v.pressureAt(end.Time.Add(-period / 2))
+	t := &Tendency{Period: period, ChangePa: end.PressurePa - p0}
+	// Thresholds are defined for 3 hours, so scale them to period
+	scale := float64(period) / float64(3*time.Hour)
+	t.Trend = getTrend(t.ChangePa, TREND_STEADY_PA*scale)
+	t.Characteristic = getCharacteristic(p1-p0, end.PressurePa-p1,
+		CHARACTERISTIC_STEADY_PA*scale)
+	return t, nil
+}
+
+// getTrend classify pressure change as rising, falling or steady.
+func getTrend(change, threshold float64) PressureTrend {
+	switch {
+	case change >= threshold:
+		return TREND_RISING
+	case change <= -threshold:
+		return TREND_FALLING
+	default:
+		return TREND_STEADY
+	}
+}
+
+// getCharacteristic classify pressure change in first (d1)
+// and second (d2) halves of period according to WMO code table 0200.
+func getCharacteristic(d1, d2, threshold float64) TendencyCharacteristic {
+	trend1 := getTrend(d1, threshold/2)
+	trend2 := getTrend(d2, threshold/2)
+	// change rate is treated as different, if it differs twice
+	slower := math.Abs(d2) < math.Abs(d1)/2
+	faster := math.Abs(d2) > math.Abs(d1)*2
+	switch getTrend(d1+d2, threshold) {
+	case TREND_RISING:
+		switch {
+		case trend1 == TREND_RISING && trend2 == TREND_FALLING:
+			return TENDENCY_INCREASING_DECREASING
+		case trend1 == TREND_RISING && (trend2 == TREND_STEADY || slower):
+			return TENDENCY_INCREASING_STEADY
+		case trend1 != TREND_RISING || faster:
+			return TENDENCY_STEADY_INCREASING
+		default:
+			return TENDENCY_INCREASING
+		}
+	case TREND_FALLING:
+		switch {
+		case trend1 == TREND_FALLING && trend2 == TREND_RISING:
+			return TENDENCY_DECREASING_INCREASING
+		case trend1 == TREND_FALLING && (trend2 == TREND_STEADY || slower):
+			return TENDENCY_DECREASING_STEADY
+		case trend1 != TREND_FALLING || faster:
+			return TENDENCY_STEADY_DECREASING
+		default:
+			return TENDENCY_DECREASING
+		}
+	default:
+		switch {
+		case trend1 == TREND_RISING && trend2 == TREND_FALLING:
+			return TENDENCY_INCREASING_DECREASING
+		case trend1 == TREND_FALLING && trend2 == TREND_RISING:
+			return TENDENCY_DECREASING_INCREASING
+		default:
+			return TENDENCY_STEADY
+		}
+	}
+}
+
+// Save write history in JSON format, so it can be restored
+// with Load after application restart.
+func (v *PressureHistory) Save(w io.Writer) error {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+	return json.NewEncoder(w).Encode(v.samples)
+}
+
+// Load read history saved by Save, merging it with readings
+// already kept. Readings older than history keeps are dropped.
+func (v *PressureHistory) Load(r io.Reader) error {
+	var samples []PressureSample
+	err := json.NewDecoder(r).Decode(&samples)
+	if err != nil {
+		return err
+	}
+	v.mu.Lock()
+	defer v.mu.Unlock()
+	for _, s := range samples {
+		v.insert(s)
+	}
+	v.prune()
+	return nil
+}
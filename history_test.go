@@ -0,0 +1,274 @@
+//--------------------------------------------------------------------------------------------------
+//
+// Copyright (c) 2018 Denis Dyakov
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy of this software and
+// associated documentation files (the "Software"), to deal in the Software without restriction,
+// including without limitation the rights to use, copy, modify, merge, publish, distribute, sublicense,
+// and/or sell copies of the Software, and to permit persons to whom the Software is furnished to do so,
+// subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all copies or substantial
+// portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR IMPLIED, INCLUDING
+// BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY, FITNESS FOR A PARTICULAR PURPOSE AND
+// NONINFRINGEMENT. IN NO EVENT SHALL THE AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM,
+// DAMAGES OR OTHER LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE SOFTWARE.
+//
+//--------------------------------------------------------------------------------------------------
+
+package bsbmp_test
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/d2r2/go-bsbmp"
+)
+
+var historyStart = time.Date(2018, time.January, 10, 9, 0, 0, 0, time.UTC)
+
+// newHistory return history filled with pressure readings
+// taken with step specified, starting from historyStart.
+func newHistory(maxAge, step time.Duration, pressures ...float64) *bsbmp.PressureHistory {
+	h := bsbmp.NewPressureHistory(maxAge)
+	for i, p := range pressures {
+		h.Add(historyStart.Add(time.Duration(i)*step), p)
+	}
+	return h
+}
+
+func TestTendency(t *testing.T) {
+	// readings at 0, 2 and 4 hours, so period start is interpolated
+	h := newHistory(6*time.Hour, 2*time.Hour, 100000, 100200, 100300)
+	for _, c := range []struct {
+		period time.Duration
+		change float64
+		trend  bsbmp.PressureTrend
+	}{
+		// 100300 - 100100 (at 1 hour); 160 Pa threshold
+		{3 * time.Hour, 200, bsbmp.TREND_RISING},
+		// 100300 - 100250 (at 3 hours); 53.3 Pa threshold
+		{time.Hour, 50, bsbmp.TREND_STEADY},
+		// exactly at reading
+		{4 * time.Hour, 300, bsbmp.TREND_RISING},
+	} {
+		tend, err := h.Tendency(c.period)
+		if err != nil {
+			t.Fatalf("%v: %v", c.period, err)
+		}
+		if tend.Period != c.period {
+			t.Errorf("%v: period = %v", c.period, tend.Period)
+		}
+		checkValue(t, fmt.Sprintf("%v change", c.period), tend.ChangePa, c.change, 1e-6)
+		if tend.Trend != c.trend {
+			t.Errorf("%v: trend = %v, want %v", c.period, tend.Trend, c.trend)
+		}
+	}
+
+	h = newHistory(6*time.Hour, time.Hour, 101000, 100900, 100800, 100700)
+	tend, err := h.Tendency(3 * time.Hour)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if tend.Trend != bsbmp.TREND_FALLING {
+		t.Errorf("trend = %v, want %v", tend.Trend, bsbmp.TREND_FALLING)
+	}
+	tend, err = h.Tendency(time.Hour)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if tend.Trend != bsbmp.TREND_FALLING {
+		t.Errorf("1 hour trend = %v, want %v", tend.Trend, bsbmp.TREND_FALLING)
+	}
+}
+
+func TestTendencyInsufficientHistory(t *testing.T) {
+	for _, c := range []struct {
+		name   string
+		h      *bsbmp.PressureHistory
+		period time.Duration
+	}{
+		{"empty history", bsbmp.NewPressureHistory(6 * time.Hour), time.Hour},
+		{"single reading", newHistory(6*time.Hour, time.Hour, 101325), time.Hour},
+		{"2 hours history", newHistory(6*time.Hour, time.Hour, 101325, 101325, 101325), 3 * time.Hour},
+		{"zero period", newHistory(6*time.Hour, time.Hour, 101325, 101325), 0},
+	} {
+		_, err := c.h.Tendency(c.period)
+		if !errors.Is(err, bsbmp.ErrInsufficientHistory) {
+			t.Errorf("%s: err = %v, want %v", c.name, err, bsbmp.ErrInsufficientHistory)
+		}
+	}
+	_, err := bsbmp.NewPressureHistory(6 * time.Hour).Forecast(bsbmp.ForecastHints{})
+	if !errors.Is(err, bsbmp.ErrInsufficientHistory) {
+		t.Errorf("forecast: err = %v, want %v", err, bsbmp.ErrInsufficientHistory)
+	}
+}
+
+// Pressure change in both halves of 3 hours period is 10 Pa
+// threshold for net change and 5 Pa for halves.
+func TestTendencyCharacteristic(t *testing.T) {
+	for _, c := range []struct {
+		d1, d2 float64
+		want   bsbmp.TendencyCharacteristic
+	}{
+		// net pressure rising
+		{30, -10, bsbmp.TENDENCY_INCREASING_DECREASING},
+		{20, 2, bsbmp.TENDENCY_INCREASING_STEADY},
+		{40, 10, bsbmp.TENDENCY_INCREASING_STEADY},
+		{0, 20, bsbmp.TENDENCY_STEADY_INCREASING},
+		{-10, 30, bsbmp.TENDENCY_STEADY_INCREASING},
+		{6, 20, bsbmp.TENDENCY_STEADY_INCREASING},
+		{10, 10, bsbmp.TENDENCY_INCREASING},
+		// net pressure falling
+		{-30, 10, bsbmp.TENDENCY_DECREASING_INCREASING},
+		{-20, -2, bsbmp.TENDENCY_DECREASING_STEADY},
+		{-40, -10, bsbmp.TENDENCY_DECREASING_STEADY},
+		{0, -20, bsbmp.TENDENCY_STEADY_DECREASING},
+		{10, -30, bsbmp.TENDENCY_STEADY_DECREASING},
+		{-6, -20, bsbmp.TENDENCY_STEADY_DECREASING},
+		{-10, -10, bsbmp.TENDENCY_DECREASING},
+		// net pressure steady
+		{10, -8, bsbmp.TENDENCY_INCREASING_DECREASING},
+		{-10, 8, bsbmp.TENDENCY_DECREASING_INCREASING},
+		{2, 3, bsbmp.TENDENCY_STEADY},
+		{0, 0, bsbmp.TENDENCY_STEADY},
+	} {
+		const p0 = 101325
+		h := newHistory(6*time.Hour, 90*time.Minute, p0, p0+c.d1, p0+c.d1+c.d2)
+		tend, err := h.Tendency(3 * time.Hour)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if tend.Characteristic != c.want {
+			t.Errorf("d1=%v, d2=%v: characteristic = %q, want %q",
+				c.d1, c.d2, tend.Characteristic, c.want)
+		}
+	}
+}
+
+func TestZambretti(t *testing.T) {
+	for _, c := range []struct {
+		hpa   float64
+		trend bsbmp.PressureTrend
+		hints bsbmp.ForecastHints
+		want  byte
+	}{
+		{950, bsbmp.TREND_FALLING, bsbmp.ForecastHints{}, 'Z'},
+		{1000, bsbmp.TREND_FALLING, bsbmp.ForecastHints{}, 'U'},
+		{1050, bsbmp.TREND_FALLING, bsbmp.ForecastHints{}, 'A'},
+		{950, bsbmp.TREND_STEADY, bsbmp.ForecastHints{}, 'Z'},
+		{1000, bsbmp.TREND_STEADY, bsbmp.ForecastHints{}, 'N'},
+		{1050, bsbmp.TREND_STEADY, bsbmp.ForecastHints{}, 'A'},
+		{950, bsbmp.TREND_RISING, bsbmp.ForecastHints{}, 'Z'},
+		{1000, bsbmp.TREND_RISING, bsbmp.ForecastHints{}, 'I'},
+		{1050, bsbmp.TREND_RISING, bsbmp.ForecastHints{}, 'A'},
+		// north wind adds 6 hPa, south wind subtracts 12 hPa
+		{1000, bsbmp.TREND_FALLING, bsbmp.ForecastHints{Wind: bsbmp.WIND_N}, 'R'},
+		{1000, bsbmp.TREND_RISING, bsbmp.ForecastHints{Wind: bsbmp.WIND_S}, 'L'},
+		{1000, bsbmp.TREND_STEADY, bsbmp.ForecastHints{Wind: bsbmp.WIND_S}, 'S'},
+		// summer adds 7 hPa to rising and subtracts from falling pressure
+		{1000, bsbmp.TREND_RISING, bsbmp.ForecastHints{Season: bsbmp.SEASON_SUMMER}, 'G'},
+		{1000, bsbmp.TREND_FALLING, bsbmp.ForecastHints{Season: bsbmp.SEASON_SUMMER}, 'X'},
+		{1000, bsbmp.TREND_STEADY, bsbmp.ForecastHints{Season: bsbmp.SEASON_SUMMER}, 'N'},
+		{1000, bsbmp.TREND_RISING, bsbmp.ForecastHints{Season: bsbmp.SEASON_WINTER}, 'I'},
+		// wind directions are mirrored in southern hemisphere
+		{1000, bsbmp.TREND_STEADY, bsbmp.ForecastHints{Wind: bsbmp.WIND_S,
+			SouthernHemisphere: true}, 'K'},
+		{1000, bsbmp.TREND_FALLING, bsbmp.ForecastHints{Wind: bsbmp.WIND_N,
+			SouthernHemisphere: true}, 'X'},
+		{1000, bsbmp.TREND_STEADY, bsbmp.ForecastHints{SouthernHemisphere: true}, 'N'},
+	} {
+		f := bsbmp.Zambretti(c.hpa*100, c.trend, c.hints)
+		if f.Letter != c.want {
+			t.Errorf("%v hPa %v, %+v: letter = %c (%s), want %c",
+				c.hpa, c.trend, c.hints, f.Letter, f, c.want)
+		}
+		if f.Text == "" || f.Trend != c.trend || f.SeaLevelPressurePa != c.hpa*100 {
+			t.Errorf("%v hPa %v: forecast = %+v", c.hpa, c.trend, f)
+		}
+	}
+}
+
+// Season is derived from month of latest reading; January
+// is summer in southern hemisphere.
+func TestForecastSeason(t *testing.T) {
+	h := newHistory(6*time.Hour, 90*time.Minute, 99800, 99900, 100000)
+	for _, c := range []struct {
+		southern bool
+		want     byte
+	}{
+		{false, 'I'},
+		{true, 'G'},
+	} {
+		f, err := h.Forecast(bsbmp.ForecastHints{SouthernHemisphere: c.southern})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if f.Letter != c.want || f.Trend != bsbmp.TREND_RISING {
+			t.Errorf("southern=%v: forecast %c %v, want %c %v",
+				c.southern, f.Letter, f.Trend, c.want, bsbmp.TREND_RISING)
+		}
+	}
+}
+
+func TestPressureHistorySaveLoad(t *testing.T) {
+	// readings every hour during 5 hours
+	h := newHistory(0, time.Hour, 100000, 100100, 100200, 100300, 100400, 100500)
+	var buf bytes.Buffer
+	err := h.Save(&buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	data := buf.Bytes()
+
+	// reading exactly maxAge older than latest one is kept
+	h2 := bsbmp.NewPressureHistory(3 * time.Hour)
+	err = h2.Load(bytes.NewReader(data))
+	if err != nil {
+		t.Fatal(err)
+	}
+	checkSamples := func(name string, h *bsbmp.PressureHistory, first int, pressures ...float64) {
+		t.Helper()
+		samples := h.Samples()
+		if len(samples) != len(pressures) {
+			t.Fatalf("%s: %d samples, want %d", name, len(samples), len(pressures))
+		}
+		for i, s := range samples {
+			want := historyStart.Add(time.Duration(first+i) * time.Hour)
+			if !s.Time.Equal(want) || s.PressurePa != pressures[i] {
+				t.Errorf("%s: sample %d = %v %v, want %v %v",
+					name, i, s.Time, s.PressurePa, want, pressures[i])
+			}
+		}
+	}
+	checkSamples("loaded", h2, 2, 100200, 100300, 100400, 100500)
+	tend, err := h2.Tendency(3 * time.Hour)
+	if err != nil {
+		t.Fatal(err)
+	}
+	checkValue(t, "loaded change", tend.ChangePa, 300, 1e-6)
+
+	// loaded readings are merged with newer ones and pruned against latest
+	h3 := bsbmp.NewPressureHistory(3 * time.Hour)
+	h3.Add(historyStart.Add(6*time.Hour), 100600)
+	err = h3.Load(bytes.NewReader(data))
+	if err != nil {
+		t.Fatal(err)
+	}
+	checkSamples("merged", h3, 3, 100300, 100400, 100500, 100600)
+	latest, ok := h3.Latest()
+	if !ok || latest.PressurePa != 100600 {
+		t.Errorf("latest = %v, %v", latest, ok)
+	}
+
+	err = h3.Load(bytes.NewReader([]byte("{")))
+	if err == nil {
+		t.Error("malformed history loaded")
+	}
+}
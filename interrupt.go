@@ -0,0 +1,79 @@
+//--------------------------------------------------------------------------------------------------
+//
+// Copyright (c) 2018 Denis Dyakov
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy of this software and
+// associated documentation files (the "Software"), to deal in the Software without restriction,
+// including without limitation the rights to use, copy, modify, merge, publish, distribute, sublicense,
+// and/or sell copies of the Software, and to permit persons to whom the Software is furnished to do so,
+// subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all copies or substantial
+// portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR IMPLIED, INCLUDING
+// BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY, FITNESS FOR A PARTICULAR PURPOSE AND
+// NONINFRINGEMENT. IN NO EVENT SHALL THE AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM,
+// DAMAGES OR OTHER LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE SOFTWARE.
+//
+//--------------------------------------------------------------------------------------------------
+
+package bsbmp
+
+import (
+	"time"
+)
+
+// Edge define GPIO line transition treated as interrupt.
+type Edge int
+
+// Edge values match Linux gpio event flags.
+const (
+	GPIO_EDGE_RISING  Edge = 1
+	GPIO_EDGE_FALLING Edge = 2
+	GPIO_EDGE_BOTH    Edge = 3
+)
+
+// String define stringer interface.
+func (v Edge) String() string {
+	switch v {
+	case GPIO_EDGE_RISING:
+		return "Rising edge"
+	case GPIO_EDGE_FALLING:
+		return "Falling edge"
+	case GPIO_EDGE_BOTH:
+		return "Both edges"
+	default:
+		return "<unknown>"
+	}
+}
+
+// InterruptPin is a host input connected to sensor INT output.
+type InterruptPin interface {
+	// WaitForEdge blocks until interrupt edge detected, or timeout expired.
+	// Returns false on timeout.
+	WaitForEdge(timeout time.Duration) (bool, error)
+}
+
+// GPIOPin is an InterruptPin implementation based on
+// Linux gpio character device (/dev/gpiochipN).
+type GPIOPin struct {
+	// Line event file descriptor.
+	fd int
+}
+
+// Static cast to verify at compile time
+// that type implement interface.
+var _ InterruptPin = &GPIOPin{}
+
+// How long to wait for interrupt before
+// falling back to status register polling.
+const interruptTimeout = 100 * time.Millisecond
+
+// interruptBus attach interrupt pin to the bus, so waitForCompletion
+// can sleep until sensor signals data ready, instead of polling.
+type interruptBus struct {
+	Bus
+	pin InterruptPin
+}
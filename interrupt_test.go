@@ -0,0 +1,196 @@
+//--------------------------------------------------------------------------------------------------
+//
+// Copyright (c) 2018 Denis Dyakov
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy of this software and
+// associated documentation files (the "Software"), to deal in the Software without restriction,
+// including without limitation the rights to use, copy, modify, merge, publish, distribute, sublicense,
+// and/or sell copies of the Software, and to permit persons to whom the Software is furnished to do so,
+// subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all copies or substantial
+// portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR IMPLIED, INCLUDING
+// BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY, FITNESS FOR A PARTICULAR PURPOSE AND
+// NONINFRINGEMENT. IN NO EVENT SHALL THE AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM,
+// DAMAGES OR OTHER LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE SOFTWARE.
+//
+//--------------------------------------------------------------------------------------------------
+
+package bsbmp_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/d2r2/go-bsbmp"
+	"github.com/d2r2/go-bsbmp/sim"
+)
+
+// Upper limit for several conversions awaited by interrupt. Much less, than
+// interrupt timeout (100 ms), after which driver falls back to polling.
+const interruptDeadline = 80 * time.Millisecond
+
+// newInterruptSensor return emulated sensor with
+// INT pin attached and interrupt configured.
+func newInterruptSensor(t *testing.T, sensorType bsbmp.SensorType, dev *sim.Device,
+	settings bsbmp.InterruptSettings) *bsbmp.BMP {
+	t.Helper()
+	// status register reports conversion completed at once,
+	// so waiting time depends on interrupt only
+	dev.SetBusyPolls(0)
+	sensor, err := bsbmp.NewBMP(sensorType, dev)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = sensor.SetInterrupt(settings)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = sensor.SetInterruptPin(dev.InterruptPin())
+	if err != nil {
+		t.Fatal(err)
+	}
+	return sensor
+}
+
+func TestInterruptDataReady(t *testing.T) {
+	for _, latch := range []bool{false, true} {
+		for _, c := range []struct {
+			sensorType bsbmp.SensorType
+			newDevice  func() *sim.Device
+		}{
+			{bsbmp.BMP388, sim.NewBMP388},
+			{bsbmp.BMP581, sim.NewBMP581},
+		} {
+			sensor := newInterruptSensor(t, c.sensorType, c.newDevice(),
+				bsbmp.InterruptSettings{ActiveHigh: true, Latch: latch, DataReady: true})
+			start := time.Now()
+			for i := 0; i < 5; i++ {
+				_, err := sensor.Measure(bsbmp.MeasureSettings{})
+				if err != nil {
+					t.Fatal(err)
+				}
+			}
+			if d := time.Since(start); d > interruptDeadline {
+				t.Errorf("%v latch=%v: 5 conversions took %v, want less than %v",
+					c.sensorType, latch, d, interruptDeadline)
+			}
+		}
+	}
+}
+
+// Edges queued in normal mode should not be mistaken for end of forced conversion.
+func TestInterruptStaleEdges(t *testing.T) {
+	dev := sim.NewBMP388()
+	sensor := newInterruptSensor(t, bsbmp.BMP388, dev,
+		bsbmp.InterruptSettings{ActiveHigh: true, DataReady: true})
+	err := sensor.SetPowerMode(bsbmp.POWER_MODE_NORMAL, bsbmp.MeasureSettings{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	dev.Sample(5)
+	err = sensor.SetPowerMode(bsbmp.POWER_MODE_FORCED, bsbmp.MeasureSettings{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = sensor.Measure(bsbmp.MeasureSettings{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if edge, _ := dev.InterruptPin().WaitForEdge(0); edge {
+		t.Error("edges left queued after conversion")
+	}
+}
+
+// Without data-ready interrupt enabled, driver falls back to polling.
+func TestInterruptFallback(t *testing.T) {
+	dev := sim.NewBMP388()
+	sensor := newInterruptSensor(t, bsbmp.BMP388, dev, bsbmp.InterruptSettings{})
+	start := time.Now()
+	_, err := sensor.Measure(bsbmp.MeasureSettings{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if d := time.Since(start); d < 90*time.Millisecond {
+		t.Errorf("conversion took %v, want fallback after interrupt timeout", d)
+	}
+	err = sensor.SetInterruptPin(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	start = time.Now()
+	_, err = sensor.Measure(bsbmp.MeasureSettings{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if d := time.Since(start); d > interruptDeadline {
+		t.Errorf("conversion with pin detached took %v", d)
+	}
+}
+
+func TestInterruptPinNotSupported(t *testing.T) {
+	sensor, err := bsbmp.NewBMP(bsbmp.BMP280, sim.NewBMP280())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := sensor.SetInterruptPin(sim.NewPin()); err == nil {
+		t.Error("BMP280 accept interrupt pin")
+	}
+}
+
+// BMP085 signal conversion end on EOC pin, so maximum
+// conversion time is not awaited, once pin attached.
+func TestInterruptEOC(t *testing.T) {
+	dev := sim.NewBMP085()
+	env := sim.Environment{Temperature: 31, Pressure: 97000}
+	dev.SetEnvironment(env)
+	sensor, err := bsbmp.NewBMP(bsbmp.BMP085, dev)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = sensor.SetInterruptPin(dev.InterruptPin())
+	if err != nil {
+		t.Fatal(err)
+	}
+	start := time.Now()
+	p, err := sensor.ReadPressurePa(bsbmp.ACCURACY_ULTRA_HIGH)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// temperature (4.5 ms) and pressure (25.5 ms) conversions
+	if d := time.Since(start); d > 25*time.Millisecond {
+		t.Errorf("conversions took %v, want less than 30 ms", d)
+	}
+	checkValue(t, "pressure", float64(p), env.Pressure, pressureTolerance)
+}
+
+// Waiting for interrupt edge is interrupted, once context is done.
+func TestInterruptContext(t *testing.T) {
+	sensor := newInterruptSensor(t, bsbmp.BMP388, sim.NewBMP388(), bsbmp.InterruptSettings{})
+	bmp085, err := bsbmp.NewBMP(bsbmp.BMP085, sim.NewBMP085())
+	if err != nil {
+		t.Fatal(err)
+	}
+	// EOC pin not connected, so no edge comes
+	err = bmp085.SetInterruptPin(sim.NewPin())
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, s := range []*bsbmp.BMP{sensor, bmp085} {
+		ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+		start := time.Now()
+		_, err := s.ReadPressurePaContext(ctx, bsbmp.ACCURACY_HIGHEST)
+		cancel()
+		if !errors.Is(err, context.DeadlineExceeded) {
+			t.Errorf("%v: err = %v, want %v", s.SensorType(), err, context.DeadlineExceeded)
+		}
+		if d := time.Since(start); d > 50*time.Millisecond {
+			t.Errorf("%v: cancelled wait took %v", s.SensorType(), d)
+		}
+	}
+}
@@ -0,0 +1,228 @@
+//--------------------------------------------------------------------------------------------------
+//
+// Copyright (c) 2018 Denis Dyakov
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy of this software and
+// associated documentation files (the "Software"), to deal in the Software without restriction,
+// including without limitation the rights to use, copy, modify, merge, publish, distribute, sublicense,
+// and/or sell copies of the Software, and to permit persons to whom the Software is furnished to do so,
+// subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all copies or substantial
+// portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR IMPLIED, INCLUDING
+// BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY, FITNESS FOR A PARTICULAR PURPOSE AND
+// NONINFRINGEMENT. IN NO EVENT SHALL THE AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM,
+// DAMAGES OR OTHER LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE SOFTWARE.
+//
+//--------------------------------------------------------------------------------------------------
+
+package bsbmp
+
+import (
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+)
+
+// Default noise values of AltitudeEstimator.
+const (
+	DEFAULT_MEASUREMENT_NOISE_M = 1.0
+	DEFAULT_PROCESS_NOISE_MS2   = 1.0
+	DEFAULT_ACCEL_NOISE_MS2     = 0.3
+	DEFAULT_ACCEL_TIMEOUT       = 250 * time.Millisecond
+)
+
+// Variance of vertical speed in (m/s)^2 assumed before first estimation.
+const initialSpeedVariance = 100.0
+
+// AltitudeEstimatorSettings contains configuration of AltitudeEstimator.
+// Zero values are replaced with defaults.
+type AltitudeEstimatorSettings struct {
+	// Reference pressure at sea level in Pa (pascal).
+	// Standard atmosphere pressure by default.
+	QNHPa float64
+	// Standard deviation of altitude calculated from single pressure
+	// reading in meters (measurement noise). Increase it for noisy
+	// sensor or low accuracy mode.
+	MeasurementNoiseM float64
+	// Standard deviation of unknown vertical acceleration in m/s^2
+	// (process noise), used when no accelerometer input provided.
+	// Increase it to follow quick climb changes faster,
+	// decrease it to get smoother vertical speed.
+	ProcessNoiseMS2 float64
+	// Standard deviation of accelerometer readings in m/s^2,
+	// used as process noise once accelerometer input provided.
+	AccelNoiseMS2 float64
+	// How long latest accelerometer reading is used. Once accelerometer
+	// stream stops for longer, estimator goes back to pressure only.
+	AccelTimeout time.Duration
+}
+
+// AltitudeEstimator estimates altitude and vertical speed from stream
+// of timestamped pressure readings by Kalman filter, optionally fusing
+// vertical acceleration from accelerometer. Useful for variometers,
+// drones, elevators and so on. It is safe for concurrent use.
+type AltitudeEstimator struct {
+	mu       sync.Mutex
+	settings AltitudeEstimatorSettings
+	// State: altitude in meters and vertical speed in m/s.
+	altitude float64
+	speed    float64
+	// State covariance matrix.
+	p [2][2]float64
+	// Time state is estimated at.
+	time        time.Time
+	initialized bool
+	// Latest vertical acceleration in m/s^2, if accelerometer used.
+	accel      float64
+	accelTime  time.Time
+	accelValid bool
+}
+
+// NewAltitudeEstimator return estimator configured with settings.
+func NewAltitudeEstimator(settings AltitudeEstimatorSettings) (*AltitudeEstimator, error) {
+	if settings.QNHPa < 0 || settings.MeasurementNoiseM < 0 ||
+		settings.ProcessNoiseMS2 < 0 || settings.AccelNoiseMS2 < 0 ||
+		settings.AccelTimeout < 0 {
+		return nil, errors.New(fmt.Sprintf("altitude estimator settings %+v should not be negative", settings))
+	}
+	if settings.QNHPa == 0 {
+		settings.QNHPa = SEA_LEVEL_PRESSURE_PA
+	}
+	if settings.MeasurementNoiseM == 0 {
+		settings.MeasurementNoiseM = DEFAULT_MEASUREMENT_NOISE_M
+	}
+	if settings.ProcessNoiseMS2 == 0 {
+		settings.ProcessNoiseMS2 = DEFAULT_PROCESS_NOISE_MS2
+	}
+	if settings.AccelNoiseMS2 == 0 {
+		settings.AccelNoiseMS2 = DEFAULT_ACCEL_NOISE_MS2
+	}
+	if settings.AccelTimeout == 0 {
+		settings.AccelTimeout = DEFAULT_ACCEL_TIMEOUT
+	}
+	v := &AltitudeEstimator{settings: settings}
+	return v, nil
+}
+
+// Settings return estimator configuration with defaults applied.
+func (v *AltitudeEstimator) Settings() AltitudeEstimatorSettings {
+	return v.settings
+}
+
+// Reset drop estimation, so next pressure reading starts it from scratch.
+func (v *AltitudeEstimator) Reset() {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+	v.initialized = false
+	v.accelValid = false
+	v.altitude, v.speed, v.accel = 0, 0, 0
+}
+
+// predict extrapolate state to time t, using latest vertical
+// acceleration as control input, if accelerometer used.
+func (v *AltitudeEstimator) predict(t time.Time) {
+	dt := t.Sub(v.time).Seconds()
+	if dt <= 0 {
+		// Reading out of order is applied at time of latest one
+		return
+	}
+	if v.accelValid && t.Sub(v.accelTime) > v.settings.AccelTimeout {
+		// accelerometer stream stopped, so stale acceleration
+		// is not integrated any more
+		v.accelValid = false
+	}
+	v.time = t
+	sigma := v.settings.ProcessNoiseMS2
+	var a float64
+	if v.accelValid {
+		a = v.accel
+		sigma = v.settings.AccelNoiseMS2
+	}
+	// x = F*x + B*a, where F = [1 dt; 0 1], B = [dt^2/2; dt]
+	v.altitude += v.speed*dt + a*dt*dt/2
+	v.speed += a * dt
+	// P = F*P*F' + Q, where Q = G*G'*sigma^2, G = [dt^2/2; dt]
+	p := v.p
+	p00 := p[0][0] + dt*(p[1][0]+p[0][1]) + dt*dt*p[1][1]
+	p01 := p[0][1] + dt*p[1][1]
+	p10 := p[1][0] + dt*p[1][1]
+	q := sigma * sigma
+	v.p[0][0] = p00 + q*dt*dt*dt*dt/4
+	v.p[0][1] = p01 + q*dt*dt*dt/2
+	v.p[1][0] = p10 + q*dt*dt*dt/2
+	v.p[1][1] = p[1][1] + q*dt*dt
+}
+
+// AddAltitude update estimation with altitude in meters measured at time t.
+// Return altitude and vertical speed estimated.
+func (v *AltitudeEstimator) AddAltitude(t time.Time, altitudeM float64) (float64, float64) {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+	r := v.settings.MeasurementNoiseM * v.settings.MeasurementNoiseM
+	if !v.initialized {
+		v.altitude, v.speed = altitudeM, 0
+		v.p = [2][2]float64{{r, 0}, {0, initialSpeedVariance}}
+		v.time = t
+		v.initialized = true
+		return v.altitude, v.speed
+	}
+	v.predict(t)
+	// Measurement matrix H = [1 0]
+	s := v.p[0][0] + r
+	k0 := v.p[0][0] / s
+	k1 := v.p[1][0] / s
+	y := altitudeM - v.altitude
+	v.altitude += k0 * y
+	v.speed += k1 * y
+	// P = (I - K*H)*P
+	p := v.p
+	v.p[0][0] = (1 - k0) * p[0][0]
+	v.p[0][1] = (1 - k0) * p[0][1]
+	v.p[1][0] = p[1][0] - k1*p[0][0]
+	v.p[1][1] = p[1][1] - k1*p[0][1]
+	return v.altitude, v.speed
+}
+
+// AddPressure update estimation with pressure in Pa (pascal) measured
+// at time t, converted to altitude by international barometric formula.
+// Return altitude and vertical speed estimated.
+func (v *AltitudeEstimator) AddPressure(t time.Time, pressurePa float64) (float64, float64) {
+	return v.AddAltitude(t, BarometricAltitude(pressurePa, v.settings.QNHPa))
+}
+
+// AddMeasurement update estimation with pressure from measurement.
+// Return altitude and vertical speed estimated.
+func (v *AltitudeEstimator) AddMeasurement(m *Measurement) (float64, float64) {
+	return v.AddPressure(m.Time, float64(m.PressureMult10Pa)/10)
+}
+
+// AddAcceleration provide vertical acceleration in m/s^2 measured at time t
+// by accelerometer (gravity compensated, positive upward). Acceleration is
+// used to extrapolate estimation till next reading, so vertical speed
+// reacts to climb changes without delay, caused by pressure filtering.
+// Keep feeding acceleration regularly: once no reading comes during
+// AccelTimeout, estimator goes back to pressure only.
+func (v *AltitudeEstimator) AddAcceleration(t time.Time, accelMS2 float64) {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+	if v.initialized {
+		v.predict(t)
+	}
+	v.accel = accelMS2
+	v.accelTime = t
+	v.accelValid = true
+}
+
+// Estimate return altitude in meters and vertical speed in m/s
+// estimated at time of latest reading. Third value is false,
+// if no pressure reading provided yet.
+func (v *AltitudeEstimator) Estimate() (altitudeM, speedMS float64, ok bool) {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+	return v.altitude, v.speed, v.initialized
+}
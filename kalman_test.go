@@ -0,0 +1,172 @@
+//--------------------------------------------------------------------------------------------------
+//
+// Copyright (c) 2018 Denis Dyakov
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy of this software and
+// associated documentation files (the "Software"), to deal in the Software without restriction,
+// including without limitation the rights to use, copy, modify, merge, publish, distribute, sublicense,
+// and/or sell copies of the Software, and to permit persons to whom the Software is furnished to do so,
+// subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all copies or substantial
+// portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR IMPLIED, INCLUDING
+// BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY, FITNESS FOR A PARTICULAR PURPOSE AND
+// NONINFRINGEMENT. IN NO EVENT SHALL THE AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM,
+// DAMAGES OR OTHER LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE SOFTWARE.
+//
+//--------------------------------------------------------------------------------------------------
+
+package bsbmp_test
+
+import (
+	"math"
+	"math/rand"
+	"testing"
+	"time"
+
+	"github.com/d2r2/go-bsbmp"
+)
+
+// Synthetic flight profile: true altitude and vertical
+// acceleration at time t seconds since start.
+type flightProfile func(t float64) (altitudeM, accelMS2 float64)
+
+// constantClimb climb at speed from altitude h0.
+func constantClimb(h0, speed float64) flightProfile {
+	return func(t float64) (float64, float64) {
+		return h0 + speed*t, 0
+	}
+}
+
+// stepClimb stay level until t0, then accelerate during d seconds
+// to reach vertical speed, and keep climbing at that speed.
+func stepClimb(h0, t0, d, speed float64) flightProfile {
+	a := speed / d
+	return func(t float64) (float64, float64) {
+		switch {
+		case t < t0:
+			return h0, 0
+		case t < t0+d:
+			return h0 + a*(t-t0)*(t-t0)/2, a
+		default:
+			return h0 + a*d*d/2 + speed*(t-t0-d), 0
+		}
+	}
+}
+
+// pressureAt return pressure at altitude by inverted barometric formula.
+func pressureAt(altitudeM float64) float64 {
+	return bsbmp.SEA_LEVEL_PRESSURE_PA * math.Pow(1-altitudeM/44330, 5.255)
+}
+
+// fly feed estimator with pressure (and accelerometer readings, if useAccel)
+// sampled at rate Hz during duration seconds, adding gaussian noise.
+// Return estimated altitude and vertical speed at the end.
+func fly(t *testing.T, settings bsbmp.AltitudeEstimatorSettings, profile flightProfile,
+	duration, rate float64, altitudeNoiseM, accelNoiseMS2 float64, useAccel bool) (float64, float64) {
+	t.Helper()
+	est, err := bsbmp.NewAltitudeEstimator(settings)
+	if err != nil {
+		t.Fatal(err)
+	}
+	rnd := rand.New(rand.NewSource(1))
+	start := time.Unix(0, 0)
+	var h, vs float64
+	for i := 0; i <= int(duration*rate); i++ {
+		sec := float64(i) / rate
+		tm := start.Add(time.Duration(sec * float64(time.Second)))
+		altitude, accel := profile(sec)
+		if useAccel {
+			est.AddAcceleration(tm, accel+rnd.NormFloat64()*accelNoiseMS2)
+		}
+		p := pressureAt(altitude + rnd.NormFloat64()*altitudeNoiseM)
+		h, vs = est.AddPressure(tm, p)
+	}
+	return h, vs
+}
+
+func TestAltitudeEstimatorConstantClimb(t *testing.T) {
+	for _, speed := range []float64{0, 2, -1.5, 8} {
+		profile := constantClimb(300, speed)
+		h, vs := fly(t, bsbmp.AltitudeEstimatorSettings{}, profile, 30, 25, 0, 0, false)
+		want, _ := profile(30)
+		checkValue(t, "altitude", h, want, 0.1)
+		checkValue(t, "vertical speed", vs, speed, 0.05)
+	}
+}
+
+func TestAltitudeEstimatorNoisy(t *testing.T) {
+	settings := bsbmp.AltitudeEstimatorSettings{MeasurementNoiseM: 0.5}
+	for _, useAccel := range []bool{false, true} {
+		profile := constantClimb(100, 2)
+		h, vs := fly(t, settings, profile, 60, 25, 0.5, 0.1, useAccel)
+		want, _ := profile(60)
+		checkValue(t, "altitude", h, want, 0.5)
+		checkValue(t, "vertical speed", vs, 2, 0.3)
+	}
+}
+
+// Accelerometer makes vertical speed follow step into climb without filter delay.
+func TestAltitudeEstimatorStep(t *testing.T) {
+	settings := bsbmp.AltitudeEstimatorSettings{MeasurementNoiseM: 1, ProcessNoiseMS2: 0.2}
+	// stop right after acceleration phase
+	profile := stepClimb(500, 10, 2, 3)
+	_, withoutAccel := fly(t, settings, profile, 12, 20, 1, 0.1, false)
+	_, withAccel := fly(t, settings, profile, 12, 20, 1, 0.1, true)
+	checkValue(t, "vertical speed with accelerometer", withAccel, 3, 0.3)
+	if math.Abs(withoutAccel-3) <= math.Abs(withAccel-3) {
+		t.Errorf("vertical speed without accelerometer %v is closer to 3 m/s, than with it %v",
+			withoutAccel, withAccel)
+	}
+	// both converge later on
+	for _, useAccel := range []bool{false, true} {
+		h, vs := fly(t, settings, profile, 60, 20, 1, 0.1, useAccel)
+		want, _ := profile(60)
+		checkValue(t, "altitude", h, want, 1)
+		checkValue(t, "vertical speed", vs, 3, 0.3)
+	}
+}
+
+// Acceleration is not integrated any more, once accelerometer stream stops.
+func TestAltitudeEstimatorAccelTimeout(t *testing.T) {
+	est, err := bsbmp.NewAltitudeEstimator(bsbmp.AltitudeEstimatorSettings{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	start := time.Unix(0, 0)
+	est.AddPressure(start, pressureAt(100))
+	est.AddAcceleration(start, 2)
+	var h, vs float64
+	for i := 1; i <= 250; i++ {
+		h, vs = est.AddPressure(start.Add(time.Duration(i)*40*time.Millisecond), pressureAt(100))
+	}
+	checkValue(t, "altitude", h, 100, 0.1)
+	checkValue(t, "vertical speed", vs, 0, 0.05)
+}
+
+func TestAltitudeEstimatorSettings(t *testing.T) {
+	_, err := bsbmp.NewAltitudeEstimator(bsbmp.AltitudeEstimatorSettings{ProcessNoiseMS2: -1})
+	if err == nil {
+		t.Error("negative process noise accepted")
+	}
+	est, err := bsbmp.NewAltitudeEstimator(bsbmp.AltitudeEstimatorSettings{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if s := est.Settings(); s.QNHPa != bsbmp.SEA_LEVEL_PRESSURE_PA ||
+		s.AccelTimeout != bsbmp.DEFAULT_ACCEL_TIMEOUT {
+		t.Errorf("defaults are not applied: %+v", s)
+	}
+	if _, _, ok := est.Estimate(); ok {
+		t.Error("estimate is available before first reading")
+	}
+	est.AddMeasurement(&bsbmp.Measurement{Time: time.Now(), PressureMult10Pa: 1013250})
+	h, _, ok := est.Estimate()
+	if !ok {
+		t.Fatal("estimate is not available")
+	}
+	checkValue(t, "altitude", h, 0, 1e-6)
+}
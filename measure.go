@@ -0,0 +1,126 @@
+//--------------------------------------------------------------------------------------------------
+//
+// Copyright (c) 2018 Denis Dyakov
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy of this software and
+// associated documentation files (the "Software"), to deal in the Software without restriction,
+// including without limitation the rights to use, copy, modify, merge, publish, distribute, sublicense,
+// and/or sell copies of the Software, and to permit persons to whom the Software is furnished to do so,
+// subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all copies or substantial
+// portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR IMPLIED, INCLUDING
+// BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY, FITNESS FOR A PARTICULAR PURPOSE AND
+// NONINFRINGEMENT. IN NO EVENT SHALL THE AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM,
+// DAMAGES OR OTHER LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE SOFTWARE.
+//
+//--------------------------------------------------------------------------------------------------
+
+package bsbmp
+
+import (
+	"time"
+)
+
+// MeasureSettings define accuracy (oversampling) of each
+// channel used in single combined measurement.
+// Humidity is taken into account only by sensors measuring it.
+type MeasureSettings struct {
+	Temperature AccuracyMode
+	Pressure    AccuracyMode
+	Humidity    AccuracyMode
+}
+
+// RawData contains uncompensated ADC values
+// read out from sensor data registers.
+type RawData struct {
+	Temperature int32
+	Pressure    int32
+	Humidity    int32
+	Gas         int32
+	GasRange    uint8
+}
+
+// Measurement contains compensated values obtained
+// from sensor in single measurement cycle.
+type Measurement struct {
+	// Time when data registers were read out.
+	Time time.Time
+	// Divide by 100 to get float temperature value in celsius.
+	TemperatureMult100C int32
+	// Divide by 10 to get float pressure value in pascal.
+	PressureMult10Pa uint32
+	// HumiditySupported is true if sensor measure relative humidity.
+	HumiditySupported bool
+	// Divide by 1024 to get float humidity value in range [0..100]%.
+	HumidityMultQ2210 uint32
+	// GasSupported is true if sensor measure gas resistance.
+	GasSupported bool
+	// Gas resistance in Ohm, 0 if measurement is not valid.
+	GasResistance uint32
+	// GasValid is true if gas resistance measurement completed.
+	GasValid bool
+	// GasHeaterStable is true if hot plate reached target
+	// temperature during measurement.
+	GasHeaterStable bool
+	// GasIndex is heater profile step used for gas measurement.
+	GasIndex uint8
+	// MeasIndex is sequence number of measurement cycle
+	// (BME688 parallel mode).
+	MeasIndex uint8
+	// Uncompensated values.
+	Raw RawData
+}
+
+// TemperatureC returns temperature in C (celsius).
+func (v *Measurement) TemperatureC() float32 {
+	return float32(v.TemperatureMult100C) / 100
+}
+
+// PressurePa returns atmospheric pressure in Pa (Pascal).
+func (v *Measurement) PressurePa() float32 {
+	return float32(v.PressureMult10Pa) / 10
+}
+
+// PressureMmHg returns atmospheric pressure in mmHg (millimeter of mercury).
+func (v *Measurement) PressureMmHg() float32 {
+	// Amount of Pa in 1 mmHg
+	var mmHg float32 = 133.322
+	return float32(v.PressureMult10Pa) / 10 / mmHg
+}
+
+// HumidityRH returns relative humidity in %RH,
+// or 0 if humidity is not supported.
+func (v *Measurement) HumidityRH() float32 {
+	return float32(v.HumidityMultQ2210) / 1024
+}
+
+// Temperature returns temperature as typed value.
+func (v *Measurement) Temperature() Temperature {
+	return Temperature(float64(v.TemperatureMult100C) / 100)
+}
+
+// Pressure returns atmospheric pressure as typed value.
+func (v *Measurement) Pressure() Pressure {
+	return Pressure(float64(v.PressureMult10Pa) / 10)
+}
+
+// Humidity returns relative humidity as typed value,
+// or 0 if humidity is not supported.
+func (v *Measurement) Humidity() RelativeHumidity {
+	return RelativeHumidity(float64(v.HumidityMultQ2210) / 1024)
+}
+
+// Psychrometrics returns values derived from temperature, humidity and
+// pressure of measurement (dew point, absolute humidity, heat index and so on).
+// Returns ErrNotSupported, if humidity is not measured by sensor.
+func (v *Measurement) Psychrometrics() (*Psychrometrics, error) {
+	if !v.HumiditySupported {
+		return nil, ErrNotSupported
+	}
+	return CalcPsychrometrics(float64(v.TemperatureMult100C)/100,
+		float64(v.HumidityMultQ2210)/1024, float64(v.PressureMult10Pa)/10), nil
+}
@@ -0,0 +1,135 @@
+//--------------------------------------------------------------------------------------------------
+//
+// Copyright (c) 2018 Denis Dyakov
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy of this software and
+// associated documentation files (the "Software"), to deal in the Software without restriction,
+// including without limitation the rights to use, copy, modify, merge, publish, distribute, sublicense,
+// and/or sell copies of the Software, and to permit persons to whom the Software is furnished to do so,
+// subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all copies or substantial
+// portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR IMPLIED, INCLUDING
+// BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY, FITNESS FOR A PARTICULAR PURPOSE AND
+// NONINFRINGEMENT. IN NO EVENT SHALL THE AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM,
+// DAMAGES OR OTHER LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE SOFTWARE.
+//
+//--------------------------------------------------------------------------------------------------
+
+package bsbmp
+
+import (
+	"math"
+)
+
+// Psychrometrics contains values derived from air temperature,
+// relative humidity and pressure taken at the same instant.
+type Psychrometrics struct {
+	// Temperature at which air become saturated
+	// with water vapor over water, C (celsius).
+	DewPointC float64
+	// Temperature at which air become saturated
+	// with water vapor over ice, C (celsius).
+	FrostPointC float64
+	// Mass of water vapor in unit volume of air, g/m³.
+	AbsoluteHumidityGM3 float64
+	// Mass of water vapor per mass of dry air, g/kg.
+	MixingRatioGKg float64
+	// Mass of water vapor per mass of moist air, g/kg.
+	SpecificHumidityGKg float64
+	// Temperature of air cooled by water evaporation
+	// (psychrometer wet bulb), C (celsius).
+	WetBulbC float64
+	// Partial pressure of water vapor, Pa (pascal).
+	VaporPressurePa float64
+	// Saturation vapor pressure over water, Pa (pascal).
+	SaturationVaporPressurePa float64
+	// Difference between saturation and actual vapor pressure, Pa (pascal).
+	VaporPressureDeficitPa float64
+	// Specific enthalpy of moist air per mass of dry air, kJ/kg.
+	EnthalpyKJKg float64
+	// Apparent temperature felt by human body, C (celsius).
+	HeatIndexC float64
+}
+
+// Magnus formula coefficients over water and over ice (WMO, Sonntag 1990).
+const (
+	magnusE0     = 611.2 // Pa
+	magnusWaterA = 17.62
+	magnusWaterB = 243.12 // C
+	magnusIceA   = 22.46
+	magnusIceB   = 272.62 // C
+)
+
+// saturationVaporPressure calculates saturation vapor pressure
+// over water in Pa (pascal) by Magnus formula.
+func saturationVaporPressure(temperatureC float64) float64 {
+	return magnusE0 * math.Exp(magnusWaterA*temperatureC/(magnusWaterB+temperatureC))
+}
+
+// CalcPsychrometrics calculates psychrometric values from temperature
+// in C (celsius), relative humidity in %RH and pressure in Pa (pascal).
+func CalcPsychrometrics(temperatureC, humidityRH, pressurePa float64) *Psychrometrics {
+	t := temperatureC
+	// avoid logarithm of zero for absolutely dry air
+	rh := math.Max(math.Min(humidityRH, 100), 0.01)
+	v := &Psychrometrics{}
+	v.SaturationVaporPressurePa = saturationVaporPressure(t)
+	e := rh / 100 * v.SaturationVaporPressurePa
+	v.VaporPressurePa = e
+	v.VaporPressureDeficitPa = v.SaturationVaporPressurePa - e
+	g := math.Log(e / magnusE0)
+	v.DewPointC = magnusWaterB * g / (magnusWaterA - g)
+	v.FrostPointC = magnusIceB * g / (magnusIceA - g)
+	// Specific gas constant of water vapor 461.5 J/(kg*K)
+	v.AbsoluteHumidityGM3 = e / (461.5 * (t + 273.15)) * 1000
+	// Ratio of water vapor and dry air molar mass 0.622
+	w := 0.622 * e / (pressurePa - e)
+	v.MixingRatioGKg = w * 1000
+	v.SpecificHumidityGKg = 0.622 * e / (pressurePa - 0.378*e) * 1000
+	v.EnthalpyKJKg = 1.006*t + w*(2501+1.86*t)
+	v.WetBulbC = wetBulb(t, e, pressurePa, v.DewPointC)
+	v.HeatIndexC = heatIndex(t, rh)
+	return v
+}
+
+// wetBulb solves psychrometer equation e = Es(Tw) - A*P*(T-Tw)
+// for wet bulb temperature Tw by bisection between dew point
+// and dry bulb temperature.
+func wetBulb(temperatureC, vaporPressurePa, pressurePa, dewPointC float64) float64 {
+	lo, hi := dewPointC, temperatureC
+	for i := 0; i < 50; i++ {
+		tw := (lo + hi) / 2
+		// Psychrometer coefficient for ventilated psychrometer, 1/C
+		a := 6.6e-4 * (1 + 0.00115*tw)
+		if saturationVaporPressure(tw)-a*pressurePa*(temperatureC-tw) > vaporPressurePa {
+			hi = tw
+		} else {
+			lo = tw
+		}
+	}
+	return (lo + hi) / 2
+}
+
+// heatIndex calculates apparent temperature in C (celsius)
+// according to NOAA Rothfusz regression with adjustments.
+func heatIndex(temperatureC, humidityRH float64) float64 {
+	t := temperatureC*9/5 + 32
+	rh := humidityRH
+	// Steadman simple formula is used below 80 F
+	hi := 0.5 * (t + 61 + (t-68)*1.2 + rh*0.094)
+	if (hi+t)/2 >= 80 {
+		hi = -42.379 + 2.04901523*t + 10.14333127*rh - 0.22475541*t*rh -
+			0.00683783*t*t - 0.05481717*rh*rh + 0.00122874*t*t*rh +
+			0.00085282*t*rh*rh - 0.00000199*t*t*rh*rh
+		if rh < 13 && t >= 80 && t <= 112 {
+			hi -= (13 - rh) / 4 * math.Sqrt((17-math.Abs(t-95))/17)
+		} else if rh > 85 && t >= 80 && t <= 87 {
+			hi += (rh - 85) / 10 * (87 - t) / 5
+		}
+	}
+	return (hi - 32) * 5 / 9
+}
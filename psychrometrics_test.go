@@ -0,0 +1,90 @@
+//--------------------------------------------------------------------------------------------------
+//
+// Copyright (c) 2018 Denis Dyakov
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy of this software and
+// associated documentation files (the "Software"), to deal in the Software without restriction,
+// including without limitation the rights to use, copy, modify, merge, publish, distribute, sublicense,
+// and/or sell copies of the Software, and to permit persons to whom the Software is furnished to do so,
+// subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all copies or substantial
+// portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR IMPLIED, INCLUDING
+// BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY, FITNESS FOR A PARTICULAR PURPOSE AND
+// NONINFRINGEMENT. IN NO EVENT SHALL THE AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM,
+// DAMAGES OR OTHER LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE SOFTWARE.
+//
+//--------------------------------------------------------------------------------------------------
+
+package bsbmp_test
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/d2r2/go-bsbmp"
+	"github.com/d2r2/go-bsbmp/sim"
+)
+
+// Reference values are taken from psychrometric tables and charts
+// (sea level pressure) and NOAA heat index table.
+func TestCalcPsychrometrics(t *testing.T) {
+	for _, c := range []struct {
+		temperature, humidity float64
+		name                  string
+		get                   func(*bsbmp.Psychrometrics) float64
+		want, tolerance       float64
+	}{
+		{25, 60, "dew point", func(p *bsbmp.Psychrometrics) float64 { return p.DewPointC }, 16.7, 0.1},
+		{10, 80, "dew point", func(p *bsbmp.Psychrometrics) float64 { return p.DewPointC }, 6.7, 0.1},
+		{20, 100, "dew point", func(p *bsbmp.Psychrometrics) float64 { return p.DewPointC }, 20, 1e-9},
+		// frost point is above dew point below 0 °C
+		{-10, 80, "dew point", func(p *bsbmp.Psychrometrics) float64 { return p.DewPointC }, -12.8, 0.1},
+		{-10, 80, "frost point", func(p *bsbmp.Psychrometrics) float64 { return p.FrostPointC }, -11.4, 0.1},
+		{20, 50, "wet bulb", func(p *bsbmp.Psychrometrics) float64 { return p.WetBulbC }, 13.8, 0.2},
+		{25, 60, "wet bulb", func(p *bsbmp.Psychrometrics) float64 { return p.WetBulbC }, 19.5, 0.2},
+		{20, 100, "wet bulb", func(p *bsbmp.Psychrometrics) float64 { return p.WetBulbC }, 20, 0.01},
+		{25, 50, "enthalpy", func(p *bsbmp.Psychrometrics) float64 { return p.EnthalpyKJKg }, 50.3, 0.3},
+		{25, 50, "mixing ratio", func(p *bsbmp.Psychrometrics) float64 { return p.MixingRatioGKg }, 9.9, 0.1},
+		{20, 100, "absolute humidity", func(p *bsbmp.Psychrometrics) float64 { return p.AbsoluteHumidityGM3 }, 17.3, 0.1},
+		// 80 °F at 40%, 90 °F at 70%, 96 °F at 65%
+		{26.67, 40, "heat index", func(p *bsbmp.Psychrometrics) float64 { return p.HeatIndexC }, 26.7, 0.5},
+		{32.22, 70, "heat index", func(p *bsbmp.Psychrometrics) float64 { return p.HeatIndexC }, 41.1, 0.5},
+		{35.56, 65, "heat index", func(p *bsbmp.Psychrometrics) float64 { return p.HeatIndexC }, 49.4, 0.5},
+	} {
+		p := bsbmp.CalcPsychrometrics(c.temperature, c.humidity, 101325)
+		checkValue(t, fmt.Sprintf("%s at %v °C, %v%%", c.name, c.temperature, c.humidity),
+			c.get(p), c.want, c.tolerance)
+	}
+}
+
+func TestMeasurementPsychrometrics(t *testing.T) {
+	env := sim.Environment{Temperature: 25, Pressure: 101325, Humidity: 60}
+	for _, ts := range testSensors {
+		dev := ts.newDevice()
+		dev.SetEnvironment(env)
+		sensor, err := bsbmp.NewBMP(ts.sensorType, dev)
+		if err != nil {
+			t.Fatal(err)
+		}
+		m, err := sensor.Measure(bsbmp.MeasureSettings{})
+		if err != nil {
+			t.Fatalf("%v: %v", ts.sensorType, err)
+		}
+		p, err := m.Psychrometrics()
+		if !ts.humidity {
+			if !errors.Is(err, bsbmp.ErrNotSupported) {
+				t.Errorf("%v: err = %v, want %v", ts.sensorType, err, bsbmp.ErrNotSupported)
+			}
+			continue
+		}
+		if err != nil {
+			t.Fatalf("%v: %v", ts.sensorType, err)
+		}
+		checkValue(t, ts.sensorType.String()+" dew point", p.DewPointC, 16.7, 0.2)
+	}
+}
@@ -0,0 +1,371 @@
+//--------------------------------------------------------------------------------------------------
+//
+// Copyright (c) 2018 Denis Dyakov
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy of this software and
+// associated documentation files (the "Software"), to deal in the Software without restriction,
+// including without limitation the rights to use, copy, modify, merge, publish, distribute, sublicense,
+// and/or sell copies of the Software, and to permit persons to whom the Software is furnished to do so,
+// subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all copies or substantial
+// portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR IMPLIED, INCLUDING
+// BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY, FITNESS FOR A PARTICULAR PURPOSE AND
+// NONINFRINGEMENT. IN NO EVENT SHALL THE AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM,
+// DAMAGES OR OTHER LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE SOFTWARE.
+//
+//--------------------------------------------------------------------------------------------------
+
+package sim
+
+import "math"
+
+// BME680 registers used by emulation.
+const (
+	bme680ResHeatValReg   = 0x00
+	bme680ResHeatRangeReg = 0x02
+	bme680RangeSwErrReg   = 0x04
+	bme680MeasStatusReg   = 0x1D
+	bme680PressReg        = 0x1F
+	bme680TempReg         = 0x22
+	bme680HumReg          = 0x25
+	bme680GasReg          = 0x2A
+	bme688GasReg          = 0x2C
+	bme688FieldSize       = 0x11
+	bme688FieldCount      = 3
+	bme680ResHeat0Reg     = 0x5A
+	bme680GasWait0Reg     = 0x64
+	bme680CtrlGas1Reg     = 0x71
+	bme680CtrlHumReg      = 0x72
+	bme680CtrlMeasReg     = 0x74
+	bme680IDReg           = 0xD0
+	bme680ResetReg        = 0xE0
+	bme680VariantReg      = 0xF0
+	bme680CoefStart1      = 0x89
+	bme680CoefStart2      = 0xE1
+)
+
+// CoeffBME680 keeps calibration coefficients of emulated BME680.
+type CoeffBME680 struct {
+	T1                  uint16
+	T2                  int16
+	T3                  int8
+	P1                  uint16
+	P2                  int16
+	P3                  int8
+	P4, P5              int16
+	P6, P7              int8
+	P8, P9              int16
+	P10                 uint8
+	H1, H2              uint16
+	H3, H4, H5          int8
+	H6                  uint8
+	H7                  int8
+	GH1                 int8
+	GH2                 int16
+	GH3                 int8
+	ResHeatRange        uint8
+	ResHeatVal          int8
+	RangeSwitchingError int8
+}
+
+// DefaultCoeffBME680 contains calibration values
+// typical for BME680 sensor.
+var DefaultCoeffBME680 = CoeffBME680{
+	T1: 26000, T2: 26000, T3: 3,
+	P1: 36000, P2: -10000, P3: 88, P4: 6800, P5: -70,
+	P6: 30, P7: 50, P8: -4000, P9: -3000, P10: 30,
+	H1: 760, H2: 1000, H3: 0, H4: 45, H5: 20, H6: 120, H7: -100,
+	GH1: -30, GH2: -12000, GH3: 18,
+	ResHeatRange: 1, ResHeatVal: 40, RangeSwitchingError: 0,
+}
+
+// Gas resistance calculation constants from datasheet.
+var bme680GasRangeConst1 = [16]float64{1, 1, 1, 1, 1, 0.99, 1, 0.992, 1, 1,
+	0.998, 0.995, 1, 0.99, 1, 1}
+var bme680GasRangeConst2 = [16]float64{8000000, 4000000, 2000000, 1000000,
+	499500.4995, 248262.1648, 125000, 63004.03226, 31281.28128, 15625,
+	7812.5, 3906.25, 1953.125, 976.5625, 488.28125, 244.140625}
+
+type chipBME680 struct {
+	coeff CoeffBME680
+	// Variant ID: 0 for BME680, 1 for BME688
+	variant byte
+	// Parallel mode state: heater profile step, cycles
+	// spent at current step and measurement counter.
+	step       byte
+	stepCycles int
+	cycle      int
+}
+
+// NewBME680 creates emulated BME680 sensor.
+func NewBME680() *Device {
+	return newDevice("BME680", &chipBME680{coeff: DefaultCoeffBME680})
+}
+
+// NewBME688 creates emulated BME688 sensor. Beside of forced mode,
+// it supports parallel mode: each Sample call emulates one measurement
+// cycle, going through heater profile steps.
+func NewBME688() *Device {
+	return newDevice("BME688", &chipBME680{coeff: DefaultCoeffBME680, variant: 0x01})
+}
+
+// runGas return ctrl_gas_1 run_gas bit, which differ by variant.
+func (v *chipBME680) runGas() byte {
+	if v.variant == 0x01 {
+		return 0x20
+	}
+	return 0x10
+}
+
+func (v *chipBME680) reset(d *Device) {
+	d.regs = [256]byte{}
+	d.regs[bme680IDReg] = 0x61
+	d.regs[bme680VariantReg] = v.variant
+	v.step, v.stepCycles, v.cycle = 0, 0, 0
+	c := &v.coeff
+	d.regs[bme680ResHeatValReg] = byte(c.ResHeatVal)
+	d.regs[bme680ResHeatRangeReg] = (c.ResHeatRange & 0x03) << 4
+	d.regs[bme680RangeSwErrReg] = byte(c.RangeSwitchingError) << 4
+	// coefficients block #1 at 0x89..0xA1
+	d.putU16LE(0x8A, uint16(c.T2))
+	d.regs[0x8C] = byte(c.T3)
+	d.putU16LE(0x8E, c.P1)
+	d.putU16LE(0x90, uint16(c.P2))
+	d.regs[0x92] = byte(c.P3)
+	d.putU16LE(0x94, uint16(c.P4))
+	d.putU16LE(0x96, uint16(c.P5))
+	d.regs[0x98] = byte(c.P7)
+	d.regs[0x99] = byte(c.P6)
+	d.putU16LE(0x9C, uint16(c.P8))
+	d.putU16LE(0x9E, uint16(c.P9))
+	d.regs[0xA0] = c.P10
+	// coefficients block #2 at 0xE1..0xF0
+	d.regs[0xE1] = byte(c.H2 >> 4)
+	d.regs[0xE2] = byte(c.H2<<4) | byte(c.H1&0x0F)
+	d.regs[0xE3] = byte(c.H1 >> 4)
+	d.regs[0xE4] = byte(c.H3)
+	d.regs[0xE5] = byte(c.H4)
+	d.regs[0xE6] = byte(c.H5)
+	d.regs[0xE7] = c.H6
+	d.regs[0xE8] = byte(c.H7)
+	d.putU16LE(0xE9, c.T1)
+	d.putU16LE(0xEB, uint16(c.GH2))
+	d.regs[0xED] = byte(c.GH1)
+	d.regs[0xEE] = byte(c.GH3)
+	// data registers contain "skipped" values after reset
+	d.putU20(bme680PressReg, 0x80000)
+	d.putU20(bme680TempReg, 0x80000)
+	d.putU16BE(bme680HumReg, 0x8000)
+}
+
+func (v *chipBME680) read(d *Device, reg byte) byte {
+	if reg == bme680MeasStatusReg {
+		if d.pollBusy() {
+			// measuring and gas_measuring bits
+			return d.regs[reg]&0x0F | 0x20 | 0x40&(d.regs[bme680CtrlGas1Reg]<<2)
+		}
+		return d.regs[reg]
+	}
+	return d.regs[reg]
+}
+
+func (v *chipBME680) write(d *Device, reg byte, value byte) {
+	switch reg {
+	case bme680ResetReg:
+		if value == 0xB6 {
+			v.reset(d)
+		}
+	case bme680CtrlMeasReg:
+		if value&0x03 == 0x01 {
+			// forced mode returns to sleep mode after measurement
+			v.measure(d, value)
+			value &^= 0x03
+			d.startMeasurement()
+		} else if value&0x03 == 0x02 && v.variant == 0x01 {
+			// parallel mode starts from the first heater step
+			// with no new data in fields
+			v.step, v.stepCycles = 0, 0
+			for i := 0; i < bme688FieldCount; i++ {
+				d.regs[bme680MeasStatusReg+bme688FieldSize*i] &^= 0x80
+			}
+		}
+		d.regs[reg] = value
+	default:
+		d.regs[reg] = value
+	}
+}
+
+// measure fills data registers with values converted from environment.
+// Channels with oversampling set to 0 are skipped.
+func (v *chipBME680) measure(d *Device, ctrlMeas byte) {
+	v.measureField(d, 0, ctrlMeas, d.regs[bme680CtrlGas1Reg]&0x0F)
+}
+
+// sample emulates one measurement cycle of parallel mode (BME688 only),
+// storing data in the next field. Heater profile step advance after
+// amount of cycles specified by gas_wait_x multiplier.
+func (v *chipBME680) sample(d *Device) {
+	ctrlMeas := d.regs[bme680CtrlMeasReg]
+	if v.variant != 0x01 || ctrlMeas&0x03 != 0x02 {
+		return
+	}
+	v.measureField(d, v.cycle%bme688FieldCount, ctrlMeas, v.step)
+	v.cycle++
+	v.stepCycles++
+	if v.stepCycles >= int(d.regs[bme680GasWait0Reg+int(v.step)]) {
+		v.stepCycles = 0
+		v.step++
+		if v.step >= d.regs[bme680CtrlGas1Reg]&0x0F {
+			v.step = 0
+		}
+	}
+}
+
+// measureField fills data field registers with values converted from
+// environment, using heater set-point index for gas measurement.
+func (v *chipBME680) measureField(d *Device, field int, ctrlMeas byte, index byte) {
+	base := byte(bme680MeasStatusReg + bme688FieldSize*field)
+	osrsT := (ctrlMeas >> 5) & 0x07
+	osrsP := (ctrlMeas >> 2) & 0x07
+	osrsH := d.regs[bme680CtrlHumReg] & 0x07
+	ut := v.rawTemperature(d.env.Temperature)
+	tFine := v.tFine(float64(ut))
+	if osrsT != 0 {
+		d.putU20(base+bme680TempReg-bme680MeasStatusReg, ut)
+	} else {
+		d.putU20(base+bme680TempReg-bme680MeasStatusReg, 0x80000)
+	}
+	if osrsP != 0 {
+		d.putU20(base+bme680PressReg-bme680MeasStatusReg, v.rawPressure(tFine, d.env.Pressure))
+	} else {
+		d.putU20(base+bme680PressReg-bme680MeasStatusReg, 0x80000)
+	}
+	if osrsH != 0 {
+		d.putU16BE(base+bme680HumReg-bme680MeasStatusReg, uint16(v.rawHumidity(tFine, d.env.Humidity)))
+	} else {
+		d.putU16BE(base+bme680HumReg-bme680MeasStatusReg, 0x8000)
+	}
+	status := byte(0x80) | index
+	var gasLSB byte
+	var gasMSB byte
+	if d.regs[bme680CtrlGas1Reg]&v.runGas() != 0 {
+		var adc int32
+		var gasRange byte
+		if v.variant == 0x01 {
+			adc, gasRange = v.rawGasHigh(d.env.GasResistance)
+		} else {
+			adc, gasRange = v.rawGas(d.env.GasResistance)
+		}
+		gasMSB = byte(adc >> 2)
+		// gas_valid flag
+		gasLSB = byte(adc<<6) | 0x20 | gasRange
+		if d.regs[bme680ResHeat0Reg+int(index)] != 0 && d.regs[bme680GasWait0Reg+int(index)] != 0 {
+			// heat_stab flag
+			gasLSB |= 0x10
+		}
+	}
+	gasReg := base + bme680GasReg - bme680MeasStatusReg
+	if v.variant == 0x01 {
+		gasReg = base + bme688GasReg - bme680MeasStatusReg
+		// sub_meas_index
+		d.regs[base+1] = byte(v.cycle)
+	}
+	d.regs[gasReg] = gasMSB
+	d.regs[gasReg+1] = gasLSB
+	d.regs[base] = status
+}
+
+// tFine calculate fine resolution temperature value
+// according to datasheet floating point formula.
+func (v *chipBME680) tFine(ut float64) float64 {
+	c := &v.coeff
+	var1 := (ut/16384 - float64(c.T1)/1024) * float64(c.T2)
+	var2 := (ut/131072 - float64(c.T1)/8192) *
+		(ut/131072 - float64(c.T1)/8192) * float64(c.T3) * 16
+	return var1 + var2
+}
+
+// pressure converts raw value to pressure in Pa (pascal)
+// according to datasheet floating point formula.
+func (v *chipBME680) pressure(tFine float64, up float64) float64 {
+	c := &v.coeff
+	var1 := tFine/2 - 64000
+	var2 := var1 * var1 * float64(c.P6) / 131072
+	var2 = var2 + var1*float64(c.P5)*2
+	var2 = var2/4 + float64(c.P4)*65536
+	var1 = (float64(c.P3)*var1*var1/16384 + float64(c.P2)*var1) / 524288
+	var1 = (1 + var1/32768) * float64(c.P1)
+	p := 1048576 - up
+	p = (p - var2/4096) * 6250 / var1
+	var1 = float64(c.P9) * p * p / 2147483648
+	var2 = p * float64(c.P8) / 32768
+	var3 := (p / 256) * (p / 256) * (p / 256) * float64(c.P10) / 131072
+	return p + (var1+var2+var3+float64(c.P7)*128)/16
+}
+
+// humidity converts raw value to relative humidity in %RH
+// according to datasheet floating point formula.
+func (v *chipBME680) humidity(tFine float64, uh float64) float64 {
+	c := &v.coeff
+	t := tFine / 5120
+	var1 := uh - (float64(c.H1)*16 + float64(c.H3)/2*t)
+	var2 := var1 * (float64(c.H2) / 262144 * (1 + float64(c.H4)/16384*t +
+		float64(c.H5)/1048576*t*t))
+	var3 := float64(c.H6) / 16384
+	var4 := float64(c.H7) / 2097152
+	return var2 + (var3+var4*t)*var2*var2
+}
+
+func (v *chipBME680) rawTemperature(t float64) int32 {
+	f := func(ut float64) float64 {
+		return v.tFine(ut) / 5120
+	}
+	return invert(f, t, 0, 1<<20-1)
+}
+
+func (v *chipBME680) rawPressure(tFine float64, p float64) int32 {
+	f := func(up float64) float64 {
+		return v.pressure(tFine, up)
+	}
+	return invert(f, p, 0, 1<<20-1)
+}
+
+func (v *chipBME680) rawHumidity(tFine float64, h float64) int32 {
+	f := func(uh float64) float64 {
+		return v.humidity(tFine, uh)
+	}
+	return invert(f, math.Max(0, math.Min(100, h)), 0, math.MaxUint16)
+}
+
+// rawGas converts gas resistance to 10-bit ADC value and gas range,
+// solving datasheet floating point formula in reverse direction.
+func (v *chipBME680) rawGas(r float64) (int32, byte) {
+	rse := float64(v.coeff.RangeSwitchingError)
+	for gasRange := 0; gasRange < 16; gasRange++ {
+		var1 := (1340 + 5*rse) * bme680GasRangeConst1[gasRange]
+		// r = var1 * const2 / (adc - 512 + var1)
+		adc := var1*bme680GasRangeConst2[gasRange]/r - var1 + 512
+		if adc < 1023.5 {
+			return int32(math.Max(0, math.Round(adc))), byte(gasRange)
+		}
+	}
+	return 1023, 15
+}
+
+// rawGasHigh converts gas resistance to 10-bit ADC value and gas range
+// for high gas variant (BME688), solving Bosch Sensortec API integer
+// formula in reverse direction.
+func (v *chipBME680) rawGasHigh(r float64) (int32, byte) {
+	for gasRange := 0; gasRange < 16; gasRange++ {
+		// r = 1000000 * (262144 >> range) / (4096 + (adc - 512) * 3)
+		adc := (1000000*float64(int32(262144)>>uint(gasRange))/r-4096)/3 + 512
+		if adc < 1023.5 {
+			return int32(math.Max(0, math.Round(adc))), byte(gasRange)
+		}
+	}
+	return 1023, 15
+}
@@ -0,0 +1,176 @@
+//--------------------------------------------------------------------------------------------------
+//
+// Copyright (c) 2018 Denis Dyakov
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy of this software and
+// associated documentation files (the "Software"), to deal in the Software without restriction,
+// including without limitation the rights to use, copy, modify, merge, publish, distribute, sublicense,
+// and/or sell copies of the Software, and to permit persons to whom the Software is furnished to do so,
+// subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all copies or substantial
+// portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR IMPLIED, INCLUDING
+// BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY, FITNESS FOR A PARTICULAR PURPOSE AND
+// NONINFRINGEMENT. IN NO EVENT SHALL THE AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM,
+// DAMAGES OR OTHER LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE SOFTWARE.
+//
+//--------------------------------------------------------------------------------------------------
+
+package sim
+
+import "math"
+
+// BMP180 registers used by emulation.
+const (
+	bmp180IDReg     = 0xD0
+	bmp180ResetReg  = 0xE0
+	bmp180CtrlReg   = 0xF4
+	bmp180OutReg    = 0xF6
+	bmp180CoefStart = 0xAA
+)
+
+// CoeffBMP180 keeps calibration coefficients of emulated BMP180.
+type CoeffBMP180 struct {
+	AC1, AC2, AC3 int16
+	AC4, AC5, AC6 uint16
+	B1, B2        int16
+	MB, MC, MD    int16
+}
+
+// DefaultCoeffBMP180 contains calibration values taken
+// from calculation example of BMP180 datasheet.
+var DefaultCoeffBMP180 = CoeffBMP180{
+	AC1: 408, AC2: -72, AC3: -14383,
+	AC4: 32741, AC5: 32757, AC6: 23153,
+	B1: 6190, B2: 4,
+	MB: -32768, MC: -8711, MD: 2868,
+}
+
+type chipBMP180 struct {
+	coeff CoeffBMP180
+	// BMP085 doesn't report SCO flag, but signal
+	// end of conversion by EOC pin instead.
+	eoc bool
+}
+
+// NewBMP180 creates emulated BMP180 sensor.
+func NewBMP180() *Device {
+	return newDevice("BMP180", &chipBMP180{coeff: DefaultCoeffBMP180})
+}
+
+// NewBMP085 creates emulated BMP085 sensor. Conversion end is
+// signaled on EOC pin, returned by InterruptPin.
+func NewBMP085() *Device {
+	return newDevice("BMP085", &chipBMP180{coeff: DefaultCoeffBMP180, eoc: true})
+}
+
+func (v *chipBMP180) reset(d *Device) {
+	d.regs = [256]byte{}
+	d.regs[bmp180IDReg] = 0x55
+	c := &v.coeff
+	for i, w := range []uint16{uint16(c.AC1), uint16(c.AC2), uint16(c.AC3),
+		c.AC4, c.AC5, c.AC6, uint16(c.B1), uint16(c.B2),
+		uint16(c.MB), uint16(c.MC), uint16(c.MD)} {
+		d.putU16BE(bmp180CoefStart+byte(i*2), w)
+	}
+}
+
+func (v *chipBMP180) read(d *Device, reg byte) byte {
+	if reg == bmp180CtrlReg && !v.eoc {
+		// SCO (start of conversion) bit stays set until conversion completes
+		if d.pollBusy() {
+			return d.regs[reg] | 0x20
+		}
+		return d.regs[reg] &^ 0x20
+	}
+	return d.regs[reg]
+}
+
+func (v *chipBMP180) write(d *Device, reg byte, value byte) {
+	switch reg {
+	case bmp180ResetReg:
+		if value == 0xB6 {
+			v.reset(d)
+		}
+	case bmp180CtrlReg:
+		d.regs[reg] = value
+		switch value & 0x3F {
+		case 0x2E:
+			// temperature measurement
+			ut := v.rawTemperature(d.env.Temperature)
+			d.putU16BE(bmp180OutReg, uint16(ut))
+			d.regs[bmp180OutReg+2] = 0
+			v.startConversion(d)
+		case 0x34:
+			// pressure measurement with oversampling
+			oss := uint(value >> 6)
+			ut := v.rawTemperature(d.env.Temperature)
+			up := v.rawPressure(ut, oss, d.env.Pressure)
+			up <<= 8 - oss
+			d.regs[bmp180OutReg] = byte(up >> 16)
+			d.regs[bmp180OutReg+1] = byte(up >> 8)
+			d.regs[bmp180OutReg+2] = byte(up)
+			v.startConversion(d)
+		}
+	default:
+		d.regs[reg] = value
+	}
+}
+
+// startConversion emulates conversion run: either SCO flag
+// reported busy for a while, or EOC pin edge after conversion.
+func (v *chipBMP180) startConversion(d *Device) {
+	if v.eoc {
+		d.interrupt()
+		return
+	}
+	d.startMeasurement()
+}
+
+// b5 calculate intermediate temperature value B5
+// according to datasheet algorithm.
+func (v *chipBMP180) b5(ut float64) float64 {
+	c := &v.coeff
+	x1 := (ut - float64(c.AC6)) * float64(c.AC5) / 32768
+	x2 := float64(c.MC) * 2048 / (x1 + float64(c.MD))
+	return x1 + x2
+}
+
+// temperature converts raw value to temperature in C (celsius).
+func (v *chipBMP180) temperature(ut float64) float64 {
+	return (v.b5(ut) + 8) / 16 / 10
+}
+
+// pressure converts raw values to pressure in Pa (pascal).
+func (v *chipBMP180) pressure(ut float64, oss uint, up float64) float64 {
+	c := &v.coeff
+	b6 := v.b5(ut) - 4000
+	x1 := float64(c.B2) * (b6 * b6 / 4096) / 2048
+	x2 := float64(c.AC2) * b6 / 2048
+	x3 := x1 + x2
+	b3 := ((float64(c.AC1)*4+x3)*float64(int(1)<<oss) + 2) / 4
+	x1 = float64(c.AC3) * b6 / 8192
+	x2 = float64(c.B1) * (b6 * b6 / 4096) / 65536
+	x3 = (x1 + x2 + 2) / 4
+	b4 := float64(c.AC4) * (x3 + 32768) / 32768
+	b7 := (up - b3) * (50000 / float64(int(1)<<oss))
+	p := b7 * 2 / b4
+	x1 = (p / 256) * (p / 256)
+	x1 = x1 * 3038 / 65536
+	x2 = -7357 * p / 65536
+	return p + (x1+x2+3791)/16
+}
+
+func (v *chipBMP180) rawTemperature(t float64) int32 {
+	return invert(v.temperature, t, 0, math.MaxUint16)
+}
+
+func (v *chipBMP180) rawPressure(ut int32, oss uint, p float64) int32 {
+	f := func(up float64) float64 {
+		return v.pressure(float64(ut), oss, up)
+	}
+	return invert(f, p, 0, int32(1)<<(16+oss)-1)
+}
@@ -0,0 +1,235 @@
+//--------------------------------------------------------------------------------------------------
+//
+// Copyright (c) 2018 Denis Dyakov
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy of this software and
+// associated documentation files (the "Software"), to deal in the Software without restriction,
+// including without limitation the rights to use, copy, modify, merge, publish, distribute, sublicense,
+// and/or sell copies of the Software, and to permit persons to whom the Software is furnished to do so,
+// subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all copies or substantial
+// portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR IMPLIED, INCLUDING
+// BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY, FITNESS FOR A PARTICULAR PURPOSE AND
+// NONINFRINGEMENT. IN NO EVENT SHALL THE AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM,
+// DAMAGES OR OTHER LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE SOFTWARE.
+//
+//--------------------------------------------------------------------------------------------------
+
+package sim
+
+import "math"
+
+// BMP280 and BME280 registers used by emulation.
+const (
+	bmx280IDReg       = 0xD0
+	bmx280ResetReg    = 0xE0
+	bmx280CtrlHumReg  = 0xF2
+	bmx280StatusReg   = 0xF3
+	bmx280CtrlMeasReg = 0xF4
+	bmx280PressReg    = 0xF7
+	bmx280TempReg     = 0xFA
+	bmx280HumReg      = 0xFD
+	bmx280CoefStart1  = 0x88
+	bmx280CoefStart2  = 0xA1
+	bmx280CoefStart3  = 0xE1
+)
+
+// CoeffBMP280 keeps calibration coefficients of emulated BMP280/BME280.
+// Humidity coefficients are used by BME280 only.
+type CoeffBMP280 struct {
+	T1                 uint16
+	T2, T3             int16
+	P1                 uint16
+	P2, P3, P4, P5, P6 int16
+	P7, P8, P9         int16
+	H1                 uint8
+	H2                 int16
+	H3                 uint8
+	H4, H5             int16
+	H6                 int8
+}
+
+// DefaultCoeffBMP280 contains temperature and pressure calibration values
+// taken from calculation example of BMP280 datasheet, and humidity
+// calibration values typical for BME280.
+var DefaultCoeffBMP280 = CoeffBMP280{
+	T1: 27504, T2: 26435, T3: -1000,
+	P1: 36477, P2: -10685, P3: 3024, P4: 2855, P5: 140, P6: -7,
+	P7: 15500, P8: -14600, P9: 6000,
+	H1: 75, H2: 362, H3: 0, H4: 313, H5: 50, H6: 30,
+}
+
+type chipBMX280 struct {
+	coeff       CoeffBMP280
+	id          byte
+	hasHumidity bool
+	// humidity oversampling becomes effective
+	// only after write to ctrl_meas register
+	osrsH byte
+}
+
+// NewBMP280 creates emulated BMP280 sensor.
+func NewBMP280() *Device {
+	return newDevice("BMP280", &chipBMX280{coeff: DefaultCoeffBMP280, id: 0x58})
+}
+
+// NewBME280 creates emulated BME280 sensor.
+func NewBME280() *Device {
+	return newDevice("BME280", &chipBMX280{coeff: DefaultCoeffBMP280, id: 0x60, hasHumidity: true})
+}
+
+func (v *chipBMX280) reset(d *Device) {
+	d.regs = [256]byte{}
+	v.osrsH = 0
+	d.regs[bmx280IDReg] = v.id
+	c := &v.coeff
+	for i, w := range []uint16{c.T1, uint16(c.T2), uint16(c.T3),
+		c.P1, uint16(c.P2), uint16(c.P3), uint16(c.P4), uint16(c.P5),
+		uint16(c.P6), uint16(c.P7), uint16(c.P8), uint16(c.P9)} {
+		d.putU16LE(bmx280CoefStart1+byte(i*2), w)
+	}
+	// data registers contain "skipped" values after reset
+	d.putU20(bmx280PressReg, 0x80000)
+	d.putU20(bmx280TempReg, 0x80000)
+	if v.hasHumidity {
+		d.regs[bmx280CoefStart2] = c.H1
+		d.putU16LE(bmx280CoefStart3, uint16(c.H2))
+		d.regs[bmx280CoefStart3+2] = c.H3
+		d.regs[bmx280CoefStart3+3] = byte(c.H4 >> 4)
+		d.regs[bmx280CoefStart3+4] = byte(c.H4&0x0F) | byte(c.H5&0x0F)<<4
+		d.regs[bmx280CoefStart3+5] = byte(c.H5 >> 4)
+		d.regs[bmx280CoefStart3+6] = byte(c.H6)
+		d.putU16BE(bmx280HumReg, 0x8000)
+	}
+}
+
+func (v *chipBMX280) read(d *Device, reg byte) byte {
+	if reg == bmx280StatusReg {
+		if d.pollBusy() {
+			// measuring bit
+			return 0x08
+		}
+		return 0
+	}
+	if d.regs[bmx280CtrlMeasReg]&0x03 == 0x03 && reg >= bmx280PressReg {
+		// normal mode: data registers always keep latest sample
+		v.measure(d, d.regs[bmx280CtrlMeasReg])
+	}
+	return d.regs[reg]
+}
+
+func (v *chipBMX280) write(d *Device, reg byte, value byte) {
+	switch reg {
+	case bmx280ResetReg:
+		if value == 0xB6 {
+			v.reset(d)
+		}
+	case bmx280CtrlHumReg:
+		if v.hasHumidity {
+			d.regs[reg] = value & 0x07
+		}
+	case bmx280CtrlMeasReg:
+		v.osrsH = d.regs[bmx280CtrlHumReg] & 0x07
+		mode := value & 0x03
+		if mode != 0 {
+			v.measure(d, value)
+		}
+		if mode == 1 || mode == 2 {
+			// forced mode returns to sleep mode after measurement
+			value &^= 0x03
+			d.startMeasurement()
+		}
+		d.regs[reg] = value
+	default:
+		d.regs[reg] = value
+	}
+}
+
+// measure fills data registers with values converted from environment.
+// Channels with oversampling set to 0 are skipped.
+func (v *chipBMX280) measure(d *Device, ctrlMeas byte) {
+	osrsT := (ctrlMeas >> 5) & 0x07
+	osrsP := (ctrlMeas >> 2) & 0x07
+	ut := v.rawTemperature(d.env.Temperature)
+	tFine := v.tFine(float64(ut))
+	if osrsT != 0 {
+		d.putU20(bmx280TempReg, ut)
+	} else {
+		d.putU20(bmx280TempReg, 0x80000)
+	}
+	if osrsP != 0 {
+		d.putU20(bmx280PressReg, v.rawPressure(tFine, d.env.Pressure))
+	} else {
+		d.putU20(bmx280PressReg, 0x80000)
+	}
+	if v.hasHumidity {
+		if v.osrsH != 0 {
+			d.putU16BE(bmx280HumReg, uint16(v.rawHumidity(tFine, d.env.Humidity)))
+		} else {
+			d.putU16BE(bmx280HumReg, 0x8000)
+		}
+	}
+}
+
+// tFine calculate fine resolution temperature value
+// according to datasheet floating point formula.
+func (v *chipBMX280) tFine(ut float64) float64 {
+	c := &v.coeff
+	var1 := (ut/16384 - float64(c.T1)/1024) * float64(c.T2)
+	var2 := (ut/131072 - float64(c.T1)/8192) *
+		(ut/131072 - float64(c.T1)/8192) * float64(c.T3)
+	return var1 + var2
+}
+
+// pressure converts raw value to pressure in Pa (pascal)
+// according to datasheet floating point formula.
+func (v *chipBMX280) pressure(tFine float64, up float64) float64 {
+	c := &v.coeff
+	var1 := tFine/2 - 64000
+	var2 := var1 * var1 * float64(c.P6) / 32768
+	var2 = var2 + var1*float64(c.P5)*2
+	var2 = var2/4 + float64(c.P4)*65536
+	var1 = (float64(c.P3)*var1*var1/524288 + float64(c.P2)*var1) / 524288
+	var1 = (1 + var1/32768) * float64(c.P1)
+	p := 1048576 - up
+	p = (p - var2/4096) * 6250 / var1
+	var1 = float64(c.P9) * p * p / 2147483648
+	var2 = p * float64(c.P8) / 32768
+	return p + (var1+var2+float64(c.P7))/16
+}
+
+// humidity converts raw value to relative humidity in %RH
+// according to datasheet floating point formula.
+func (v *chipBMX280) humidity(tFine float64, uh float64) float64 {
+	c := &v.coeff
+	h := tFine - 76800
+	h = (uh - (float64(c.H4)*64 + float64(c.H5)/16384*h)) *
+		(float64(c.H2) / 65536 * (1 + float64(c.H6)/67108864*h*
+			(1+float64(c.H3)/67108864*h)))
+	return h * (1 - float64(c.H1)*h/524288)
+}
+
+func (v *chipBMX280) rawTemperature(t float64) int32 {
+	f := func(ut float64) float64 {
+		return v.tFine(ut) / 5120
+	}
+	return invert(f, t, 0, 1<<20-1)
+}
+
+func (v *chipBMX280) rawPressure(tFine float64, p float64) int32 {
+	f := func(up float64) float64 {
+		return v.pressure(tFine, up)
+	}
+	return invert(f, p, 0, 1<<20-1)
+}
+
+func (v *chipBMX280) rawHumidity(tFine float64, h float64) int32 {
+	f := func(uh float64) float64 {
+		return v.humidity(tFine, uh)
+	}
+	return invert(f, math.Max(0, math.Min(100, h)), 0, math.MaxUint16)
+}
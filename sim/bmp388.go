@@ -0,0 +1,371 @@
+//--------------------------------------------------------------------------------------------------
+//
+// Copyright (c) 2018 Denis Dyakov
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy of this software and
+// associated documentation files (the "Software"), to deal in the Software without restriction,
+// including without limitation the rights to use, copy, modify, merge, publish, distribute, sublicense,
+// and/or sell copies of the Software, and to permit persons to whom the Software is furnished to do so,
+// subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all copies or substantial
+// portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR IMPLIED, INCLUDING
+// BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY, FITNESS FOR A PARTICULAR PURPOSE AND
+// NONINFRINGEMENT. IN NO EVENT SHALL THE AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM,
+// DAMAGES OR OTHER LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE SOFTWARE.
+//
+//--------------------------------------------------------------------------------------------------
+
+package sim
+
+import "math"
+
+// BMP388 registers used by emulation (BMP390 and BMP384 share them).
+const (
+	bmp388IDReg      = 0x00
+	bmp388StatusReg  = 0x03
+	bmp388PressReg   = 0x04
+	bmp388TempReg    = 0x07
+	bmp388IntStatReg = 0x11
+	bmp388FIFOLenReg = 0x12
+	bmp388FIFOData   = 0x14
+	bmp388FIFOWtm    = 0x15
+	bmp388FIFOConf1  = 0x17
+	bmp388FIFOConf2  = 0x18
+	bmp388IntCtrlReg = 0x19
+	bmp388PwrCtrlReg = 0x1B
+	bmp388OSRReg     = 0x1C
+	bmp388ODRReg     = 0x1D
+	bmp388ConfigReg  = 0x1F
+	bmp388CmdReg     = 0x7E
+	bmp388CoefStart  = 0x31
+	bmp388FIFOSize   = 512
+)
+
+// CoeffBMP388 keeps calibration coefficients (NVM values) of emulated BMP388.
+type CoeffBMP388 struct {
+	T1, T2   uint16
+	T3       int8
+	P1, P2   int16
+	P3, P4   int8
+	P5, P6   uint16
+	P7, P8   int8
+	P9       int16
+	P10, P11 int8
+}
+
+// DefaultCoeffBMP388 contains calibration values giving
+// raw readings in the range typical for BMP388.
+var DefaultCoeffBMP388 = CoeffBMP388{
+	T1: 27000, T2: 19000, T3: -7,
+	P1: -1000, P2: 16000, P3: 5, P4: 0,
+	P5: 24000, P6: 200, P7: 3, P8: -5,
+	P9: 2000, P10: 10, P11: -10,
+}
+
+type chipBMP388 struct {
+	coeff CoeffBMP388
+	id    byte
+	// data ready status bits
+	drdy byte
+	// interrupt status bits, cleared on read
+	intStatus byte
+	// latched INT output is asserted until status read
+	intLatched bool
+	// FIFO buffer content
+	fifo []byte
+	// Frames sent after the last stored frame
+	tail []byte
+	// Sensortime frame already sent after the last stored frame
+	timeSent bool
+	// Samples taken since last frame stored to FIFO
+	skipped int
+	// Sensortime counter, 25.6 kHz
+	sensorTime uint32
+}
+
+// NewBMP388 creates emulated BMP388 sensor.
+func NewBMP388() *Device {
+	return newDevice("BMP388", &chipBMP388{coeff: DefaultCoeffBMP388, id: 0x50})
+}
+
+// NewBMP390 creates emulated BMP390 sensor.
+func NewBMP390() *Device {
+	return newDevice("BMP390", &chipBMP388{coeff: DefaultCoeffBMP388, id: 0x60})
+}
+
+// NewBMP384 creates emulated BMP384 sensor.
+func NewBMP384() *Device {
+	return newDevice("BMP384", &chipBMP388{coeff: DefaultCoeffBMP388, id: 0x50})
+}
+
+func (v *chipBMP388) reset(d *Device) {
+	d.regs = [256]byte{}
+	v.drdy = 0
+	v.intStatus = 0
+	v.intLatched = false
+	v.fifo = nil
+	v.tail = nil
+	v.skipped = 0
+	v.sensorTime = 0
+	d.regs[bmp388FIFOConf2] = 0x02
+	d.regs[bmp388IDReg] = v.id
+	c := &v.coeff
+	d.putU16LE(bmp388CoefStart, c.T1)
+	d.putU16LE(bmp388CoefStart+2, c.T2)
+	d.regs[bmp388CoefStart+4] = byte(c.T3)
+	d.putU16LE(bmp388CoefStart+5, uint16(c.P1))
+	d.putU16LE(bmp388CoefStart+7, uint16(c.P2))
+	d.regs[bmp388CoefStart+9] = byte(c.P3)
+	d.regs[bmp388CoefStart+10] = byte(c.P4)
+	d.putU16LE(bmp388CoefStart+11, c.P5)
+	d.putU16LE(bmp388CoefStart+13, c.P6)
+	d.regs[bmp388CoefStart+15] = byte(c.P7)
+	d.regs[bmp388CoefStart+16] = byte(c.P8)
+	d.putU16LE(bmp388CoefStart+17, uint16(c.P9))
+	d.regs[bmp388CoefStart+19] = byte(c.P10)
+	d.regs[bmp388CoefStart+20] = byte(c.P11)
+}
+
+func (v *chipBMP388) read(d *Device, reg byte) byte {
+	if reg == bmp388StatusReg {
+		// command decoder always ready
+		if d.pollBusy() {
+			return 0x10
+		}
+		return 0x10 | v.drdy
+	}
+	switch reg {
+	case bmp388IntStatReg:
+		b := v.intStatus
+		v.intStatus = 0
+		v.intLatched = false
+		return b
+	case bmp388FIFOLenReg:
+		// new readout started
+		v.tail = nil
+		v.timeSent = false
+		return byte(len(v.fifo))
+	case bmp388FIFOLenReg + 1:
+		return byte(len(v.fifo) >> 8)
+	case bmp388FIFOData:
+		return v.popFIFO(d)
+	}
+	if (d.regs[bmp388PwrCtrlReg]>>4)&0x03 == 0x03 && reg >= bmp388PressReg && reg < bmp388TempReg+3 {
+		// normal mode: data registers always keep latest sample
+		v.measure(d, d.regs[bmp388PwrCtrlReg])
+	}
+	return d.regs[reg]
+}
+
+func (v *chipBMP388) write(d *Device, reg byte, value byte) {
+	switch reg {
+	case bmp388CmdReg:
+		switch value {
+		case 0xB6:
+			v.reset(d)
+		case 0xB0:
+			v.fifo = nil
+		}
+	case bmp388OSRReg, bmp388ODRReg, bmp388ConfigReg:
+		if d.regs[bmp388FIFOConf1]&0x01 != 0 && d.regs[reg] != value {
+			// configuration change frame
+			v.pushFIFO(d, []byte{0x48, 0x01})
+		}
+		d.regs[reg] = value
+	case bmp388PwrCtrlReg:
+		mode := (value >> 4) & 0x03
+		if mode != 0 {
+			v.measure(d, value)
+		}
+		if mode == 1 || mode == 2 {
+			// forced mode returns to sleep mode after measurement
+			value &^= 0x30
+			v.signal(d, 0x08)
+			d.startMeasurement()
+		}
+		d.regs[reg] = value
+	default:
+		d.regs[reg] = value
+	}
+}
+
+// measure fills data registers of enabled channels
+// with values converted from environment.
+func (v *chipBMP388) measure(d *Device, pwrCtrl byte) {
+	v.drdy = 0
+	ut := v.rawTemperature(d.env.Temperature)
+	if pwrCtrl&0x02 != 0 {
+		d.putU24LE(bmp388TempReg, ut)
+		v.drdy |= 0x40
+	}
+	if pwrCtrl&0x01 != 0 {
+		up := v.rawPressure(v.tLin(float64(ut)), d.env.Pressure)
+		d.putU24LE(bmp388PressReg, up)
+		v.drdy |= 0x20
+	}
+}
+
+func (v *chipBMP388) isPort(reg byte) bool {
+	return reg == bmp388FIFOData
+}
+
+// sample takes next sample in normal mode and stores
+// it to FIFO according to FIFO configuration.
+func (v *chipBMP388) sample(d *Device) {
+	pwrCtrl := d.regs[bmp388PwrCtrlReg]
+	if (pwrCtrl>>4)&0x03 != 0x03 {
+		return
+	}
+	v.measure(d, pwrCtrl)
+	v.signal(d, 0x08)
+	// sampling period is 5 ms * 2^odr
+	v.sensorTime = (v.sensorTime + 128<<(d.regs[bmp388ODRReg]&0x1F)) & 0xFFFFFF
+	conf1 := d.regs[bmp388FIFOConf1]
+	if conf1&0x01 == 0 {
+		return
+	}
+	v.skipped++
+	if v.skipped < 1<<(d.regs[bmp388FIFOConf2]&0x07) {
+		return
+	}
+	v.skipped = 0
+	header := byte(0x80)
+	frame := []byte{header}
+	if conf1&0x10 != 0 && pwrCtrl&0x02 != 0 {
+		header |= 0x10
+		frame = append(frame, d.regs[bmp388TempReg:bmp388TempReg+3]...)
+	}
+	if conf1&0x08 != 0 && pwrCtrl&0x01 != 0 {
+		header |= 0x04
+		frame = append(frame, d.regs[bmp388PressReg:bmp388PressReg+3]...)
+	}
+	if header == 0x80 {
+		return
+	}
+	frame[0] = header
+	v.pushFIFO(d, frame)
+}
+
+// signal set interrupt status bits and triggers
+// INT output, if interrupt enabled.
+func (v *chipBMP388) signal(d *Device, status byte) {
+	v.intStatus |= status
+	ctrl := d.regs[bmp388IntCtrlReg]
+	var enabled byte
+	if ctrl&0x08 != 0 {
+		enabled |= 0x01
+	}
+	if ctrl&0x10 != 0 {
+		enabled |= 0x02
+	}
+	if ctrl&0x40 != 0 {
+		enabled |= 0x08
+	}
+	if status&enabled != 0 && !v.intLatched {
+		// in latched mode no more edges until status read
+		v.intLatched = ctrl&0x04 != 0
+		d.interrupt()
+	}
+}
+
+// pushFIFO append frame to FIFO. When FIFO is full, frame is dropped
+// in stop-on-full mode, otherwise oldest frames are removed.
+func (v *chipBMP388) pushFIFO(d *Device, frame []byte) {
+	for len(v.fifo)+len(frame) > bmp388FIFOSize {
+		v.signal(d, 0x02)
+		if d.regs[bmp388FIFOConf1]&0x02 != 0 {
+			return
+		}
+		v.fifo = v.fifo[bmp388FrameSize(v.fifo[0]):]
+	}
+	v.fifo = append(v.fifo, frame...)
+	wtm := int(d.regs[bmp388FIFOWtm]) | int(d.regs[bmp388FIFOWtm+1]&0x01)<<8
+	if wtm > 0 && len(v.fifo) >= wtm {
+		v.signal(d, 0x01)
+	}
+	v.tail = nil
+	v.timeSent = false
+}
+
+// popFIFO return next byte from FIFO. Past the last frame sensortime
+// frame is returned once, if enabled, followed by empty frames.
+func (v *chipBMP388) popFIFO(d *Device) byte {
+	if len(v.fifo) > 0 {
+		b := v.fifo[0]
+		v.fifo = v.fifo[1:]
+		return b
+	}
+	if len(v.tail) == 0 {
+		if d.regs[bmp388FIFOConf1]&0x04 != 0 && !v.timeSent {
+			v.tail = []byte{0xA0, byte(v.sensorTime),
+				byte(v.sensorTime >> 8), byte(v.sensorTime >> 16)}
+			v.timeSent = true
+		} else {
+			v.tail = []byte{0x80, 0x00}
+		}
+	}
+	b := v.tail[0]
+	v.tail = v.tail[1:]
+	return b
+}
+
+// bmp388FrameSize return FIFO frame size by header.
+func bmp388FrameSize(header byte) int {
+	switch header {
+	case 0x94:
+		return 7
+	case 0x90, 0x84, 0xA0:
+		return 4
+	default:
+		return 2
+	}
+}
+
+// tLin calculate linearized temperature in C (celsius)
+// according to datasheet floating point formula.
+func (v *chipBMP388) tLin(ut float64) float64 {
+	c := &v.coeff
+	parT1 := float64(c.T1) * 256
+	parT2 := float64(c.T2) / (1 << 30)
+	parT3 := float64(c.T3) / (1 << 48)
+	pd1 := ut - parT1
+	pd2 := pd1 * parT2
+	return pd2 + pd1*pd1*parT3
+}
+
+// pressure converts raw value to pressure in Pa (pascal)
+// according to datasheet floating point formula.
+func (v *chipBMP388) pressure(tLin float64, up float64) float64 {
+	c := &v.coeff
+	parP1 := (float64(c.P1) - (1 << 14)) / (1 << 20)
+	parP2 := (float64(c.P2) - (1 << 14)) / (1 << 29)
+	parP3 := float64(c.P3) / (1 << 32)
+	parP4 := float64(c.P4) / (1 << 37)
+	parP5 := float64(c.P5) * 8
+	parP6 := float64(c.P6) / (1 << 6)
+	parP7 := float64(c.P7) / (1 << 8)
+	parP8 := float64(c.P8) / (1 << 15)
+	parP9 := float64(c.P9) / (1 << 48)
+	parP10 := float64(c.P10) / (1 << 48)
+	parP11 := float64(c.P11) / math.Pow(2, 65)
+	t := tLin
+	out1 := parP5 + parP6*t + parP7*t*t + parP8*t*t*t
+	out2 := up * (parP1 + parP2*t + parP3*t*t + parP4*t*t*t)
+	out3 := up*up*(parP9+parP10*t) + up*up*up*parP11
+	return out1 + out2 + out3
+}
+
+func (v *chipBMP388) rawTemperature(t float64) int32 {
+	return invert(v.tLin, t, 0, 1<<24-1)
+}
+
+func (v *chipBMP388) rawPressure(tLin float64, p float64) int32 {
+	f := func(up float64) float64 {
+		return v.pressure(tLin, up)
+	}
+	return invert(f, p, 0, 1<<24-1)
+}
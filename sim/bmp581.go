@@ -0,0 +1,234 @@
+//--------------------------------------------------------------------------------------------------
+//
+// Copyright (c) 2018 Denis Dyakov
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy of this software and
+// associated documentation files (the "Software"), to deal in the Software without restriction,
+// including without limitation the rights to use, copy, modify, merge, publish, distribute, sublicense,
+// and/or sell copies of the Software, and to permit persons to whom the Software is furnished to do so,
+// subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all copies or substantial
+// portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR IMPLIED, INCLUDING
+// BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY, FITNESS FOR A PARTICULAR PURPOSE AND
+// NONINFRINGEMENT. IN NO EVENT SHALL THE AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM,
+// DAMAGES OR OTHER LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE SOFTWARE.
+//
+//--------------------------------------------------------------------------------------------------
+
+package sim
+
+import "math"
+
+// BMP581 registers used by emulation.
+const (
+	bmp581IDReg         = 0x01
+	bmp581IntConfigReg  = 0x14
+	bmp581IntSourceReg  = 0x15
+	bmp581FIFOConfigReg = 0x16
+	bmp581FIFOCountReg  = 0x17
+	bmp581FIFOSelReg    = 0x18
+	bmp581TempReg       = 0x1D
+	bmp581PressReg      = 0x20
+	bmp581IntStatusReg  = 0x27
+	bmp581StatusReg     = 0x28
+	bmp581FIFOData      = 0x29
+	bmp581OORThrReg     = 0x32
+	bmp581OORRangeReg   = 0x34
+	bmp581OORConfigReg  = 0x35
+	bmp581OSRConfigReg  = 0x36
+	bmp581ODRConfigReg  = 0x37
+	bmp581OSREffReg     = 0x38
+	bmp581CmdReg        = 0x7E
+)
+
+type chipBMP581 struct {
+	id byte
+	// interrupt status bits, cleared on read
+	intStatus byte
+	// latched INT output is asserted until status read
+	intLatched bool
+	// FIFO buffer content
+	fifo []byte
+	// Samples taken since last frame stored to FIFO
+	skipped int
+}
+
+// NewBMP581 creates emulated BMP581 sensor.
+func NewBMP581() *Device {
+	return newDevice("BMP581", &chipBMP581{id: 0x50})
+}
+
+// NewBMP585 creates emulated BMP585 sensor.
+func NewBMP585() *Device {
+	return newDevice("BMP585", &chipBMP581{id: 0x51})
+}
+
+func (v *chipBMP581) reset(d *Device) {
+	d.regs = [256]byte{}
+	v.fifo = nil
+	v.skipped = 0
+	v.intLatched = false
+	d.regs[bmp581IDReg] = v.id
+	// NVM ready
+	d.regs[bmp581StatusReg] = 0x02
+	// power-on reset detected
+	v.intStatus = 0x10
+	// deep standby, 1 Hz
+	d.regs[bmp581ODRConfigReg] = 0x70
+	d.regs[bmp581OSREffReg] = 0x80
+}
+
+// mode return power mode bits of ODR_CONFIG register.
+func (v *chipBMP581) mode(d *Device) byte {
+	return d.regs[bmp581ODRConfigReg] & 0x03
+}
+
+func (v *chipBMP581) read(d *Device, reg byte) byte {
+	switch reg {
+	case bmp581ODRConfigReg:
+		if v.mode(d) == 2 && !d.pollBusy() {
+			// forced conversion completed
+			d.regs[reg] &^= 0x03
+		}
+	case bmp581IntStatusReg:
+		b := v.intStatus
+		v.intStatus = 0
+		v.intLatched = false
+		return b
+	case bmp581FIFOCountReg:
+		if size := v.frameSize(d); size > 0 {
+			return byte(len(v.fifo) / size)
+		}
+		return 0
+	case bmp581FIFOData:
+		if len(v.fifo) == 0 {
+			return 0x7F
+		}
+		b := v.fifo[0]
+		v.fifo = v.fifo[1:]
+		return b
+	}
+	if mode := v.mode(d); (mode == 1 || mode == 3) &&
+		reg >= bmp581TempReg && reg < bmp581PressReg+3 {
+		// normal mode: data registers always keep latest sample
+		v.measure(d)
+	}
+	return d.regs[reg]
+}
+
+func (v *chipBMP581) write(d *Device, reg byte, value byte) {
+	switch reg {
+	case bmp581CmdReg:
+		if value == 0xB6 {
+			v.reset(d)
+		}
+	case bmp581ODRConfigReg:
+		d.regs[reg] = value
+		if value&0x03 == 2 {
+			v.measure(d)
+			v.signal(d, 0x01)
+			d.startMeasurement()
+		}
+	case bmp581FIFOSelReg:
+		if d.regs[reg]&0x03 != value&0x03 {
+			// frame content change flush FIFO
+			v.fifo = nil
+		}
+		d.regs[reg] = value
+	default:
+		d.regs[reg] = value
+	}
+}
+
+// measure fills data registers with values from environment,
+// the same way as on-chip compensation does.
+func (v *chipBMP581) measure(d *Device) {
+	t := int32(math.Floor(d.env.Temperature*65536 + 0.5))
+	d.putU24LE(bmp581TempReg, t)
+	if d.regs[bmp581OSRConfigReg]&0x40 == 0 {
+		// pressure measurement disabled
+		return
+	}
+	p := int32(math.Floor(d.env.Pressure*64 + 0.5))
+	d.putU24LE(bmp581PressReg, p)
+	thr := float64(d.regs[bmp581OORThrReg]) + float64(d.regs[bmp581OORThrReg+1])*256 +
+		float64(d.regs[bmp581OORConfigReg]&0x01)*65536
+	if math.Abs(d.env.Pressure-thr) > float64(d.regs[bmp581OORRangeReg]) {
+		v.signal(d, 0x08)
+	}
+}
+
+// frameSize return FIFO frame size in bytes.
+func (v *chipBMP581) frameSize(d *Device) int {
+	switch d.regs[bmp581FIFOSelReg] & 0x03 {
+	case 1, 2:
+		return 3
+	case 3:
+		return 6
+	default:
+		return 0
+	}
+}
+
+// sample takes next sample in normal mode and stores
+// it to FIFO according to FIFO configuration.
+func (v *chipBMP581) sample(d *Device) {
+	if mode := v.mode(d); mode != 1 && mode != 3 {
+		return
+	}
+	v.measure(d)
+	v.signal(d, 0x01)
+	size := v.frameSize(d)
+	if size == 0 {
+		return
+	}
+	v.skipped++
+	if v.skipped < 1<<((d.regs[bmp581FIFOSelReg]>>2)&0x07) {
+		return
+	}
+	v.skipped = 0
+	sel := d.regs[bmp581FIFOSelReg]
+	var frame []byte
+	if sel&0x01 != 0 {
+		frame = append(frame, d.regs[bmp581TempReg:bmp581TempReg+3]...)
+	}
+	if sel&0x02 != 0 {
+		frame = append(frame, d.regs[bmp581PressReg:bmp581PressReg+3]...)
+	}
+	// 32 frames of single value, or 16 frames of both values
+	capacity := 96
+	if len(v.fifo)+size > capacity {
+		if d.regs[bmp581FIFOConfigReg]&0x20 != 0 {
+			return
+		}
+		v.fifo = v.fifo[size:]
+	}
+	v.fifo = append(v.fifo, frame...)
+	if len(v.fifo)+size > capacity {
+		v.signal(d, 0x02)
+	}
+	thr := int(d.regs[bmp581FIFOConfigReg] & 0x1F)
+	if thr > 0 && len(v.fifo)/size >= thr {
+		v.signal(d, 0x04)
+	}
+}
+
+// signal set interrupt status bits and triggers
+// INT output, if interrupt enabled.
+func (v *chipBMP581) signal(d *Device, status byte) {
+	v.intStatus |= status
+	config := d.regs[bmp581IntConfigReg]
+	if config&0x08 != 0 && status&d.regs[bmp581IntSourceReg] != 0 && !v.intLatched {
+		// in latched mode no more edges until status read
+		v.intLatched = config&0x01 != 0
+		d.interrupt()
+	}
+}
+
+func (v *chipBMP581) isPort(reg byte) bool {
+	return reg == bmp581FIFOData
+}
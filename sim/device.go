@@ -0,0 +1,294 @@
+//--------------------------------------------------------------------------------------------------
+//
+// Copyright (c) 2018 Denis Dyakov
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy of this software and
+// associated documentation files (the "Software"), to deal in the Software without restriction,
+// including without limitation the rights to use, copy, modify, merge, publish, distribute, sublicense,
+// and/or sell copies of the Software, and to permit persons to whom the Software is furnished to do so,
+// subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all copies or substantial
+// portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR IMPLIED, INCLUDING
+// BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY, FITNESS FOR A PARTICULAR PURPOSE AND
+// NONINFRINGEMENT. IN NO EVENT SHALL THE AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM,
+// DAMAGES OR OTHER LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE SOFTWARE.
+//
+//--------------------------------------------------------------------------------------------------
+
+// Package sim implements in-memory emulation of Bosch Sensortec sensors register map.
+// Emulated device implements bsbmp.Bus interface, so it might be passed to bsbmp.NewBMP
+// instead of real i2c connection, which allow to run driver code end to end without
+// any hardware attached (in CI, for instance).
+//
+// Device keeps calibration coefficients in NVM registers, reacts on measurement start
+// via control registers, emulate "busy" status bits and produce raw ADC values from
+// configured environment (true temperature, pressure and humidity), applying
+// datasheet compensation formulas in reverse direction. Emulated gpio line connected
+// to INT output (see Device.InterruptPin) allows to run interrupt driven code as well.
+//
+//	Sensors emulated:
+//	  BMP085 - Abs Press, Temp.
+//	  BMP180 - Abs Press, Temp.
+//	  BMP280 - Abs Press, Temp.
+//	  BME280 - Abs Press, Temp, Relative Humidity
+//	  BMP388 - Abs Press, Temp.
+//	  BMP390 - Abs Press, Temp.
+//	  BMP384 - Abs Press, Temp.
+//	  BMP581 - Abs Press, Temp.
+//	  BMP585 - Abs Press, Temp.
+//	  BME680 - Abs Press, Temp, Relative Humidity, Gas resistance
+//	  BME688 - Abs Press, Temp, Relative Humidity, Gas resistance
+package sim
+
+import (
+	"math"
+	"sync"
+)
+
+// Environment describe "true" physical values
+// which emulated sensor should measure.
+type Environment struct {
+	// Temperature in C (celsius).
+	Temperature float64
+	// Atmospheric pressure in Pa (pascal).
+	Pressure float64
+	// Relative humidity in range [0..100]%.
+	Humidity float64
+	// Gas sensor resistance in Ohm.
+	GasResistance float64
+}
+
+// DefaultEnvironment is used by emulated devices after creation.
+var DefaultEnvironment = Environment{
+	Temperature:   25,
+	Pressure:      101325,
+	Humidity:      50,
+	GasResistance: 50000,
+}
+
+// chip implement model specific behavior of device register map.
+type chip interface {
+	// reset fills registers with power-on values, including
+	// chip identifier and calibration coefficients.
+	reset(d *Device)
+	// read return register value, taking into account dynamic
+	// registers, like status register.
+	read(d *Device, reg byte) byte
+	// write store value to register, and run side effects, like
+	// measurement start or soft reset.
+	write(d *Device, reg byte, value byte)
+}
+
+// portChip is implemented by chips having data port registers, which
+// don't advance register address on burst read (FIFO data, for instance).
+type portChip interface {
+	isPort(reg byte) bool
+}
+
+// sampler is implemented by chips keeping samples
+// taken in normal mode (in FIFO buffer, for instance).
+type sampler interface {
+	sample(d *Device)
+}
+
+// Device emulates Bosch Sensortec sensor connected to the bus.
+type Device struct {
+	mu   sync.Mutex
+	name string
+	chip chip
+	regs [256]byte
+	env  Environment
+	// Amount of status register reads reporting "busy"
+	// after measurement started.
+	busyPolls int
+	// Amount of status register reads left until
+	// current measurement completes.
+	busy int
+	// Line connected to INT output, if requested.
+	pin *Pin
+}
+
+func newDevice(name string, c chip) *Device {
+	v := &Device{name: name, chip: c, env: DefaultEnvironment, busyPolls: 1}
+	c.reset(v)
+	return v
+}
+
+// Name returns emulated sensor model name.
+func (v *Device) Name() string {
+	return v.name
+}
+
+// SetEnvironment change physical values which would be
+// measured by the next conversion.
+func (v *Device) SetEnvironment(env Environment) {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+	v.env = env
+}
+
+// Environment returns physical values used by emulation.
+func (v *Device) Environment() Environment {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+	return v.env
+}
+
+// SetBusyPolls defines how many times status register reports
+// measurement in progress, before data registers become ready.
+func (v *Device) SetBusyPolls(polls int) {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+	v.busyPolls = polls
+}
+
+// Register returns raw register content without side effects.
+func (v *Device) Register(reg byte) byte {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+	return v.regs[reg]
+}
+
+// SetRegister overwrite raw register content without side effects.
+// Might be used to emulate corrupted NVM, for instance.
+func (v *Device) SetRegister(reg byte, value byte) {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+	v.regs[reg] = value
+}
+
+// ReadRegU8 reads single byte from register reg.
+func (v *Device) ReadRegU8(reg byte) (byte, error) {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+	return v.chip.read(v, reg), nil
+}
+
+// WriteRegU8 writes single byte value to register reg.
+func (v *Device) WriteRegU8(reg byte, value byte) error {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+	v.chip.write(v, reg, value)
+	return nil
+}
+
+// ReadRegBytes reads block of n bytes starting from register reg,
+// with register address autoincrement, as real sensors do.
+// Data port registers (FIFO) are read n times instead.
+func (v *Device) ReadRegBytes(reg byte, n int) ([]byte, int, error) {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+	port := false
+	if c, ok := v.chip.(portChip); ok {
+		port = c.isPort(reg)
+	}
+	buf := make([]byte, n)
+	for i := range buf {
+		if port {
+			buf[i] = v.chip.read(v, reg)
+		} else {
+			buf[i] = v.chip.read(v, reg+byte(i))
+		}
+	}
+	return buf, n, nil
+}
+
+// Sample emulates n sampling periods of normal mode passed by.
+// Sensors having FIFO store frames there, if FIFO enabled.
+func (v *Device) Sample(n int) {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+	if c, ok := v.chip.(sampler); ok {
+		for i := 0; i < n; i++ {
+			c.sample(v)
+		}
+	}
+}
+
+// InterruptPin returns emulated gpio line connected to INT output.
+// Device triggers edge on each interrupt enabled in sensor configuration.
+func (v *Device) InterruptPin() *Pin {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+	if v.pin == nil {
+		v.pin = NewPin()
+	}
+	return v.pin
+}
+
+// interrupt triggers edge on INT output.
+func (v *Device) interrupt() {
+	if v.pin != nil {
+		v.pin.Trigger()
+	}
+}
+
+// startMeasurement marks device as busy for
+// the next busyPolls status register reads.
+func (v *Device) startMeasurement() {
+	v.busy = v.busyPolls
+}
+
+// pollBusy returns true, if measurement still
+// in progress and count down status reads.
+func (v *Device) pollBusy() bool {
+	if v.busy > 0 {
+		v.busy--
+		return true
+	}
+	return false
+}
+
+// putU16LE store 16-bit value to two registers as little-endian.
+func (v *Device) putU16LE(reg byte, value uint16) {
+	v.regs[reg] = byte(value)
+	v.regs[reg+1] = byte(value >> 8)
+}
+
+// putU16BE store 16-bit value to two registers as big-endian.
+func (v *Device) putU16BE(reg byte, value uint16) {
+	v.regs[reg] = byte(value >> 8)
+	v.regs[reg+1] = byte(value)
+}
+
+// putU20 store 20-bit ADC value to msb, lsb, xlsb[7:4] registers.
+func (v *Device) putU20(reg byte, value int32) {
+	v.regs[reg] = byte(value >> 12)
+	v.regs[reg+1] = byte(value >> 4)
+	v.regs[reg+2] = byte(value<<4) & 0xF0
+}
+
+// putU24LE store 24-bit ADC value to xlsb, lsb, msb registers.
+func (v *Device) putU24LE(reg byte, value int32) {
+	v.regs[reg] = byte(value)
+	v.regs[reg+1] = byte(value >> 8)
+	v.regs[reg+2] = byte(value >> 16)
+}
+
+// invert find integer raw value in range [lo..hi], which
+// being compensated by monotonic function f, give result
+// closest to target. Used to convert "true" physical values
+// to raw ADC values, going through compensation formulas
+// in reverse direction.
+func invert(f func(raw float64) float64, target float64, lo, hi int32) int32 {
+	a, b := float64(lo), float64(hi)
+	rising := f(b) > f(a)
+	for i := 0; i < 64; i++ {
+		m := (a + b) / 2
+		if (f(m) < target) == rising {
+			a = m
+		} else {
+			b = m
+		}
+	}
+	raw := int32(math.Floor(a))
+	// pick closest of two neighbour integer values
+	if raw < hi && math.Abs(f(float64(raw+1))-target) < math.Abs(f(float64(raw))-target) {
+		raw++
+	}
+	return raw
+}
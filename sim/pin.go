@@ -0,0 +1,66 @@
+//--------------------------------------------------------------------------------------------------
+//
+// Copyright (c) 2018 Denis Dyakov
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy of this software and
+// associated documentation files (the "Software"), to deal in the Software without restriction,
+// including without limitation the rights to use, copy, modify, merge, publish, distribute, sublicense,
+// and/or sell copies of the Software, and to permit persons to whom the Software is furnished to do so,
+// subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all copies or substantial
+// portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR IMPLIED, INCLUDING
+// BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY, FITNESS FOR A PARTICULAR PURPOSE AND
+// NONINFRINGEMENT. IN NO EVENT SHALL THE AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM,
+// DAMAGES OR OTHER LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE SOFTWARE.
+//
+//--------------------------------------------------------------------------------------------------
+
+package sim
+
+import (
+	"time"
+)
+
+// Pin emulates gpio line connected to sensor INT output. It implements
+// bsbmp.InterruptPin interface, so it might be attached to the sensor
+// with SetInterruptPin. Edges are queued, as Linux gpio driver does.
+type Pin struct {
+	edges chan struct{}
+}
+
+// NewPin creates emulated gpio line not connected to any device.
+// Use Trigger to produce edges.
+func NewPin() *Pin {
+	return &Pin{edges: make(chan struct{}, 16)}
+}
+
+// Trigger produce single edge. When queue is full, edge is lost.
+func (v *Pin) Trigger() {
+	select {
+	case v.edges <- struct{}{}:
+	default:
+	}
+}
+
+// WaitForEdge blocks until edge triggered, or timeout expired.
+// Returns false on timeout. Zero timeout only check queued edges.
+func (v *Pin) WaitForEdge(timeout time.Duration) (bool, error) {
+	if timeout <= 0 {
+		select {
+		case <-v.edges:
+			return true, nil
+		default:
+			return false, nil
+		}
+	}
+	select {
+	case <-v.edges:
+		return true, nil
+	case <-time.After(timeout):
+		return false, nil
+	}
+}
@@ -0,0 +1,98 @@
+//--------------------------------------------------------------------------------------------------
+//
+// Copyright (c) 2018 Denis Dyakov
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy of this software and
+// associated documentation files (the "Software"), to deal in the Software without restriction,
+// including without limitation the rights to use, copy, modify, merge, publish, distribute, sublicense,
+// and/or sell copies of the Software, and to permit persons to whom the Software is furnished to do so,
+// subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all copies or substantial
+// portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR IMPLIED, INCLUDING
+// BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY, FITNESS FOR A PARTICULAR PURPOSE AND
+// NONINFRINGEMENT. IN NO EVENT SHALL THE AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM,
+// DAMAGES OR OTHER LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE SOFTWARE.
+//
+//--------------------------------------------------------------------------------------------------
+
+package sim
+
+import (
+	"testing"
+	"time"
+)
+
+func TestPinWaitForEdge(t *testing.T) {
+	pin := NewPin()
+	edge, err := pin.WaitForEdge(10 * time.Millisecond)
+	if err != nil || edge {
+		t.Fatalf("WaitForEdge() = %v, %v on idle pin, want timeout", edge, err)
+	}
+	pin.Trigger()
+	pin.Trigger()
+	for i := 0; i < 2; i++ {
+		edge, err = pin.WaitForEdge(10 * time.Millisecond)
+		if err != nil || !edge {
+			t.Fatalf("WaitForEdge() = %v, %v, want queued edge %d", edge, err, i)
+		}
+	}
+	edge, _ = pin.WaitForEdge(0)
+	if edge {
+		t.Fatal("edge reported twice")
+	}
+}
+
+func TestPinWaitForEdgeBlocks(t *testing.T) {
+	pin := NewPin()
+	go func() {
+		time.Sleep(20 * time.Millisecond)
+		pin.Trigger()
+	}()
+	edge, err := pin.WaitForEdge(time.Second)
+	if err != nil || !edge {
+		t.Fatalf("WaitForEdge() = %v, %v, want edge", edge, err)
+	}
+}
+
+func TestPinQueueOverflow(t *testing.T) {
+	pin := NewPin()
+	for i := 0; i < 100; i++ {
+		pin.Trigger()
+	}
+	n := 0
+	for {
+		edge, _ := pin.WaitForEdge(0)
+		if !edge {
+			break
+		}
+		n++
+	}
+	if n != cap(pin.edges) {
+		t.Errorf("%d edges queued, want %d", n, cap(pin.edges))
+	}
+}
+
+func TestDeviceInterruptLatched(t *testing.T) {
+	d := NewBMP388()
+	pin := d.InterruptPin()
+	// latched data-ready interrupt
+	d.WriteRegU8(bmp388IntCtrlReg, 0x40|0x04)
+	// two forced conversions without status read
+	d.WriteRegU8(bmp388PwrCtrlReg, 0x13)
+	d.WriteRegU8(bmp388PwrCtrlReg, 0x13)
+	if edge, _ := pin.WaitForEdge(0); !edge {
+		t.Fatal("no edge on first conversion")
+	}
+	if edge, _ := pin.WaitForEdge(0); edge {
+		t.Fatal("edge while INT is latched")
+	}
+	d.ReadRegU8(bmp388IntStatReg)
+	d.WriteRegU8(bmp388PwrCtrlReg, 0x13)
+	if edge, _ := pin.WaitForEdge(0); !edge {
+		t.Fatal("no edge after status read")
+	}
+}
@@ -0,0 +1,158 @@
+//--------------------------------------------------------------------------------------------------
+//
+// Copyright (c) 2018 Denis Dyakov
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy of this software and
+// associated documentation files (the "Software"), to deal in the Software without restriction,
+// including without limitation the rights to use, copy, modify, merge, publish, distribute, sublicense,
+// and/or sell copies of the Software, and to permit persons to whom the Software is furnished to do so,
+// subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all copies or substantial
+// portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR IMPLIED, INCLUDING
+// BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY, FITNESS FOR A PARTICULAR PURPOSE AND
+// NONINFRINGEMENT. IN NO EVENT SHALL THE AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM,
+// DAMAGES OR OTHER LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE SOFTWARE.
+//
+//--------------------------------------------------------------------------------------------------
+
+package bsbmp
+
+// SPIConn is a low level full-duplex SPI connection
+// with chip select asserted during single transfer.
+type SPIConn interface {
+	// Tx sends w and simultaneously receives len(r) bytes
+	// to r, within single chip select assertion.
+	Tx(w, r []byte) error
+	// Close release connection resources.
+	Close() error
+}
+
+// BME680 specific SPI memory page selection.
+const (
+	// Status register holding spi_mem_page bit,
+	// which is accessible from both memory pages.
+	BME680_SPI_STATUS_REG = 0x73
+	BME680_SPI_MEM_PAGE   = 0x10
+)
+
+// SPI implements Bus interface over 4-wire SPI connection,
+// taking care of sensor specific SPI conventions:
+// bit 7 of register address selects read (1) or write (0) operation,
+// BMP3 family returns one dummy byte before data on read, and BME680
+// splits registers into two memory pages of 128 bytes each.
+// Register addresses are the same, as in i2c mode.
+type SPI struct {
+	conn       SPIConn
+	sensorType SensorType
+	// Amount of dummy bytes preceding data on read operation.
+	dummy int
+	// BME680 memory page switching required.
+	paged bool
+	// Currently selected memory page, negative if unknown.
+	page int
+}
+
+// Static cast to verify at compile time
+// that type implement interface.
+var _ Bus = &SPI{}
+
+// NewSPIBus creates Bus on top of SPI connection to sensor of sensorType.
+func NewSPIBus(conn SPIConn, sensorType SensorType) (*SPI, error) {
+	v := &SPI{conn: conn, sensorType: sensorType, page: -1}
+	switch sensorType {
+	case BMP280, BME280, BMP581, BMP585:
+	case BMP388, BMP390, BMP384:
+		v.dummy = 1
+	case BME680, BME688:
+		v.paged = true
+	default:
+		return nil, &NotSupportedError{Sensor: sensorType, Feature: "SPI interface"}
+	}
+	return v, nil
+}
+
+// Close release SPI connection.
+func (v *SPI) Close() error {
+	return v.conn.Close()
+}
+
+// selectPage switch BME680 memory page if required and return
+// 7-bit SPI address corresponding to i2c register address.
+// Page 0 contains registers 0x80..0xFF, page 1 - 0x00..0x7F.
+func (v *SPI) selectPage(reg byte) (byte, error) {
+	if !v.paged || reg == BME680_SPI_STATUS_REG {
+		return reg & 0x7F, nil
+	}
+	page := 1
+	if reg >= 0x80 {
+		page = 0
+	}
+	if page != v.page {
+		var b byte
+		if page == 1 {
+			b = BME680_SPI_MEM_PAGE
+		}
+		err := v.conn.Tx([]byte{BME680_SPI_STATUS_REG, b}, make([]byte, 2))
+		if err != nil {
+			v.page = -1
+			return 0, err
+		}
+		v.page = page
+	}
+	return reg & 0x7F, nil
+}
+
+// ReadRegBytes reads block of n bytes starting from register reg.
+// Block should not cross BME680 memory page boundary.
+func (v *SPI) ReadRegBytes(reg byte, n int) ([]byte, int, error) {
+	addr, err := v.selectPage(reg)
+	if err != nil {
+		return nil, 0, err
+	}
+	w := make([]byte, 1+v.dummy+n)
+	w[0] = addr | 0x80
+	r := make([]byte, len(w))
+	err = v.conn.Tx(w, r)
+	if err != nil {
+		return nil, 0, err
+	}
+	return r[1+v.dummy:], n, nil
+}
+
+// ReadRegU8 reads single byte from register reg.
+func (v *SPI) ReadRegU8(reg byte) (byte, error) {
+	buf, _, err := v.ReadRegBytes(reg, 1)
+	if err != nil {
+		return 0, err
+	}
+	return buf[0], nil
+}
+
+// WriteRegU8 writes single byte value to register reg.
+func (v *SPI) WriteRegU8(reg byte, value byte) error {
+	addr, err := v.selectPage(reg)
+	if err != nil {
+		return err
+	}
+	err = v.conn.Tx([]byte{addr & 0x7F, value}, make([]byte, 2))
+	if err != nil {
+		return err
+	}
+	if v.paged && reg == BME680_SPI_STATUS_REG {
+		// page selected explicitly by caller
+		v.page = 0
+		if value&BME680_SPI_MEM_PAGE != 0 {
+			v.page = 1
+		}
+	}
+	if v.paged && reg == BME280_RESET {
+		// soft reset register share the same address 0xE0 in all
+		// sensors, reset make BME680 memory page selection unknown
+		v.page = -1
+	}
+	return nil
+}
@@ -0,0 +1,134 @@
+//go:build linux
+// +build linux
+
+//--------------------------------------------------------------------------------------------------
+//
+// Copyright (c) 2018 Denis Dyakov
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy of this software and
+// associated documentation files (the "Software"), to deal in the Software without restriction,
+// including without limitation the rights to use, copy, modify, merge, publish, distribute, sublicense,
+// and/or sell copies of the Software, and to permit persons to whom the Software is furnished to do so,
+// subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all copies or substantial
+// portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR IMPLIED, INCLUDING
+// BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY, FITNESS FOR A PARTICULAR PURPOSE AND
+// NONINFRINGEMENT. IN NO EVENT SHALL THE AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM,
+// DAMAGES OR OTHER LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE SOFTWARE.
+//
+//--------------------------------------------------------------------------------------------------
+
+package bsbmp
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"runtime"
+	"syscall"
+	"unsafe"
+)
+
+// Linux spidev ioctl requests, see linux/spi/spidev.h.
+const (
+	spiIOCWrMode        = 0x40016B01
+	spiIOCWrBitsPerWord = 0x40016B03
+	spiIOCWrMaxSpeedHz  = 0x40046B04
+	spiIOCMessage1      = 0x40206B00
+)
+
+// spiIOCTransfer mirrors struct spi_ioc_transfer from linux/spi/spidev.h.
+type spiIOCTransfer struct {
+	txBuf          uint64
+	rxBuf          uint64
+	length         uint32
+	speedHz        uint32
+	delayUsecs     uint16
+	bitsPerWord    uint8
+	csChange       uint8
+	txNbits        uint8
+	rxNbits        uint8
+	wordDelayUsecs uint8
+	pad            uint8
+}
+
+// spidev is a SPIConn implementation based on Linux spidev driver.
+type spidev struct {
+	file    *os.File
+	speedHz uint32
+}
+
+// Static cast to verify at compile time
+// that type implement interface.
+var _ SPIConn = &spidev{}
+
+// NewSPI opens Linux spidev device /dev/spidev<bus>.<cs> in SPI mode 0
+// with clock limited to speedHz, and creates Bus to talk to sensor of
+// sensorType. Result can be passed to NewBMP the same way as i2c connection.
+func NewSPI(bus, cs int, speedHz uint32, sensorType SensorType) (*SPI, error) {
+	path := fmt.Sprintf("/dev/spidev%d.%d", bus, cs)
+	f, err := os.OpenFile(path, os.O_RDWR, 0600)
+	if err != nil {
+		return nil, err
+	}
+	dev := &spidev{file: f, speedHz: speedHz}
+	var mode, bits uint8 = 0, 8
+	err = dev.ioctl(spiIOCWrMode, uintptr(unsafe.Pointer(&mode)))
+	if err == nil {
+		err = dev.ioctl(spiIOCWrBitsPerWord, uintptr(unsafe.Pointer(&bits)))
+	}
+	if err == nil {
+		err = dev.ioctl(spiIOCWrMaxSpeedHz, uintptr(unsafe.Pointer(&speedHz)))
+	}
+	if err != nil {
+		f.Close()
+		return nil, err
+	}
+	v, err := NewSPIBus(dev, sensorType)
+	if err != nil {
+		f.Close()
+		return nil, err
+	}
+	lg.Debugf("SPI device %s opened at %d Hz", path, speedHz)
+	return v, nil
+}
+
+func (v *spidev) ioctl(req uintptr, arg uintptr) error {
+	_, _, errno := syscall.Syscall(syscall.SYS_IOCTL, v.file.Fd(), req, arg)
+	if errno != 0 {
+		return errno
+	}
+	return nil
+}
+
+// Tx implements SPIConn interface.
+func (v *spidev) Tx(w, r []byte) error {
+	if len(w) == 0 {
+		return nil
+	}
+	tr := spiIOCTransfer{
+		txBuf:       uint64(uintptr(unsafe.Pointer(&w[0]))),
+		length:      uint32(len(w)),
+		speedHz:     v.speedHz,
+		bitsPerWord: 8,
+	}
+	if len(r) > 0 {
+		if len(r) != len(w) {
+			return errors.New("SPI transfer buffers must have the same length")
+		}
+		tr.rxBuf = uint64(uintptr(unsafe.Pointer(&r[0])))
+	}
+	err := v.ioctl(spiIOCMessage1, uintptr(unsafe.Pointer(&tr)))
+	runtime.KeepAlive(w)
+	runtime.KeepAlive(r)
+	return err
+}
+
+// Close implements SPIConn interface.
+func (v *spidev) Close() error {
+	return v.file.Close()
+}
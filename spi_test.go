@@ -0,0 +1,170 @@
+//--------------------------------------------------------------------------------------------------
+//
+// Copyright (c) 2018 Denis Dyakov
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy of this software and
+// associated documentation files (the "Software"), to deal in the Software without restriction,
+// including without limitation the rights to use, copy, modify, merge, publish, distribute, sublicense,
+// and/or sell copies of the Software, and to permit persons to whom the Software is furnished to do so,
+// subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all copies or substantial
+// portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR IMPLIED, INCLUDING
+// BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY, FITNESS FOR A PARTICULAR PURPOSE AND
+// NONINFRINGEMENT. IN NO EVENT SHALL THE AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM,
+// DAMAGES OR OTHER LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE SOFTWARE.
+//
+//--------------------------------------------------------------------------------------------------
+
+package bsbmp_test
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/d2r2/go-bsbmp"
+)
+
+// fakeSPIConn record transfers, responding with byte index
+// within transfer to each byte sent.
+type fakeSPIConn struct {
+	tx [][]byte
+}
+
+func (v *fakeSPIConn) Tx(w, r []byte) error {
+	v.tx = append(v.tx, append([]byte(nil), w...))
+	for i := range r {
+		r[i] = byte(i)
+	}
+	return nil
+}
+
+func (v *fakeSPIConn) Close() error {
+	return nil
+}
+
+// checkTx compare transfers recorded with expected ones and reset record.
+func checkTx(t *testing.T, name string, conn *fakeSPIConn, want ...[]byte) {
+	t.Helper()
+	if len(conn.tx) != len(want) {
+		t.Errorf("%s: transfers = %x, want %x", name, conn.tx, want)
+	} else {
+		for i := range want {
+			if !bytes.Equal(conn.tx[i], want[i]) {
+				t.Errorf("%s: transfers = %x, want %x", name, conn.tx, want)
+				break
+			}
+		}
+	}
+	conn.tx = nil
+}
+
+func TestSPIReadWrite(t *testing.T) {
+	for _, c := range []struct {
+		sensorType bsbmp.SensorType
+		dummy      int
+	}{
+		{bsbmp.BMP280, 0},
+		{bsbmp.BME280, 0},
+		{bsbmp.BMP581, 0},
+		// BMP3 family return dummy byte before data
+		{bsbmp.BMP388, 1},
+		{bsbmp.BMP390, 1},
+	} {
+		name := c.sensorType.String()
+		conn := &fakeSPIConn{}
+		bus, err := bsbmp.NewSPIBus(conn, c.sensorType)
+		if err != nil {
+			t.Fatal(err)
+		}
+		buf, n, err := bus.ReadRegBytes(0x88, 3)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if n != 3 || !bytes.Equal(buf, []byte{byte(1 + c.dummy), byte(2 + c.dummy), byte(3 + c.dummy)}) {
+			t.Errorf("%s: ReadRegBytes() = %x, %d", name, buf, n)
+		}
+		want := make([]byte, 4+c.dummy)
+		want[0] = 0x88
+		checkTx(t, name+" read", conn, want)
+		b, err := bus.ReadRegU8(0x1B)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if b != byte(1+c.dummy) {
+			t.Errorf("%s: ReadRegU8() = %x", name, b)
+		}
+		// bit 7 set for read
+		want = make([]byte, 2+c.dummy)
+		want[0] = 0x9B
+		checkTx(t, name+" read", conn, want)
+		err = bus.WriteRegU8(0xF4, 0x27)
+		if err != nil {
+			t.Fatal(err)
+		}
+		// bit 7 cleared for write
+		checkTx(t, name+" write", conn, []byte{0x74, 0x27})
+	}
+	_, err := bsbmp.NewSPIBus(&fakeSPIConn{}, bsbmp.BMP180)
+	if err == nil {
+		t.Error("SPI bus created for BMP180")
+	}
+}
+
+// BME680 register map is split into two pages, selected by spi_mem_page bit
+// of status register 0x73, which is accessible from both pages.
+func TestSPIBME680Pages(t *testing.T) {
+	page0 := []byte{bsbmp.BME680_SPI_STATUS_REG, 0}
+	page1 := []byte{bsbmp.BME680_SPI_STATUS_REG, bsbmp.BME680_SPI_MEM_PAGE}
+	conn := &fakeSPIConn{}
+	bus, err := bsbmp.NewSPIBus(conn, bsbmp.BME688)
+	if err != nil {
+		t.Fatal(err)
+	}
+	steps := []struct {
+		name string
+		run  func() error
+		want [][]byte
+	}{
+		{"read page 0", func() error {
+			_, err := bus.ReadRegU8(0xD0)
+			return err
+		}, [][]byte{page0, {0xD0, 0}}},
+		{"read page 0 again", func() error {
+			_, _, err := bus.ReadRegBytes(0xE1, 2)
+			return err
+		}, [][]byte{{0xE1, 0, 0}}},
+		{"write page 1", func() error {
+			return bus.WriteRegU8(0x74, 0x25)
+		}, [][]byte{page1, {0x74, 0x25}}},
+		{"read page 1", func() error {
+			_, _, err := bus.ReadRegBytes(0x1D, 3)
+			return err
+		}, [][]byte{{0x9D, 0, 0, 0}}},
+		{"read status", func() error {
+			_, err := bus.ReadRegU8(bsbmp.BME680_SPI_STATUS_REG)
+			return err
+		}, [][]byte{{0xF3, 0}}},
+		{"select page 0 explicitly", func() error {
+			return bus.WriteRegU8(bsbmp.BME680_SPI_STATUS_REG, 0)
+		}, [][]byte{page0}},
+		{"write page 0", func() error {
+			return bus.WriteRegU8(0xE0, 0xB6)
+		}, [][]byte{{0x60, 0xB6}}},
+		// soft reset make page selection unknown
+		{"read page 0 after reset", func() error {
+			_, err := bus.ReadRegU8(0xD0)
+			return err
+		}, [][]byte{page0, {0xD0, 0}}},
+	}
+	for _, step := range steps {
+		err = step.run()
+		if err != nil {
+			t.Fatal(err)
+		}
+		checkTx(t, step.name, conn, step.want...)
+	}
+}
@@ -0,0 +1,400 @@
+//--------------------------------------------------------------------------------------------------
+//
+// Copyright (c) 2018 Denis Dyakov
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy of this software and
+// associated documentation files (the "Software"), to deal in the Software without restriction,
+// including without limitation the rights to use, copy, modify, merge, publish, distribute, sublicense,
+// and/or sell copies of the Software, and to permit persons to whom the Software is furnished to do so,
+// subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all copies or substantial
+// portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR IMPLIED, INCLUDING
+// BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY, FITNESS FOR A PARTICULAR PURPOSE AND
+// NONINFRINGEMENT. IN NO EVENT SHALL THE AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM,
+// DAMAGES OR OTHER LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE SOFTWARE.
+//
+//--------------------------------------------------------------------------------------------------
+
+package bsbmp
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// Conversion factors to Pa (pascal) and m (meter).
+const (
+	PA_PER_HPA  = 100.0
+	PA_PER_INHG = 3386.389
+	PA_PER_PSI  = 6894.757
+	PA_PER_ATM  = 101325.0
+	PA_PER_TORR = PA_PER_ATM / 760
+	PA_PER_MMHG = 133.322387415
+	M_PER_FOOT  = 0.3048
+)
+
+// Temperature in C (celsius).
+type Temperature float64
+
+// Pressure in Pa (pascal).
+type Pressure float64
+
+// RelativeHumidity in %RH, in range [0..100].
+type RelativeHumidity float64
+
+// Altitude in m (meters) above sea level.
+type Altitude float64
+
+// Celsius returns temperature in C (celsius).
+func (v Temperature) Celsius() float64 {
+	return float64(v)
+}
+
+// Fahrenheit returns temperature in F (fahrenheit).
+func (v Temperature) Fahrenheit() float64 {
+	return float64(v)*9/5 + 32
+}
+
+// Kelvin returns temperature in K (kelvin).
+func (v Temperature) Kelvin() float64 {
+	return float64(v) + 273.15
+}
+
+// String define stringer interface.
+func (v Temperature) String() string {
+	return fmt.Sprintf("%.2f °C", float64(v))
+}
+
+// Pascal returns pressure in Pa (pascal).
+func (v Pressure) Pascal() float64 {
+	return float64(v)
+}
+
+// Hectopascal returns pressure in hPa (hectopascal).
+func (v Pressure) Hectopascal() float64 {
+	return float64(v) / PA_PER_HPA
+}
+
+// Millibar returns pressure in mbar (millibar), which equals to hPa.
+func (v Pressure) Millibar() float64 {
+	return float64(v) / PA_PER_HPA
+}
+
+// InchHg returns pressure in inHg (inch of mercury).
+func (v Pressure) InchHg() float64 {
+	return float64(v) / PA_PER_INHG
+}
+
+// MmHg returns pressure in mmHg (millimeter of mercury).
+func (v Pressure) MmHg() float64 {
+	return float64(v) / PA_PER_MMHG
+}
+
+// Psi returns pressure in psi (pound per square inch).
+func (v Pressure) Psi() float64 {
+	return float64(v) / PA_PER_PSI
+}
+
+// Atm returns pressure in atm (standard atmosphere).
+func (v Pressure) Atm() float64 {
+	return float64(v) / PA_PER_ATM
+}
+
+// Torr returns pressure in Torr.
+func (v Pressure) Torr() float64 {
+	return float64(v) / PA_PER_TORR
+}
+
+// Altitude calculates altitude from pressure with reference
+// pressure at sea level qnh, by international barometric formula.
+func (v Pressure) Altitude(qnh Pressure) Altitude {
+	return Altitude(BarometricAltitude(float64(v), float64(qnh)))
+}
+
+// String define stringer interface.
+func (v Pressure) String() string {
+	return fmt.Sprintf("%.1f Pa", float64(v))
+}
+
+// Percent returns relative humidity in %RH.
+func (v RelativeHumidity) Percent() float64 {
+	return float64(v)
+}
+
+// Fraction returns relative humidity in range [0..1].
+func (v RelativeHumidity) Fraction() float64 {
+	return float64(v) / 100
+}
+
+// String define stringer interface.
+func (v RelativeHumidity) String() string {
+	return fmt.Sprintf("%.2f %%RH", float64(v))
+}
+
+// Meters returns altitude in m (meters).
+func (v Altitude) Meters() float64 {
+	return float64(v)
+}
+
+// Feet returns altitude in ft (feet).
+func (v Altitude) Feet() float64 {
+	return float64(v) / M_PER_FOOT
+}
+
+// String define stringer interface.
+func (v Altitude) String() string {
+	return fmt.Sprintf("%.2f m", float64(v))
+}
+
+// Units accepted by UnmarshalText, converting value to base unit.
+var (
+	temperatureUnits = map[string]func(float64) float64{
+		"": nil, "C": nil, "°C": nil,
+		"F":  func(f float64) float64 { return (f - 32) * 5 / 9 },
+		"°F": func(f float64) float64 { return (f - 32) * 5 / 9 },
+		"K":  func(k float64) float64 { return k - 273.15 },
+	}
+	pressureUnits = map[string]func(float64) float64{
+		"": nil, "Pa": nil,
+		"hPa":  func(p float64) float64 { return p * PA_PER_HPA },
+		"mbar": func(p float64) float64 { return p * PA_PER_HPA },
+		"inHg": func(p float64) float64 { return p * PA_PER_INHG },
+		"mmHg": func(p float64) float64 { return p * PA_PER_MMHG },
+		"psi":  func(p float64) float64 { return p * PA_PER_PSI },
+		"atm":  func(p float64) float64 { return p * PA_PER_ATM },
+		"Torr": func(p float64) float64 { return p * PA_PER_TORR },
+	}
+	humidityUnits = map[string]func(float64) float64{
+		"": nil, "%": nil, "%RH": nil,
+	}
+	altitudeUnits = map[string]func(float64) float64{
+		"": nil, "m": nil,
+		"ft": func(a float64) float64 { return a * M_PER_FOOT },
+	}
+)
+
+// parseQuantity parse text containing number with optional unit,
+// and converts value to base unit.
+func parseQuantity(text []byte, units map[string]func(float64) float64) (float64, error) {
+	s := strings.TrimSpace(string(text))
+	i := strings.IndexFunc(s, func(r rune) bool {
+		return !strings.ContainsRune("+-.0123456789eE", r)
+	})
+	num, unit := s, ""
+	if i >= 0 {
+		num, unit = s[:i], strings.TrimSpace(s[i:])
+	}
+	conv, ok := units[unit]
+	if !ok {
+		return 0, errors.New(fmt.Sprintf("unknown unit %q in %q", unit, s))
+	}
+	f, err := strconv.ParseFloat(num, 64)
+	if err != nil {
+		return 0, err
+	}
+	if conv != nil {
+		f = conv(f)
+	}
+	return f, nil
+}
+
+// formatQuantity format value with unit, keeping
+// the shortest representation, which parse back exactly.
+func formatQuantity(value float64, unit string) []byte {
+	return []byte(strconv.FormatFloat(value, 'g', -1, 64) + " " + unit)
+}
+
+// unmarshalQuantity decode JSON number, or string parsed by parseQuantity.
+func unmarshalQuantity(data []byte, units map[string]func(float64) float64) (float64, error) {
+	var s string
+	if json.Unmarshal(data, &s) == nil {
+		return parseQuantity([]byte(s), units)
+	}
+	var f float64
+	err := json.Unmarshal(data, &f)
+	return f, err
+}
+
+// MarshalText implement encoding.TextMarshaler interface.
+// Unlike String, value is not rounded.
+func (v Temperature) MarshalText() ([]byte, error) {
+	return formatQuantity(float64(v), "°C"), nil
+}
+
+// UnmarshalText implement encoding.TextUnmarshaler interface,
+// accepting value in C, F or K (C if no unit specified).
+func (v *Temperature) UnmarshalText(text []byte) error {
+	f, err := parseQuantity(text, temperatureUnits)
+	if err != nil {
+		return err
+	}
+	*v = Temperature(f)
+	return nil
+}
+
+// MarshalJSON implement json.Marshaler interface. Value is encoded as number in C.
+func (v Temperature) MarshalJSON() ([]byte, error) {
+	return json.Marshal(float64(v))
+}
+
+// UnmarshalJSON implement json.Unmarshaler interface,
+// accepting number in C, or string with unit.
+func (v *Temperature) UnmarshalJSON(data []byte) error {
+	f, err := unmarshalQuantity(data, temperatureUnits)
+	if err != nil {
+		return err
+	}
+	*v = Temperature(f)
+	return nil
+}
+
+// MarshalText implement encoding.TextMarshaler interface.
+// Unlike String, value is not rounded.
+func (v Pressure) MarshalText() ([]byte, error) {
+	return formatQuantity(float64(v), "Pa"), nil
+}
+
+// UnmarshalText implement encoding.TextUnmarshaler interface, accepting value
+// in Pa, hPa, mbar, inHg, mmHg, psi, atm or Torr (Pa if no unit specified).
+func (v *Pressure) UnmarshalText(text []byte) error {
+	f, err := parseQuantity(text, pressureUnits)
+	if err != nil {
+		return err
+	}
+	*v = Pressure(f)
+	return nil
+}
+
+// MarshalJSON implement json.Marshaler interface. Value is encoded as number in Pa.
+func (v Pressure) MarshalJSON() ([]byte, error) {
+	return json.Marshal(float64(v))
+}
+
+// UnmarshalJSON implement json.Unmarshaler interface,
+// accepting number in Pa, or string with unit.
+func (v *Pressure) UnmarshalJSON(data []byte) error {
+	f, err := unmarshalQuantity(data, pressureUnits)
+	if err != nil {
+		return err
+	}
+	*v = Pressure(f)
+	return nil
+}
+
+// MarshalText implement encoding.TextMarshaler interface.
+// Unlike String, value is not rounded.
+func (v RelativeHumidity) MarshalText() ([]byte, error) {
+	return formatQuantity(float64(v), "%RH"), nil
+}
+
+// UnmarshalText implement encoding.TextUnmarshaler interface.
+func (v *RelativeHumidity) UnmarshalText(text []byte) error {
+	f, err := parseQuantity(text, humidityUnits)
+	if err != nil {
+		return err
+	}
+	*v = RelativeHumidity(f)
+	return nil
+}
+
+// MarshalJSON implement json.Marshaler interface. Value is encoded as number in %RH.
+func (v RelativeHumidity) MarshalJSON() ([]byte, error) {
+	return json.Marshal(float64(v))
+}
+
+// UnmarshalJSON implement json.Unmarshaler interface,
+// accepting number in %RH, or string with unit.
+func (v *RelativeHumidity) UnmarshalJSON(data []byte) error {
+	f, err := unmarshalQuantity(data, humidityUnits)
+	if err != nil {
+		return err
+	}
+	*v = RelativeHumidity(f)
+	return nil
+}
+
+// MarshalText implement encoding.TextMarshaler interface.
+// Unlike String, value is not rounded.
+func (v Altitude) MarshalText() ([]byte, error) {
+	return formatQuantity(float64(v), "m"), nil
+}
+
+// UnmarshalText implement encoding.TextUnmarshaler interface,
+// accepting value in m or ft (m if no unit specified).
+func (v *Altitude) UnmarshalText(text []byte) error {
+	f, err := parseQuantity(text, altitudeUnits)
+	if err != nil {
+		return err
+	}
+	*v = Altitude(f)
+	return nil
+}
+
+// MarshalJSON implement json.Marshaler interface. Value is encoded as number in m.
+func (v Altitude) MarshalJSON() ([]byte, error) {
+	return json.Marshal(float64(v))
+}
+
+// UnmarshalJSON implement json.Unmarshaler interface,
+// accepting number in m, or string with unit.
+func (v *Altitude) UnmarshalJSON(data []byte) error {
+	f, err := unmarshalQuantity(data, altitudeUnits)
+	if err != nil {
+		return err
+	}
+	*v = Altitude(f)
+	return nil
+}
+
+// ReadTemperature reads temperature, returning it as typed value
+// without lossy float32 rounding.
+func (v *BMP) ReadTemperature(accuracy AccuracyMode) (Temperature, error) {
+	return v.ReadTemperatureContext(context.Background(), accuracy)
+}
+
+// ReadTemperatureContext is ReadTemperature with context.
+func (v *BMP) ReadTemperatureContext(ctx context.Context, accuracy AccuracyMode) (Temperature, error) {
+	t, err := v.ReadTemperatureMult100CContext(ctx, accuracy)
+	if err != nil {
+		return 0, err
+	}
+	return Temperature(float64(t) / 100), nil
+}
+
+// ReadPressure reads atmospheric pressure, returning it as typed value
+// without lossy float32 rounding.
+func (v *BMP) ReadPressure(accuracy AccuracyMode) (Pressure, error) {
+	return v.ReadPressureContext(context.Background(), accuracy)
+}
+
+// ReadPressureContext is ReadPressure with context.
+func (v *BMP) ReadPressureContext(ctx context.Context, accuracy AccuracyMode) (Pressure, error) {
+	p, err := v.ReadPressureMult10PaContext(ctx, accuracy)
+	if err != nil {
+		return 0, err
+	}
+	return Pressure(float64(p) / 10), nil
+}
+
+// ReadHumidity reads relative humidity, returning it as typed value.
+// First value returned is false, if humidity is not supported by sensor.
+func (v *BMP) ReadHumidity(accuracy AccuracyMode) (bool, RelativeHumidity, error) {
+	return v.ReadHumidityContext(context.Background(), accuracy)
+}
+
+// ReadHumidityContext is ReadHumidity with context.
+func (v *BMP) ReadHumidityContext(ctx context.Context, accuracy AccuracyMode) (bool, RelativeHumidity, error) {
+	supported, h, err := v.bmp.ReadHumidityMultQ2210(withContext(ctx, v.bus), accuracy)
+	if !supported || err != nil {
+		return supported, 0, err
+	}
+	return supported, RelativeHumidity(float64(h) / 1024), nil
+}
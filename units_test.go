@@ -0,0 +1,137 @@
+//--------------------------------------------------------------------------------------------------
+//
+// Copyright (c) 2018 Denis Dyakov
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy of this software and
+// associated documentation files (the "Software"), to deal in the Software without restriction,
+// including without limitation the rights to use, copy, modify, merge, publish, distribute, sublicense,
+// and/or sell copies of the Software, and to permit persons to whom the Software is furnished to do so,
+// subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all copies or substantial
+// portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR IMPLIED, INCLUDING
+// BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY, FITNESS FOR A PARTICULAR PURPOSE AND
+// NONINFRINGEMENT. IN NO EVENT SHALL THE AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM,
+// DAMAGES OR OTHER LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE SOFTWARE.
+//
+//--------------------------------------------------------------------------------------------------
+
+package bsbmp_test
+
+import (
+	"encoding"
+	"encoding/json"
+	"fmt"
+	"testing"
+
+	"github.com/d2r2/go-bsbmp"
+)
+
+func TestUnitConversions(t *testing.T) {
+	temperature := bsbmp.Temperature(20)
+	pressure := bsbmp.Pressure(101325)
+	for _, c := range []struct {
+		name      string
+		got, want float64
+		tolerance float64
+	}{
+		{"Fahrenheit", temperature.Fahrenheit(), 68, 1e-9},
+		{"Kelvin", temperature.Kelvin(), 293.15, 1e-9},
+		{"Hectopascal", pressure.Hectopascal(), 1013.25, 1e-9},
+		{"Millibar", pressure.Millibar(), 1013.25, 1e-9},
+		{"InchHg", pressure.InchHg(), 29.92, 0.01},
+		{"MmHg", pressure.MmHg(), 760, 0.001},
+		{"Psi", pressure.Psi(), 14.696, 0.001},
+		{"Atm", pressure.Atm(), 1, 1e-12},
+		{"Torr", pressure.Torr(), 760, 1e-9},
+		{"Feet", bsbmp.Altitude(1000).Feet(), 3280.84, 0.01},
+		{"Fraction", bsbmp.RelativeHumidity(45).Fraction(), 0.45, 1e-12},
+	} {
+		checkValue(t, c.name, c.got, c.want, c.tolerance)
+	}
+}
+
+func TestUnitString(t *testing.T) {
+	for _, c := range []struct {
+		value fmt.Stringer
+		want  string
+	}{
+		{bsbmp.Temperature(20.126), "20.13 °C"},
+		{bsbmp.Pressure(101325.04), "101325.0 Pa"},
+		{bsbmp.RelativeHumidity(45.5), "45.50 %RH"},
+		{bsbmp.Altitude(12), "12.00 m"},
+	} {
+		if got := c.value.String(); got != c.want {
+			t.Errorf("String() = %q, want %q", got, c.want)
+		}
+	}
+}
+
+// Text marshaling should not lose precision, unlike String.
+func TestUnitTextRoundTrip(t *testing.T) {
+	t1, t2 := bsbmp.Temperature(21.123456789), bsbmp.Temperature(0)
+	p1, p2 := bsbmp.Pressure(101325.0123456), bsbmp.Pressure(0)
+	h1, h2 := bsbmp.RelativeHumidity(45.000001), bsbmp.RelativeHumidity(0)
+	a1, a2 := bsbmp.Altitude(-12.3456789e-5), bsbmp.Altitude(0)
+	for _, c := range []struct {
+		in  encoding.TextMarshaler
+		out encoding.TextUnmarshaler
+	}{
+		{t1, &t2}, {p1, &p2}, {h1, &h2}, {a1, &a2},
+	} {
+		text, err := c.in.MarshalText()
+		if err != nil {
+			t.Fatal(err)
+		}
+		err = c.out.UnmarshalText(text)
+		if err != nil {
+			t.Fatalf("UnmarshalText(%q): %v", text, err)
+		}
+	}
+	if t2 != t1 || p2 != p1 || h2 != h1 || a2 != a1 {
+		t.Errorf("round trip = %v, %v, %v, %v, want %v, %v, %v, %v",
+			float64(t2), float64(p2), float64(h2), float64(a2),
+			float64(t1), float64(p1), float64(h1), float64(a1))
+	}
+}
+
+func TestUnitJSON(t *testing.T) {
+	type record struct {
+		T bsbmp.Temperature
+		P bsbmp.Pressure
+		H bsbmp.RelativeHumidity
+		A bsbmp.Altitude
+	}
+	b, err := json.Marshal(record{20.125, 101325.5, 45.5, 12})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := `{"T":20.125,"P":101325.5,"H":45.5,"A":12}`; string(b) != want {
+		t.Errorf("json.Marshal() = %s, want %s", b, want)
+	}
+	var r record
+	err = json.Unmarshal([]byte(`{"T":"68 °F","P":"29.92 inHg","H":"50%","A":"1000 ft"}`), &r)
+	if err != nil {
+		t.Fatal(err)
+	}
+	checkValue(t, "T", float64(r.T), 20, 1e-9)
+	checkValue(t, "P", float64(r.P), 101320.76, 0.01)
+	checkValue(t, "H", float64(r.H), 50, 1e-9)
+	checkValue(t, "A", float64(r.A), 304.8, 1e-9)
+	err = r.P.UnmarshalText([]byte("5 bananas"))
+	if err == nil {
+		t.Error("unknown unit accepted")
+	}
+	err = json.Unmarshal([]byte(`{"T":"hot","P":"5 bananas","H":true,"A":"1 mile"}`), &r)
+	if err == nil {
+		t.Error("invalid values accepted")
+	}
+	// Value is kept intact on error.
+	checkValue(t, "T after error", float64(r.T), 20, 1e-9)
+	checkValue(t, "P after error", float64(r.P), 101320.76, 0.01)
+	checkValue(t, "H after error", float64(r.H), 50, 1e-9)
+	checkValue(t, "A after error", float64(r.A), 304.8, 1e-9)
+}
@@ -24,10 +24,7 @@ package bsbmp
 import (
 	"bytes"
 	"encoding/binary"
-	"fmt"
 	"time"
-
-	i2c "github.com/d2r2/go-i2c"
 )
 
 // Utility functions
@@ -60,35 +57,115 @@ func getU16LE(buf []byte) uint16 {
 	return v
 }
 
+// getU20BE extract 20-bit unsigned ADC value stored in 3 bytes
+// as msb, lsb and xlsb[7:4], used by BMP280/BME280 family.
+func getU20BE(buf []byte) int32 {
+	v := int32(buf[0])<<12 + int32(buf[1])<<4 + int32(buf[2]&0xF0)>>4
+	return v
+}
+
+// getU24LE extract 3-byte integer as unsigned little-endian.
+func getU24LE(buf []byte) int32 {
+	v := int32(buf[0]) + int32(buf[1])<<8 + int32(buf[2])<<16
+	return v
+}
+
+// getS24LE extract 3-byte integer as signed little-endian.
+func getS24LE(buf []byte) int32 {
+	v := getU24LE(buf)
+	// extend sign bit
+	return v << 8 >> 8
+}
+
 // checkCoefficient verify that compensation parameter looks valid.
 func checkCoefficient(coef uint16, name string) error {
 	if coef == 0 || coef == 0xFFFF {
-		return fmt.Errorf("Coefficient %s is invalid: 0x%X", name, coef)
+		return &CoefficientError{Name: name, Value: coef}
+	}
+	return nil
+}
+
+// updateRegU8 change bits of register reg selected by mask to value,
+// keeping other bits unchanged (read-modify-write operation).
+func updateRegU8(bus Bus, reg byte, mask byte, value byte) error {
+	b, err := bus.ReadRegU8(reg)
+	if err != nil {
+		return err
+	}
+	b = b&^mask | value&mask
+	return bus.WriteRegU8(reg, b)
+}
+
+// Amount of edges queued by gpio driver, which are dropped
+// before conversion start at most.
+const maxQueuedEdges = 64
+
+// clearInterrupt prepare interrupt pin attached to the bus (if any) for
+// conversion about to start. Interrupt status is read, which release INT
+// output kept asserted in latched mode, then edges left queued (by normal
+// mode samples, for instance) are dropped, so they are not mistaken
+// for end of conversion.
+func clearInterrupt(sensor SensorInterface, bus Bus) error {
+	pin := busInterruptPin(bus)
+	if pin == nil {
+		return nil
+	}
+	if s, ok := sensor.(InterruptInterface); ok {
+		_, err := s.ReadInterruptStatus(bus)
+		if err != nil {
+			return err
+		}
+	}
+	for i := 0; i < maxQueuedEdges; i++ {
+		edge, err := pin.WaitForEdge(0)
+		if err != nil || !edge {
+			return err
+		}
 	}
 	return nil
 }
 
+// How long to poll status register before giving up. Longest conversion
+// (BME280 with all channels x16 oversampled) takes about 115 ms.
+const completionTimeout = 500 * time.Millisecond
+
 // waitForCompletion Wait until sensor completes measurements and calculations,
-// otherwise return on timeout.
-func waitForCompletion(sensor SensorInterface, i2c *i2c.I2C) (timeout bool, err error) {
-	for i := 0; i < 10; i++ {
-		flag, err := sensor.IsBusy(i2c)
+// otherwise return ErrTimeout. If interrupt pin attached to the bus, sleep until
+// sensor signals data ready first, so status register is read just once.
+// If context attached to the bus is done, return context error instead.
+func waitForCompletion(sensor SensorInterface, bus Bus) error {
+	if pin := busInterruptPin(bus); pin != nil {
+		edge, err := waitForEdge(bus, pin, interruptTimeout)
+		if err != nil {
+			return err
+		}
+		if !edge {
+			lg.Debugf("no interrupt in %v, fall back to status polling", interruptTimeout)
+		}
+	}
+	start := time.Now()
+	for {
+		flag, err := sensor.IsBusy(bus)
 		if err != nil {
-			return false, err
+			return err
 		}
 		if flag == false {
-			return false, nil
+			return nil
+		}
+		if time.Since(start) > completionTimeout {
+			return ErrTimeout
+		}
+		err = sleep(bus, 5*time.Millisecond)
+		if err != nil {
+			return err
 		}
-		time.Sleep(5 * time.Millisecond)
 	}
-	return true, nil
 }
 
-// Read byte block from i2c device to struct object.
-func readDataToStruct(i2c *i2c.I2C, byteCount int,
+// Read byte block starting from register reg to struct object.
+func readDataToStruct(bus Bus, reg byte, byteCount int,
 	byteOrder binary.ByteOrder, obj interface{}) error {
-	buf1 := make([]byte, byteCount)
-	_, err := i2c.ReadBytes(buf1)
+	buf1, _, err := bus.ReadRegBytes(reg, byteCount)
 	if err != nil {
 		return err
 	}